@@ -0,0 +1,300 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+//go:build arrow
+
+package twobit
+
+import (
+    "io"
+    "bufio"
+    "encoding/binary"
+    "fmt"
+    "math"
+)
+
+// Arrow IPC constants from the Arrow columnar format specification
+const (
+    arrowMetadataV5     = 4
+    arrowSchema         = 1
+    arrowRecordBatch    = 3
+    arrowTypeInt        = 2
+    arrowTypeFloat      = 3
+    arrowTypeUtf8       = 5
+    arrowDouble         = 2
+    arrowContinuation   = 0xFFFFFFFF
+)
+
+// arrowColumn is one non-nullable column of a table holding strings, int64
+// or float64 values according to its type
+type arrowColumn struct {
+    name     string
+    typeID   uint8
+    strings  []string
+    ints     []int64
+    floats   []float64
+}
+
+// Return columns named names with the first holding strings, the last
+// float64 and the others int64, the shape of both exported tables
+func arrowColumns(names ...string) ([]*arrowColumn) {
+    cols := make([]*arrowColumn, len(names))
+    for i, name := range names {
+        cols[i] = &arrowColumn{name: name, typeID: arrowTypeInt}
+    }
+    cols[0].typeID = arrowTypeUtf8
+    cols[len(cols)-1].typeID = arrowTypeFloat
+
+    return cols
+}
+
+// WriteMetadataArrow writes metadata rows as an Apache Arrow IPC stream
+// (the format read by pyarrow.ipc.open_stream and polars.read_ipc_stream)
+// with the columns of WriteMetadataTable. Built only with the arrow tag:
+//
+//   go build -tags arrow
+func WriteMetadataArrow(out io.Writer, rows []*SequenceMetadata) (error) {
+    cols := arrowColumns("name", "length", "offset", "n_blocks", "m_blocks", "a", "c", "g", "t", "n", "masked", "gc")
+    for _, m := range rows {
+        cols[0].strings = append(cols[0].strings, m.Name)
+        for i, v := range []int64{int64(m.Length), m.Offset, int64(m.NBlocks), int64(m.MBlocks),
+            int64(m.A), int64(m.C), int64(m.G), int64(m.T), int64(m.N), int64(m.Masked)} {
+            cols[i+1].ints = append(cols[i+1].ints, v)
+        }
+        cols[11].floats = append(cols[11].floats, m.GC)
+    }
+
+    return writeArrowStream(out, cols, len(rows))
+}
+
+// WriteWindowArrow writes window rows as an Apache Arrow IPC stream with the
+// columns of WriteWindowTable. Built only with the arrow tag.
+func WriteWindowArrow(out io.Writer, rows []*WindowStats) (error) {
+    cols := arrowColumns("name", "start", "end", "a", "c", "g", "t", "n", "masked", "gc")
+    for _, s := range rows {
+        cols[0].strings = append(cols[0].strings, s.Name)
+        for i, v := range []int64{int64(s.Start), int64(s.End),
+            int64(s.A), int64(s.C), int64(s.G), int64(s.T), int64(s.N), int64(s.Masked)} {
+            cols[i+1].ints = append(cols[i+1].ints, v)
+        }
+        cols[9].floats = append(cols[9].floats, s.GC)
+    }
+
+    return writeArrowStream(out, cols, len(rows))
+}
+
+// Write cols of length rows as a stream of a schema message, one record
+// batch and the end of stream marker
+func writeArrowStream(out io.Writer, cols []*arrowColumn, rows int) (error) {
+    fields := make(fbTables, len(cols))
+    for i, c := range cols {
+        var typ fbTable
+        switch c.typeID {
+        case arrowTypeUtf8:
+            typ = fbTable{}
+        case arrowTypeFloat:
+            typ = fbTable{int16(arrowDouble)}
+        default:
+            // bitWidth, is_signed
+            typ = fbTable{int32(64), uint8(1)}
+        }
+        // name, nullable, type_type, type, dictionary, children
+        fields[i] = fbTable{fbString(c.name), uint8(0), c.typeID, typ, nil, fbTables{}}
+    }
+    // version, header_type, header (endianness, fields), bodyLength
+    schema := fbTable{int16(arrowMetadataV5), uint8(arrowSchema), fbTable{int16(0), fields}, int64(0)}
+
+    var body, nodes, buffers []byte
+    addBuffer := func(data []byte) {
+        buffers = binary.LittleEndian.AppendUint64(buffers, uint64(len(body)))
+        buffers = binary.LittleEndian.AppendUint64(buffers, uint64(len(data)))
+        body = append(body, data...)
+        for len(body)%8 != 0 {
+            body = append(body, 0)
+        }
+    }
+    for _, c := range cols {
+        nodes = binary.LittleEndian.AppendUint64(nodes, uint64(rows))
+        nodes = binary.LittleEndian.AppendUint64(nodes, 0)
+        // no validity bitmap as there are no nulls
+        addBuffer(nil)
+
+        var data []byte
+        switch c.typeID {
+        case arrowTypeFloat:
+            for _, v := range c.floats {
+                data = binary.LittleEndian.AppendUint64(data, math.Float64bits(v))
+            }
+        case arrowTypeInt:
+            for _, v := range c.ints {
+                data = binary.LittleEndian.AppendUint64(data, uint64(v))
+            }
+        default:
+            offsets := binary.LittleEndian.AppendUint32(nil, 0)
+            for _, s := range c.strings {
+                data = append(data, s...)
+                if len(data) > math.MaxInt32 {
+                    return fmt.Errorf("Column %s is too large for an Arrow string column", c.name)
+                }
+                offsets = binary.LittleEndian.AppendUint32(offsets, uint32(len(data)))
+            }
+            addBuffer(offsets)
+        }
+        addBuffer(data)
+    }
+    // length, nodes, buffers
+    batch := fbTable{int16(arrowMetadataV5), uint8(arrowRecordBatch),
+        fbTable{int64(rows), fbStructs(nodes), fbStructs(buffers)}, int64(len(body))}
+
+    w := bufio.NewWriter(out)
+    writeArrowMessage(w, fbRoot(schema), nil)
+    writeArrowMessage(w, fbRoot(batch), body)
+    writeArrowMessage(w, nil, nil)
+
+    return w.Flush()
+}
+
+// Write an encapsulated message: the continuation marker, the length of the
+// padded metadata, the metadata and the body. Empty metadata marks the end
+// of the stream.
+func writeArrowMessage(w *bufio.Writer, meta, body []byte) {
+    var prefix [8]byte
+    binary.LittleEndian.PutUint32(prefix[0:], arrowContinuation)
+    binary.LittleEndian.PutUint32(prefix[4:], uint32(len(meta)))
+    w.Write(prefix[:])
+    w.Write(meta)
+    w.Write(body)
+}
+
+// fbTable is a flatbuffer table to encode, one entry per field slot with nil
+// for absent fields. Entries are scalars (uint8, int16, int32 or int64) or
+// objects (fbTable, fbTables, fbString or fbStructs).
+type fbTable []interface{}
+
+// fbTables is a vector of tables
+type fbTables []fbTable
+
+// fbString is a string
+type fbString string
+
+// fbStructs is a vector of structs with 8 byte alignment, already encoded.
+// Every struct used here is two longs.
+type fbStructs []byte
+
+// fbBuilder encodes flatbuffers front to back: each object is written before
+// the objects it refers to so every offset points forward, as required
+type fbBuilder struct {
+    buf  []byte
+}
+
+// Encode t as the root table of a flatbuffer padded to 8 bytes
+func fbRoot(t fbTable) ([]byte) {
+    b := &fbBuilder{buf: make([]byte, 4)}
+    b.patch(0, b.table(t))
+    b.pad(8)
+
+    return b.buf
+}
+
+func (b *fbBuilder) pad(align int) {
+    for len(b.buf)%align != 0 {
+        b.buf = append(b.buf, 0)
+    }
+}
+
+// Point the offset at pos to target
+func (b *fbBuilder) patch(pos, target int) {
+    binary.LittleEndian.PutUint32(b.buf[pos:], uint32(target-pos))
+}
+
+// Append obj and return its position
+func (b *fbBuilder) object(obj interface{}) (int) {
+    switch o := obj.(type) {
+    case fbTable:
+        return b.table(o)
+    case fbTables:
+        b.pad(4)
+        pos := len(b.buf)
+        b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(len(o)))
+        b.buf = append(b.buf, make([]byte, 4*len(o))...)
+        for i, t := range o {
+            b.patch(pos+4+4*i, b.table(t))
+        }
+        return pos
+    case fbString:
+        b.pad(4)
+        pos := len(b.buf)
+        b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(len(o)))
+        b.buf = append(b.buf, o...)
+        b.buf = append(b.buf, 0)
+        return pos
+    case fbStructs:
+        // the elements after the length must be 8 byte aligned
+        b.pad(4)
+        if len(b.buf)%8 == 0 {
+            b.buf = append(b.buf, 0, 0, 0, 0)
+        }
+        pos := len(b.buf)
+        b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(len(o)/16))
+        b.buf = append(b.buf, o...)
+        return pos
+    }
+
+    panic(fmt.Sprintf("Invalid flatbuffer object %T", obj))
+}
+
+// Append the vtable and table t and the objects it refers to, returning the
+// position of the table
+func (b *fbBuilder) table(t fbTable) (int) {
+    b.pad(2)
+    vtable := len(b.buf)
+    b.buf = append(b.buf, make([]byte, 4+2*len(t))...)
+    b.pad(8)
+    pos := len(b.buf)
+    b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(pos-vtable))
+
+    type ref struct {
+        at   int
+        obj  interface{}
+    }
+    refs := make([]ref, 0)
+    for i, f := range t {
+        if f == nil {
+            continue
+        }
+
+        var at int
+        switch v := f.(type) {
+        case uint8:
+            at = len(b.buf)
+            b.buf = append(b.buf, v)
+        case int16:
+            b.pad(2)
+            at = len(b.buf)
+            b.buf = binary.LittleEndian.AppendUint16(b.buf, uint16(v))
+        case int32:
+            b.pad(4)
+            at = len(b.buf)
+            b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(v))
+        case int64:
+            b.pad(8)
+            at = len(b.buf)
+            b.buf = binary.LittleEndian.AppendUint64(b.buf, uint64(v))
+        default:
+            b.pad(4)
+            at = len(b.buf)
+            b.buf = append(b.buf, 0, 0, 0, 0)
+            refs = append(refs, ref{at, f})
+        }
+        binary.LittleEndian.PutUint16(b.buf[vtable+4+2*i:], uint16(at-pos))
+    }
+    binary.LittleEndian.PutUint16(b.buf[vtable:], uint16(4+2*len(t)))
+    binary.LittleEndian.PutUint16(b.buf[vtable+2:], uint16(len(b.buf)-pos))
+
+    for _, r := range refs {
+        b.patch(r.at, b.object(r.obj))
+    }
+
+    return pos
+}
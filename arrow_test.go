@@ -0,0 +1,266 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+//go:build arrow
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "encoding/binary"
+    "io/ioutil"
+    "math"
+    "path/filepath"
+    "reflect"
+)
+
+// fbReader reads tables of a flatbuffer
+type fbReader []byte
+
+func (f fbReader) u16(pos int) (int) { return int(binary.LittleEndian.Uint16(f[pos:])) }
+func (f fbReader) u32(pos int) (int) { return int(binary.LittleEndian.Uint32(f[pos:])) }
+func (f fbReader) i64(pos int) (int64) { return int64(binary.LittleEndian.Uint64(f[pos:])) }
+
+// Return the position of field slot of the table at pos, -1 if absent
+func (f fbReader) field(pos, slot int) (int) {
+    vtable := pos-int(int32(f.u32(pos)))
+    if 4+2*slot >= f.u16(vtable) || f.u16(vtable+4+2*slot) == 0 {
+        return -1
+    }
+
+    return pos+f.u16(vtable+4+2*slot)
+}
+
+// Return the scalar field slot of the table at pos read by get, def if the
+// field is absent as flatbuffers omit fields holding their default value
+func (f fbReader) scalar(pos, slot int, def int64, get func(int) (int64)) (int64) {
+    if at := f.field(pos, slot); at >= 0 {
+        return get(at)
+    }
+    return def
+}
+
+func (f fbReader) byteAt(pos int) (int64) { return int64(f[pos]) }
+func (f fbReader) u16At(pos int) (int64) { return int64(f.u16(pos)) }
+func (f fbReader) u32At(pos int) (int64) { return int64(f.u32(pos)) }
+
+// Follow the offset at pos
+func (f fbReader) deref(pos int) (int) {
+    return pos+f.u32(pos)
+}
+
+func (f fbReader) str(pos int) (string) {
+    pos = f.deref(pos)
+    return string(f[pos+4:pos+4+f.u32(pos)])
+}
+
+// Decode an Arrow stream of int64, float64 and utf8 columns
+func readArrowStream(t *testing.T, data []byte) (map[string]interface{}, []string) {
+    message := func() (fbReader, []byte) {
+        if binary.LittleEndian.Uint32(data) != arrowContinuation {
+            t.Fatalf("Missing continuation marker")
+        }
+        size := int(binary.LittleEndian.Uint32(data[4:]))
+        if size%8 != 0 {
+            t.Fatalf("Metadata not padded: %d", size)
+        }
+        meta := fbReader(data[8:8+size])
+        data = data[8+size:]
+        if size == 0 {
+            return nil, nil
+        }
+        if meta.scalar(meta.u32(0), 0, 0, meta.u16At) != arrowMetadataV5 {
+            t.Fatalf("Invalid metadata version")
+        }
+        bodyLen := int(meta.scalar(meta.u32(0), 3, 0, meta.i64))
+        body := data[:bodyLen]
+        data = data[bodyLen:]
+        return meta, body
+    }
+
+    meta, _ := message()
+    root := meta.u32(0)
+    if meta.scalar(root, 1, 0, meta.byteAt) != arrowSchema {
+        t.Fatalf("First message is not a schema")
+    }
+    schema := meta.deref(meta.field(root, 2))
+    fields := meta.deref(meta.field(schema, 1))
+    names := make([]string, meta.u32(fields))
+    types := make([]byte, len(names))
+    for i := range names {
+        field := meta.deref(fields+4+4*i)
+        names[i] = meta.str(meta.field(field, 0))
+        types[i] = byte(meta.scalar(field, 2, 0, meta.byteAt))
+        if meta.field(field, 5) < 0 {
+            t.Errorf("Field %s has no children vector", names[i])
+        }
+        typ := meta.deref(meta.field(field, 3))
+        if types[i] == arrowTypeInt && (meta.scalar(typ, 0, 0, meta.u32At) != 64 || meta.scalar(typ, 1, 0, meta.byteAt) != 1) {
+            t.Errorf("Field %s is not a signed 64 bit int", names[i])
+        }
+        if types[i] == arrowTypeFloat && meta.scalar(typ, 0, 0, meta.u16At) != arrowDouble {
+            t.Errorf("Field %s is not a double", names[i])
+        }
+    }
+
+    meta, body := message()
+    root = meta.u32(0)
+    if meta.scalar(root, 1, 0, meta.byteAt) != arrowRecordBatch {
+        t.Fatalf("Second message is not a record batch")
+    }
+    batch := meta.deref(meta.field(root, 2))
+    rows := int(meta.scalar(batch, 0, 0, meta.i64))
+    buffers := meta.deref(meta.field(batch, 2))
+    if (buffers+4)%8 != 0 {
+        t.Errorf("Buffers not aligned")
+    }
+    buffer := func(i int) ([]byte) {
+        pos := buffers+4+16*i
+        return body[meta.i64(pos):meta.i64(pos)+meta.i64(pos+8)]
+    }
+
+    cols := make(map[string]interface{})
+    b := 0
+    for i, name := range names {
+        b++ // validity
+        switch types[i] {
+        case arrowTypeUtf8:
+            offsets, data := buffer(b), buffer(b+1)
+            b += 2
+            vals := make([]string, rows)
+            for j := range vals {
+                vals[j] = string(data[binary.LittleEndian.Uint32(offsets[4*j:]):binary.LittleEndian.Uint32(offsets[4*j+4:])])
+            }
+            cols[name] = vals
+        case arrowTypeInt:
+            data := buffer(b)
+            b++
+            vals := make([]int64, rows)
+            for j := range vals {
+                vals[j] = int64(binary.LittleEndian.Uint64(data[8*j:]))
+            }
+            cols[name] = vals
+        case arrowTypeFloat:
+            data := buffer(b)
+            b++
+            vals := make([]float64, rows)
+            for j := range vals {
+                vals[j] = math.Float64frombits(binary.LittleEndian.Uint64(data[8*j:]))
+            }
+            cols[name] = vals
+        }
+    }
+
+    if meta, _ := message(); meta != nil || len(data) != 0 {
+        t.Errorf("Missing end of stream")
+    }
+
+    return cols, names
+}
+
+func TestWriteArrow(t *testing.T) {
+    tb := newTestReader(t, map[string]string{"chr1": "ACGTNNacgtGC"})
+
+    windows, err := tb.WindowStats(5)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    var out bytes.Buffer
+    err = WriteWindowArrow(&out, windows)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    cols, names := readArrowStream(t, out.Bytes())
+    if len(names) != 10 || names[0] != "name" || names[9] != "gc" {
+        t.Errorf("Invalid columns: %v", names)
+    }
+    ends := cols["end"].([]int64)
+    if len(ends) != 3 || ends[0] != 5 || ends[2] != 12 || cols["name"].([]string)[2] != "chr1" {
+        t.Errorf("Invalid window columns: %v", cols)
+    }
+    if gc := cols["gc"].([]float64); gc[0] != 0.5 || gc[2] != 1 {
+        t.Errorf("Invalid gc column: %v", gc)
+    }
+
+    meta, err := tb.SequenceMetadata()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    out.Reset()
+    err = WriteMetadataArrow(&out, meta)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    cols, names = readArrowStream(t, out.Bytes())
+    if len(names) != 12 || cols["length"].([]int64)[0] != 12 || cols["masked"].([]int64)[0] != 4 {
+        t.Errorf("Invalid metadata columns: %v", cols)
+    }
+
+    out.Reset()
+    err = WriteMetadataArrow(&out, nil)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    cols, _ = readArrowStream(t, out.Bytes())
+    if len(cols["name"].([]string)) != 0 {
+        t.Errorf("Invalid empty table")
+    }
+}
+
+// The golden streams were written by the Apache Arrow Go module for the same
+// tables, see testdata/arrow/make_golden.go, which also checks that arrow-go
+// reads the output of WriteWindowArrow and WriteMetadataArrow
+func TestWriteArrowGolden(t *testing.T) {
+    // in the order of make_golden.go as the rows follow the file order
+    w := NewWriter()
+    w.Add("chr1", "ACGTNNacgtGC")
+    w.Add("chr2", "ggggNNNNAT")
+    var file bytes.Buffer
+    w.WriteTo(&file)
+    tb, err := NewReader(bytes.NewReader(file.Bytes()))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    windows, err := tb.WindowStats(5)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    meta, err := tb.SequenceMetadata()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    tables := []struct {
+        golden  string
+        rows    int
+        write   func(*bytes.Buffer) (error)
+    }{
+        {"windows.arrows", 5, func(out *bytes.Buffer) (error) { return WriteWindowArrow(out, windows) }},
+        {"metadata.arrows", 2, func(out *bytes.Buffer) (error) { return WriteMetadataArrow(out, meta) }},
+    }
+
+    for _, table := range tables {
+        golden, err := ioutil.ReadFile(filepath.Join("testdata", "arrow", table.golden))
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        var out bytes.Buffer
+        err = table.write(&out)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+
+        wantCols, wantNames := readArrowStream(t, golden)
+        if len(wantCols["name"].([]string)) != table.rows {
+            t.Errorf("%s: invalid golden table: %v", table.golden, wantCols)
+        }
+        cols, names := readArrowStream(t, out.Bytes())
+        if !reflect.DeepEqual(names, wantNames) || !reflect.DeepEqual(cols, wantCols) {
+            t.Errorf("%s: table differs from arrow-go: %v %v != %v %v", table.golden, names, cols, wantNames, wantCols)
+        }
+    }
+}
@@ -0,0 +1,149 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "io"
+    "bufio"
+    "fmt"
+    "strconv"
+    "strings"
+)
+
+// Parse a comma separated BED12 list of count integers of at least min
+func parseBedList(list string, count, min int) ([]int, error) {
+    fields := strings.Split(strings.TrimRight(list, ","), ",")
+    if len(fields) != count {
+        return nil, fmt.Errorf("expected %d values, got %d", count, len(fields))
+    }
+
+    values := make([]int, count)
+    for i, f := range fields {
+        v, err := strconv.Atoi(f)
+        if err != nil || v < min {
+            return nil, fmt.Errorf("invalid value %s", f)
+        }
+        values[i] = v
+    }
+
+    return values, nil
+}
+
+// Split a BED line into columns. Tab separated lines are split on tabs,
+// keeping empty columns, and other lines on runs of white space.
+func bedColumns(text string) ([]string) {
+    if strings.Contains(text, "\t") {
+        return strings.Split(text, "\t")
+    }
+
+    return strings.Fields(text)
+}
+
+// ScanBED calls fn with the interval (the chrom, start and end columns), all
+// columns and the line number of each record of the BED file in, in file
+// order. Header, track, comment and blank lines are skipped. Empty intervals
+// are invalid. Scanning stops at the first error, which is returned.
+func ScanBED(in io.Reader, fn func(iv Interval, cols []string, line int) (error)) (error) {
+    scanner := bufio.NewScanner(in)
+    scanner.Buffer(make([]byte, defaultBufSize), 64*1024*1024)
+
+    line := 0
+    for scanner.Scan() {
+        line++
+        text := strings.TrimRight(scanner.Text(), "\r")
+        if len(text) == 0 || text[0] == '#' || strings.HasPrefix(text, "track") || strings.HasPrefix(text, "browser") {
+            continue
+        }
+
+        cols := bedColumns(text)
+        iv, err := parseBEDInterval(cols, line)
+        if err != nil {
+            return err
+        }
+
+        err = fn(iv, cols, line)
+        if err != nil {
+            return err
+        }
+    }
+
+    if err := scanner.Err(); err != nil {
+        return fmt.Errorf("Failed to read BED: %s", err)
+    }
+
+    return nil
+}
+
+// ReadBEDRecord returns the sequence of the BED record (BED3 to BED12) with
+// interval iv and columns cols, as passed by ScanBED. For BED12 records the
+// blocks (exons) are stitched together, excluding introns. Records on the
+// minus strand are reverse complemented.
+func (r *Reader) ReadBEDRecord(iv Interval, cols []string) ([]byte, error) {
+    blocks := []Interval{iv}
+    if len(cols) >= 12 {
+        count, err := strconv.Atoi(cols[9])
+        if err != nil || count < 1 {
+            return nil, fmt.Errorf("Invalid BED block count: %s", cols[9])
+        }
+        sizes, err := parseBedList(cols[10], count, 1)
+        if err != nil {
+            return nil, fmt.Errorf("Invalid BED block sizes: %s", err)
+        }
+        starts, err := parseBedList(cols[11], count, 0)
+        if err != nil {
+            return nil, fmt.Errorf("Invalid BED block starts: %s", err)
+        }
+
+        blocks = make([]Interval, count)
+        for i := range blocks {
+            blocks[i] = Interval{Name: iv.Name, Start: iv.Start+starts[i], End: iv.Start+starts[i]+sizes[i]}
+            if blocks[i].End > iv.End {
+                return nil, fmt.Errorf("BED block extends past end of record")
+            }
+        }
+    }
+
+    parts, err := r.ReadRanges(blocks)
+    if err != nil {
+        return nil, fmt.Errorf("Failed to read BED record: %s", err)
+    }
+
+    seq := make([]byte, 0)
+    for _, p := range parts {
+        seq = append(seq, p...)
+    }
+
+    if len(cols) > 5 && cols[5] == "-" {
+        ReverseComplementInPlace(seq)
+    }
+
+    return seq, nil
+}
+
+// Read BED records (BED3 to BED12) from in and write the sequence of each in
+// FASTA format to out, see ReadBEDRecord. Records are named by the name
+// column or chrom:start-end when there is none.
+func (r *Reader) ExtractBED(in io.Reader, out io.Writer) (error) {
+    w := bufio.NewWriter(out)
+
+    err := ScanBED(in, func(iv Interval, cols []string, line int) (error) {
+        name := iv.String()
+        if len(cols) > 3 && len(cols[3]) > 0 {
+            name = cols[3]
+        }
+
+        seq, err := r.ReadBEDRecord(iv, cols)
+        if err != nil {
+            return fmt.Errorf("BED record on line %d: %s", line, err)
+        }
+
+        return writeFasta(w, name, seq)
+    })
+    if err != nil {
+        return err
+    }
+
+    return w.Flush()
+}
@@ -0,0 +1,89 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "strings"
+)
+
+func TestExtractBED(t *testing.T) {
+    tb := newTestReader(t, map[string]string{"chr1": "AAAACCCCGGGGTTTTacgt"})
+
+    bed := strings.Join([]string{
+        "track name=test",
+        "chr1\t0\t4",
+        "chr1\t4\t8\tplus\t0\t+",
+        "chr1\t4\t8\tminus\t0\t-",
+        "chr1\t0\t20\ttx1\t0\t+\t0\t20\t0\t3\t2,2,4,\t0,6,16,",
+        "chr1\t0\t20\ttx2\t0\t-\t0\t20\t0\t2\t4,4\t0,16",
+    }, "\n")
+
+    var out bytes.Buffer
+    err := tb.ExtractBED(strings.NewReader(bed), &out)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    good := ">chr1:0-4\nAAAA\n>plus\nCCCC\n>minus\nGGGG\n>tx1\nAACCacgt\n>tx2\nacgtTTTT\n"
+    if out.String() != good {
+        t.Errorf("Invalid BED extraction: %q != %q", out.String(), good)
+    }
+
+    for _, bad := range []string{
+        "chr1\t5\t2",
+        "chr1\t0\t20\ttx\t0\t+\t0\t20\t0\t2\t2\t0,6",
+        "chr1\t0\t10\ttx\t0\t+\t0\t10\t0\t1\t12\t0",
+        "chr1\t0\t0",
+        "chr1\t4\t4",
+        "chr1\t0\t10\ttx\t0\t+\t0\t10\t0\t2\t0,4\t0,6",
+    } {
+        err = tb.ExtractBED(strings.NewReader(bad), &bytes.Buffer{})
+        if err == nil {
+            t.Errorf("Expected error for %q", bad)
+        }
+    }
+}
+
+func TestScanBED(t *testing.T) {
+    bed := "# header\r\nbrowser position chr1\r\nchr1 2 6 spaced\r\n\r\nchr1\t0\t4\t\t0\t-\r\n"
+
+    names := make([]string, 0)
+    err := ScanBED(strings.NewReader(bed), func(iv Interval, cols []string, line int) (error) {
+        names = append(names, iv.String()+"/"+cols[len(cols)-1])
+        return nil
+    })
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if strings.Join(names, ",") != "chr1:2-6/spaced,chr1:0-4/-" {
+        t.Errorf("Invalid BED records: %v", names)
+    }
+
+    // every BED reader accepts the same lines
+    ivs, err := ReadBEDIntervals(strings.NewReader(bed))
+    if err != nil || len(ivs) != 2 || ivs[1].End != 4 {
+        t.Errorf("Invalid BED intervals: %v %v", ivs, err)
+    }
+    blocks, err := ReadBED(strings.NewReader(bed))
+    if err != nil || len(blocks["chr1"]) != 2 {
+        t.Errorf("Invalid BED blocks: %v %v", blocks, err)
+    }
+
+    tb := newTestReader(t, map[string]string{"chr1": "AAAACCCC"})
+    var out bytes.Buffer
+    err = tb.ExtractBED(strings.NewReader(bed), &out)
+    if err != nil || out.String() != ">spaced\nAACC\n>chr1:0-4\nTTTT\n" {
+        t.Errorf("Invalid BED extraction: %q %v", out.String(), err)
+    }
+
+    err = ScanBED(strings.NewReader("chr1\t0\t4\nchr1\t4\t4\n"), func(iv Interval, cols []string, line int) (error) {
+        return nil
+    })
+    if err == nil || !strings.Contains(err.Error(), "line 2") {
+        t.Errorf("Expected error for empty interval on line 2: %v", err)
+    }
+}
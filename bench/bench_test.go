@@ -0,0 +1,217 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package bench
+
+import (
+    "testing"
+    "bytes"
+    "flag"
+    "io/ioutil"
+    "math/rand"
+    "os"
+    "path/filepath"
+    "sync"
+    "github.com/aebruno/twobit"
+    "github.com/aebruno/twobit/testfixtures"
+)
+
+var (
+    chroms    = flag.Int("genome.chroms", 4, "number of chromosomes in the synthetic genome")
+    size      = flag.Int("genome.size", 4000000, "bases per chromosome")
+    scaffolds = flag.Int("genome.scaffolds", 10000, "number of 1-6kb scaffolds added to the genome")
+    seed      = flag.Int64("genome.seed", 1, "random seed")
+)
+
+// genome is the synthetic genome shared by the benchmarks
+type genome struct {
+    seqs  map[string]string
+    names []string
+    data  []byte
+    path  string
+}
+
+var (
+    once   sync.Once
+    shared *genome
+    dir    string
+)
+
+func TestMain(m *testing.M) {
+    flag.Parse()
+    code := m.Run()
+    if dir != "" {
+        os.RemoveAll(dir)
+    }
+    os.Exit(code)
+}
+
+// Add a sequence to the genome, implements testfixtures.Adder
+func (g *genome) Add(name, seq string) (error) {
+    g.names = append(g.names, name)
+    g.seqs[name] = seq
+    return nil
+}
+
+// Build the synthetic genome once and write it to a temporary file
+func load(b *testing.B) (*genome) {
+    once.Do(func() {
+        g := &genome{seqs: make(map[string]string)}
+        err := testfixtures.Assembly(g, *seed, *chroms, *size, *scaffolds)
+        if err != nil {
+            panic(err)
+        }
+
+        w := twobit.NewWriter()
+        for _, name := range g.names {
+            w.Add(name, g.seqs[name])
+        }
+        var out bytes.Buffer
+        err = w.WriteTo(&out)
+        if err != nil {
+            panic(err)
+        }
+        g.data = out.Bytes()
+
+        dir, err = ioutil.TempDir("", "twobit-bench")
+        if err != nil {
+            panic(err)
+        }
+        g.path = filepath.Join(dir, "genome.2bit")
+        err = ioutil.WriteFile(g.path, g.data, 0644)
+        if err != nil {
+            panic(err)
+        }
+
+        shared = g
+    })
+
+    if *chroms < 1 {
+        b.Fatalf("At least one chromosome is required")
+    }
+    b.ResetTimer()
+
+    return shared
+}
+
+func BenchmarkOpen(b *testing.B) {
+    g := load(b)
+    for i := 0; i < b.N; i++ {
+        tb, err := twobit.Open(g.path)
+        if err != nil {
+            b.Fatal(err)
+        }
+        tb.Close()
+    }
+}
+
+func BenchmarkIndex(b *testing.B) {
+    g := load(b)
+    for i := 0; i < b.N; i++ {
+        tb, err := twobit.NewReader(bytes.NewReader(g.data))
+        if err != nil {
+            b.Fatal(err)
+        }
+        if tb.Count() != len(g.names) {
+            b.Fatalf("Invalid count: %d", tb.Count())
+        }
+    }
+}
+
+func BenchmarkReadRandom(b *testing.B) {
+    g := load(b)
+    tb, err := twobit.Open(g.path)
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer tb.Close()
+
+    const width = 100
+    rng := rand.New(rand.NewSource(*seed))
+    b.SetBytes(width)
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        name := g.names[rng.Intn(*chroms)]
+        start := rng.Intn(*size-width)
+        _, err := tb.ReadRange(name, start, start+width)
+        if err != nil {
+            b.Fatal(err)
+        }
+    }
+}
+
+func BenchmarkReadChromosome(b *testing.B) {
+    g := load(b)
+    tb, err := twobit.Open(g.path)
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer tb.Close()
+
+    b.SetBytes(int64(*size))
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        _, err := tb.Read(g.names[0])
+        if err != nil {
+            b.Fatal(err)
+        }
+    }
+}
+
+func BenchmarkPack(b *testing.B) {
+    g := load(b)
+    seq := g.seqs[g.names[0]]
+    b.SetBytes(int64(len(seq)))
+    for i := 0; i < b.N; i++ {
+        _, err := twobit.Pack(seq)
+        if err != nil {
+            b.Fatal(err)
+        }
+    }
+}
+
+func BenchmarkWrite(b *testing.B) {
+    g := load(b)
+    bases := 0
+    for _, seq := range g.seqs {
+        bases += len(seq)
+    }
+    b.SetBytes(int64(bases))
+    for i := 0; i < b.N; i++ {
+        w := twobit.NewWriter()
+        for _, name := range g.names {
+            err := w.Add(name, g.seqs[name])
+            if err != nil {
+                b.Fatal(err)
+            }
+        }
+        err := w.WriteTo(ioutil.Discard)
+        if err != nil {
+            b.Fatal(err)
+        }
+    }
+}
+
+// Regression test for the hot read path: once block tables are cached,
+// reading into a reused buffer must not allocate
+func TestReadRangeIntoAllocs(t *testing.T) {
+    w := twobit.NewWriter()
+    testfixtures.Assembly(w, *seed, 1, 100000, 0)
+    var out bytes.Buffer
+    w.WriteTo(&out)
+
+    tb, err := twobit.NewReader(bytes.NewReader(out.Bytes()))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    dst := make([]byte, 1000)
+    tb.ReadRangeInto(dst, "chr1", 0, 1000)
+    allocs := testing.AllocsPerRun(100, func() {
+        tb.ReadRangeInto(dst, "chr1", 5000, 6000)
+    })
+    if allocs != 0 {
+        t.Errorf("ReadRangeInto allocated %.1f times per read", allocs)
+    }
+}
@@ -0,0 +1,37 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+// Package bench holds the performance benchmarks of the twobit package. The
+// benchmarks run against a synthetic soft-masked genome with N gaps, built
+// once per run by testfixtures.Assembly, so no data files are needed:
+//
+//   go test -run x -bench . ./bench
+//
+// The size of the genome is set with flags after -args:
+//
+//   go test -run x -bench . ./bench -args -genome.chroms 2 -genome.size 50000000
+//
+//   -genome.chroms     number of chromosomes (default 4)
+//   -genome.size       bases per chromosome (default 4000000)
+//   -genome.scaffolds  number of 1-6kb scaffolds, chrUn_scaffold1... (default 10000)
+//   -genome.seed       random seed (default 1)
+//
+// Compare runs before and after a change with benchstat:
+//
+//   go test -run x -bench . -count 10 ./bench > old.txt
+//   go test -run x -bench . -count 10 ./bench > new.txt
+//   benchstat old.txt new.txt
+//
+// Baseline with the default genome (go1.27, linux/amd64, Intel Xeon):
+//
+//   BenchmarkOpen              2485454 ns/op
+//   BenchmarkIndex             2370144 ns/op
+//   BenchmarkReadRandom           2376 ns/op     42.08 MB/s
+//   BenchmarkReadChromosome    2672346 ns/op   1496.81 MB/s
+//   BenchmarkPack              6029515 ns/op    663.40 MB/s
+//   BenchmarkWrite           356520775 ns/op    122.89 MB/s
+//
+// TestReadRangeIntoAllocs runs with the regular tests and fails if the
+// cached read path starts allocating.
+package bench
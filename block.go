@@ -0,0 +1,191 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "sort"
+)
+
+// Block represents either blocks of Ns or masked (lower-case) blocks. Blocks
+// cover Start to Start+Length (0-based, end exclusive).
+type Block struct {
+    Start    int
+    Length   int
+}
+
+// Blocks is a list of blocks such as the N or mask blocks of a sequence
+type Blocks []*Block
+
+// Return end of block (exclusive)
+func (b *Block) End() int {
+    return b.Start+b.Length
+}
+
+// Return count of block.
+//
+// Deprecated: use the Length field.
+func (b *Block) Count() int {
+    return b.Length
+}
+
+// Returns true if b and o share at least one base
+func (b *Block) Overlaps(o *Block) (bool) {
+    return b.Start < o.End() && o.Start < b.End() && b.Length > 0 && o.Length > 0
+}
+
+// Return the bases shared by b and o. Returns false if they don't overlap.
+func (b *Block) Intersect(o *Block) (*Block, bool) {
+    if !b.Overlaps(o) {
+        return nil, false
+    }
+
+    start := b.Start
+    if o.Start > start {
+        start = o.Start
+    }
+    end := b.End()
+    if o.End() < end {
+        end = o.End()
+    }
+
+    return &Block{Start: start, Length: end-start}, true
+}
+
+// Return the union of b and o. Returns false if they neither overlap nor are
+// adjacent, in which case the union is not a single block.
+func (b *Block) Merge(o *Block) (*Block, bool) {
+    if b.Start > o.End() || o.Start > b.End() {
+        return nil, false
+    }
+
+    start := b.Start
+    if o.Start < start {
+        start = o.Start
+    }
+    end := b.End()
+    if o.End() > end {
+        end = o.End()
+    }
+
+    return &Block{Start: start, Length: end-start}, true
+}
+
+// Return the part of block b within start to end. The block does not overlap
+// the range if the returned start is not less than the returned end.
+func (b *Block) clip(start, end int) (int, int) {
+    lo := b.Start
+    if lo < start {
+        lo = start
+    }
+    hi := b.End()
+    if hi > end {
+        hi = end
+    }
+
+    return lo, hi
+}
+
+// Sort blocks in place by start then length
+func (bs Blocks) Sort() {
+    sort.Slice(bs, func(i, j int) bool {
+        if bs[i].Start != bs[j].Start {
+            return bs[i].Start < bs[j].Start
+        }
+        return bs[i].Length < bs[j].Length
+    })
+}
+
+// Return a sorted copy of bs with overlapping and adjacent blocks merged and
+// empty blocks removed. bs is not modified.
+func (bs Blocks) Normalize() (Blocks) {
+    sorted := make(Blocks, 0, len(bs))
+    for _, b := range bs {
+        if b.Length > 0 {
+            sorted = append(sorted, &Block{Start: b.Start, Length: b.Length})
+        }
+    }
+    sorted.Sort()
+
+    out := make(Blocks, 0, len(sorted))
+    for _, b := range sorted {
+        if n := len(out); n > 0 {
+            if m, ok := out[n-1].Merge(b); ok {
+                out[n-1] = m
+                continue
+            }
+        }
+        out = append(out, b)
+    }
+
+    return out
+}
+
+// Return a deep copy of bs
+func (bs Blocks) copy() (Blocks) {
+    out := make(Blocks, len(bs))
+    for i, b := range bs {
+        out[i] = &Block{Start: b.Start, Length: b.Length}
+    }
+
+    return out
+}
+
+// Return the bases covered by either bs or o as normalized blocks
+func (bs Blocks) Union(o Blocks) (Blocks) {
+    all := make(Blocks, 0, len(bs)+len(o))
+    all = append(all, bs...)
+    all = append(all, o...)
+
+    return all.Normalize()
+}
+
+// Return the bases covered by both bs and o as normalized blocks
+func (bs Blocks) Intersect(o Blocks) (Blocks) {
+    a := bs.Normalize()
+    b := o.Normalize()
+
+    out := make(Blocks, 0)
+    i, j := 0, 0
+    for i < len(a) && j < len(b) {
+        if x, ok := a[i].Intersect(b[j]); ok {
+            out = append(out, x)
+        }
+        if a[i].End() < b[j].End() {
+            i++
+        } else {
+            j++
+        }
+    }
+
+    return out
+}
+
+// Return the bases covered by bs but not by o as normalized blocks
+func (bs Blocks) Subtract(o Blocks) (Blocks) {
+    a := bs.Normalize()
+    b := o.Normalize()
+
+    out := make(Blocks, 0)
+    j := 0
+    for _, x := range a {
+        start := x.Start
+        for j < len(b) && b[j].End() <= start {
+            j++
+        }
+        for k := j; k < len(b) && b[k].Start < x.End(); k++ {
+            if b[k].Start > start {
+                out = append(out, &Block{Start: start, Length: b[k].Start-start})
+            }
+            if b[k].End() > start {
+                start = b[k].End()
+            }
+        }
+        if start < x.End() {
+            out = append(out, &Block{Start: start, Length: x.End()-start})
+        }
+    }
+
+    return out
+}
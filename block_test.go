@@ -0,0 +1,46 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "reflect"
+)
+
+func TestBlockOps(t *testing.T) {
+    a := &Block{Start: 2, Length: 5}
+    b := &Block{Start: 6, Length: 4}
+    c := &Block{Start: 7, Length: 1}
+
+    if !a.Overlaps(b) || a.Overlaps(c) || b.Overlaps(&Block{Start: 10, Length: 1}) {
+        t.Errorf("Invalid overlaps")
+    }
+
+    if i, ok := a.Intersect(b); !ok || *i != (Block{Start: 6, Length: 1}) {
+        t.Errorf("Invalid intersect: %v", i)
+    }
+
+    if _, ok := a.Intersect(c); ok {
+        t.Errorf("Expected no intersection")
+    }
+
+    if m, ok := a.Merge(c); !ok || *m != (Block{Start: 2, Length: 6}) {
+        t.Errorf("Invalid merge of adjacent blocks: %v", m)
+    }
+
+    if _, ok := a.Merge(&Block{Start: 8, Length: 1}); ok {
+        t.Errorf("Expected no merge of separated blocks")
+    }
+
+    blocks := Blocks{c, {Start: 20, Length: 0}, b, a, {Start: 12, Length: 2}}
+    good := Blocks{{Start: 2, Length: 8}, {Start: 12, Length: 2}}
+    if norm := blocks.Normalize(); !reflect.DeepEqual(norm, good) {
+        t.Errorf("Invalid normalized blocks: %v", norm)
+    }
+
+    if blocks[0] != c {
+        t.Errorf("Normalize modified its input")
+    }
+}
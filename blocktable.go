@@ -0,0 +1,297 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "fmt"
+    "io"
+    "sort"
+)
+
+// BlockMode selects how range reads access the N and mask block tables
+type BlockMode int
+
+const (
+    BLOCKS_AUTO   BlockMode = iota // choose per read, see BlockTables
+    BLOCKS_LOAD                    // parse and cache the whole tables on first access
+    BLOCKS_SEARCH                  // binary search the tables on disk for each range
+)
+
+// Sequences with at most this many N and mask blocks always have their
+// tables loaded by BLOCKS_AUTO
+const AUTO_LOAD_BLOCKS = 4096
+
+// Number of searches BLOCKS_AUTO makes on one sequence before loading its
+// tables, as repeated reads recover the cost of a full load
+const AUTO_SEARCH_READS = 64
+
+// Ranges covering at least 1/AUTO_RANGE_FRACTION of a sequence make
+// BLOCKS_AUTO load its tables, as decoding the range dominates the cost
+const AUTO_RANGE_FRACTION = 16
+
+// BlockTables sets how ReadRange, ReadRangeInto and ReadRangeMax access the
+// block tables of a sequence. With BLOCKS_LOAD the tables are parsed once and
+// cached, which is fastest for many reads on one sequence. With BLOCKS_SEARCH
+// the sorted starts array is binary searched on disk and only the blocks
+// overlapping the range are read, so a small read on a scaffold with hundreds
+// of thousands of blocks does not parse them all. BLOCKS_AUTO (the default)
+// loads small tables and searches large ones until the sequence has been read
+// AUTO_SEARCH_READS times or a range covers a large part of it. Tables
+// already cached by other calls are always used.
+func BlockTables(mode BlockMode) (ReadOption) {
+    return func(r *Reader) (error) {
+        if mode < BLOCKS_AUTO || mode > BLOCKS_SEARCH {
+            return fmt.Errorf("Invalid block mode: %d", mode)
+        }
+        r.blockMode = mode
+        return nil
+    }
+}
+
+// blockTable is the location of a block table in the file. The starts array
+// begins at offset and is followed by the sizes array.
+type blockTable struct {
+    offset  int64
+    count   int
+}
+
+// tableRecord is a sequence record whose block tables are left on disk
+type tableRecord struct {
+    dnaSize  uint32
+    offset   int64 // first byte of packed dna
+    nTable   blockTable
+    mTable   blockTable
+    searches int // ranges read by searching the tables
+}
+
+// Returns true if BLOCKS_AUTO should load the tables of tr to read start to
+// end, counting the read as a search otherwise
+func (tr *tableRecord) preferLoad(start, end int) (bool) {
+    if tr.nTable.count+tr.mTable.count <= AUTO_LOAD_BLOCKS {
+        return true
+    }
+    if (end-start)*AUTO_RANGE_FRACTION >= int(tr.dnaSize) {
+        return true
+    }
+    if tr.searches >= AUTO_SEARCH_READS {
+        return true
+    }
+
+    tr.searches++
+    return false
+}
+
+// Return the record for sequence name with the blocks overlapping start to
+// end, after normalizing the range as clampRange
+func (r *Reader) rangeRecord(name string, start, end int) (*seqRecord, int, int, error) {
+    load := func() (*seqRecord, int, int, error) {
+        rec, err := r.parseRecord(name, true)
+        if err != nil {
+            return nil, start, end, err
+        }
+        start, end, err = clampRange(start, end, int(rec.dnaSize))
+        return rec, start, end, err
+    }
+
+    if _, ok := r.records[name]; ok || r.blockMode == BLOCKS_LOAD || r.strict {
+        return load()
+    }
+
+    tr, err := r.parseTables(name)
+    if err != nil {
+        return nil, start, end, err
+    }
+
+    s, e, err := clampRange(start, end, int(tr.dnaSize))
+    if err != nil {
+        return nil, start, end, err
+    }
+    if r.blockMode == BLOCKS_AUTO && tr.preferLoad(s, e) {
+        delete(r.tables, name)
+        return load()
+    }
+    start, end = s, e
+
+    rec := &seqRecord{dnaSize: tr.dnaSize, offset: tr.offset}
+    rec.nBlocks, err = r.searchBlocks(tr.nTable, start, end)
+    if err != nil {
+        return nil, start, end, fmt.Errorf("Failed to read nBlocks: %s", err)
+    }
+    rec.mBlocks, err = r.searchBlocks(tr.mTable, start, end)
+    if err != nil {
+        return nil, start, end, fmt.Errorf("Failed to read mBlocks: %s", err)
+    }
+
+    return rec, start, end, nil
+}
+
+// Locate the block tables and packed dna of sequence name without reading
+// the tables. Results are cached.
+func (r *Reader) parseTables(name string) (*tableRecord, error) {
+    if tr, ok := r.tables[name]; ok {
+        return tr, nil
+    }
+
+    offset, ok, err := r.lookup(name)
+    if err != nil {
+        return nil, err
+    }
+    if !ok {
+        return nil, fmt.Errorf("Invalid sequence name: %s", name)
+    }
+
+    _, err = r.reader.Seek(offset, io.SeekStart)
+    if err != nil {
+        return nil, err
+    }
+
+    tr := new(tableRecord)
+    buf := make([]byte, 4)
+    _, err = io.ReadFull(r.reader, buf)
+    if err != nil {
+        return nil, fmt.Errorf("Failed to read dnaSize: %s", err)
+    }
+    tr.dnaSize = r.hdr.byteOrder.Uint32(buf)
+
+    _, err = toInt(int64(tr.dnaSize))
+    if err != nil {
+        return nil, fmt.Errorf("Sequence %s is too large: %s", name, err)
+    }
+
+    tr.nTable, err = r.skipBlockTable()
+    if err != nil {
+        return nil, fmt.Errorf("Failed to read nBlocks: %s", err)
+    }
+    tr.mTable, err = r.skipBlockTable()
+    if err != nil {
+        return nil, fmt.Errorf("Failed to read mBlocks: %s", err)
+    }
+
+    _, err = io.ReadFull(r.reader, buf)
+    if err != nil {
+        return nil, fmt.Errorf("Failed to read reserved: %s", err)
+    }
+    if r.hdr.byteOrder.Uint32(buf) != 0 {
+        return nil, fmt.Errorf("Invalid reserved")
+    }
+
+    // checkPacked records the packed offset in a seqRecord
+    rec := &seqRecord{dnaSize: tr.dnaSize}
+    err = r.checkPacked(name, rec)
+    if err != nil {
+        return nil, err
+    }
+    tr.offset = rec.offset
+
+    if r.tables == nil {
+        r.tables = make(map[string]*tableRecord)
+    }
+    r.tables[name] = tr
+
+    return tr, nil
+}
+
+// Read a block count and seek past the starts and sizes arrays that follow
+func (r *Reader) skipBlockTable() (blockTable, error) {
+    buf := make([]byte, 4)
+    _, err := io.ReadFull(r.reader, buf)
+    if err != nil {
+        return blockTable{}, fmt.Errorf("Failed to read blockCount: %s", err)
+    }
+
+    count := int64(r.hdr.byteOrder.Uint32(buf))
+    offset, err := r.reader.Seek(0, io.SeekCurrent)
+    if err != nil {
+        return blockTable{}, err
+    }
+
+    end := offset+2*RECORD_BLOCK_FIELD_LEN*count
+    if r.size >= 0 && end > r.size {
+        return blockTable{}, fmt.Errorf("%w: block table of %d entries extends past end of file", ErrTruncated, count)
+    }
+
+    _, err = r.reader.Seek(end, io.SeekStart)
+    if err != nil {
+        return blockTable{}, err
+    }
+
+    return blockTable{offset: offset, count: int(count)}, nil
+}
+
+// Read entries i to j of the uint32 array at offset
+func (r *Reader) readUint32s(offset int64, i, j int) ([]uint32, error) {
+    buf := make([]byte, RECORD_BLOCK_FIELD_LEN*(j-i))
+    _, err := r.reader.Seek(offset+int64(RECORD_BLOCK_FIELD_LEN*i), io.SeekStart)
+    if err != nil {
+        return nil, err
+    }
+    _, err = io.ReadFull(r.reader, buf)
+    if err != nil {
+        return nil, err
+    }
+
+    vals := make([]uint32, j-i)
+    for k := range vals {
+        vals[k] = r.hdr.byteOrder.Uint32(buf[RECORD_BLOCK_FIELD_LEN*k:])
+    }
+
+    return vals, nil
+}
+
+// Return the blocks of t overlapping start to end. The starts array is
+// binary searched on disk, which relies on the blocks being sorted and not
+// overlapping as in every valid file. Blocks read out of order are an error.
+func (r *Reader) searchBlocks(t blockTable, start, end int) (Blocks, error) {
+    var err error
+    at := func(i int) (int) {
+        if err != nil {
+            return 0
+        }
+        var v []uint32
+        v, err = r.readUint32s(t.offset, i, i+1)
+        if err != nil {
+            return 0
+        }
+        return int(v[0])
+    }
+
+    // the last block starting at or before start may extend into the range,
+    // every later block starting before end overlaps it
+    lo := sort.Search(t.count, func(i int) bool { return at(i) > start })
+    if lo > 0 {
+        lo--
+    }
+    hi := lo+sort.Search(t.count-lo, func(i int) bool { return at(lo+i) >= end })
+    if err != nil {
+        return nil, err
+    }
+    if lo >= hi {
+        return Blocks{}, nil
+    }
+
+    starts, err := r.readUint32s(t.offset, lo, hi)
+    if err != nil {
+        return nil, err
+    }
+    sizes, err := r.readUint32s(t.offset+int64(RECORD_BLOCK_FIELD_LEN*t.count), lo, hi)
+    if err != nil {
+        return nil, err
+    }
+
+    blocks := make(Blocks, 0, len(starts))
+    prev := 0
+    for k := range starts {
+        b := &Block{Start: int(starts[k]), Length: int(sizes[k])}
+        if b.Start < prev {
+            return nil, fmt.Errorf("Invalid block table: blocks are not sorted at %d", b.Start)
+        }
+        prev = b.End()
+        if b.End() > start && b.Start < end {
+            blocks = append(blocks, b)
+        }
+    }
+
+    return blocks, nil
+}
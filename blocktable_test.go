@@ -0,0 +1,122 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "math/rand"
+    "strings"
+)
+
+// Return a fragmented scaffold of n bases with many short N and mask blocks
+func fragmented(rng *rand.Rand, n int) (string) {
+    var sb strings.Builder
+    for sb.Len() < n {
+        sb.WriteString(strings.Repeat(string("ACGTNacgtn"[rng.Intn(10)]), 1+rng.Intn(20)))
+    }
+
+    return sb.String()[:n]
+}
+
+func TestBlockSearch(t *testing.T) {
+    rng := rand.New(rand.NewSource(1))
+    seq := fragmented(rng, 20000)
+
+    w := NewWriter()
+    w.Add("scaffold", seq)
+    w.Add("chr1", "ACGTacgtNNNN")
+    var out bytes.Buffer
+    w.WriteTo(&out)
+
+    tb, err := NewReader(bytes.NewReader(out.Bytes()), BlockTables(BLOCKS_SEARCH))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    for i := 0; i < 500; i++ {
+        start := rng.Intn(len(seq))
+        end := start+1+rng.Intn(200)
+        if end > len(seq) {
+            end = len(seq)
+        }
+        got, err := tb.ReadRange("scaffold", start, end)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        if string(got) != seq[start:end] {
+            t.Fatalf("Invalid range %d-%d: %s != %s", start, end, got, seq[start:end])
+        }
+    }
+
+    if _, ok := tb.records["scaffold"]; ok {
+        t.Errorf("Block tables loaded in search mode")
+    }
+
+    got, err := tb.Read("chr1")
+    if err != nil || string(got) != "ACGTacgtNNNN" {
+        t.Errorf("Invalid sequence: %s %v", got, err)
+    }
+
+    _, err = tb.ReadRange("scaffold", 0, len(seq)+1)
+    if err == nil {
+        t.Errorf("Range past end accepted")
+    }
+
+    _, err = NewReader(bytes.NewReader(out.Bytes()), BlockTables(BlockMode(9)))
+    if err == nil {
+        t.Errorf("Invalid block mode accepted")
+    }
+}
+
+func TestBlockAuto(t *testing.T) {
+    rng := rand.New(rand.NewSource(1))
+    seq := fragmented(rng, 200000)
+
+    w := NewWriter()
+    w.Add("scaffold", seq)
+    w.Add("chr1", "ACGTacgtNNNN")
+    var out bytes.Buffer
+    w.WriteTo(&out)
+
+    tb, err := NewReader(bytes.NewReader(out.Bytes()))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    // small tables are loaded on first read
+    tb.ReadRange("chr1", 0, 4)
+    if _, ok := tb.records["chr1"]; !ok {
+        t.Errorf("Small block tables not loaded")
+    }
+
+    // large tables are searched until read repeatedly
+    for i := 0; i < AUTO_SEARCH_READS; i++ {
+        got, err := tb.ReadRange("scaffold", i*100, i*100+50)
+        if err != nil || string(got) != seq[i*100:i*100+50] {
+            t.Fatalf("Invalid range: %s %v", got, err)
+        }
+        if _, ok := tb.records["scaffold"]; ok {
+            t.Fatalf("Large block tables loaded after %d reads", i+1)
+        }
+    }
+    got, err := tb.ReadRange("scaffold", 10, 20)
+    if err != nil || string(got) != seq[10:20] {
+        t.Fatalf("Invalid range: %s %v", got, err)
+    }
+    if _, ok := tb.records["scaffold"]; !ok {
+        t.Errorf("Block tables not loaded after %d reads", AUTO_SEARCH_READS)
+    }
+
+    // large ranges load the tables
+    tb, _ = NewReader(bytes.NewReader(out.Bytes()))
+    got, err = tb.ReadRange("scaffold", 0, len(seq)/2)
+    if err != nil || string(got) != seq[:len(seq)/2] {
+        t.Fatalf("Invalid range: %v", err)
+    }
+    if _, ok := tb.records["scaffold"]; !ok {
+        t.Errorf("Block tables not loaded for a large range")
+    }
+}
@@ -0,0 +1,123 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "fmt"
+    "io"
+)
+
+// bufferedReadSeeker buffers reads from an io.ReadSeeker. Parsing the index
+// and record headers issues many tiny reads which are served from the buffer.
+// Seeking within the buffered window is free, seeking outside it drops the
+// buffer. If read ahead is enabled, a read continuing exactly where the
+// previous underlying read stopped is treated as a sequential scan and fills
+// the larger read ahead buffer instead.
+type bufferedReadSeeker struct {
+    reader    io.ReadSeeker
+    buf       []byte
+    ahead     []byte // read ahead buffer, allocated on first sequential read
+    aheadSize int
+    data      []byte // buffer holding the current window, buf or ahead
+    start     int64 // file offset of buf[0]
+    n         int   // number of valid bytes in buf
+    pos       int64 // logical read position
+    upos      int64 // position of the underlying reader, -1 if unknown
+}
+
+func newBufferedReadSeeker(r io.ReadSeeker, size int) (*bufferedReadSeeker) {
+    return &bufferedReadSeeker{reader: r, buf: make([]byte, size), upos: -1}
+}
+
+// Position the underlying reader at pos
+func (b *bufferedReadSeeker) seekUnderlying(pos int64) (error) {
+    if b.upos == pos {
+        return nil
+    }
+
+    _, err := b.reader.Seek(pos, 0)
+    if err != nil {
+        b.upos = -1
+        return err
+    }
+    b.upos = pos
+
+    return nil
+}
+
+func (b *bufferedReadSeeker) Read(p []byte) (int, error) {
+    if len(p) == 0 {
+        return 0, nil
+    }
+
+    if b.pos >= b.start && b.pos < b.start+int64(b.n) {
+        n := copy(p, b.data[b.pos-b.start:b.n])
+        b.pos += int64(n)
+        return n, nil
+    }
+
+    // decide before seeking, which always leaves upos at pos
+    sequential := b.pos > 0 && b.pos == b.upos
+    err := b.seekUnderlying(b.pos)
+    if err != nil {
+        return 0, err
+    }
+
+    buf := b.buf
+    if b.aheadSize > len(b.buf) && sequential {
+        if b.ahead == nil {
+            b.ahead = make([]byte, b.aheadSize)
+        }
+        buf = b.ahead
+    }
+
+    // large reads bypass the buffer
+    if len(p) >= len(buf) {
+        n, err := b.reader.Read(p)
+        b.pos += int64(n)
+        b.upos += int64(n)
+        return n, err
+    }
+
+    n, err := io.ReadAtLeast(b.reader, buf, 1)
+    b.data = buf
+    b.start = b.pos
+    b.n = n
+    b.upos += int64(n)
+    if n == 0 {
+        return 0, err
+    }
+
+    n = copy(p, b.data[0:b.n])
+    b.pos += int64(n)
+
+    return n, nil
+}
+
+func (b *bufferedReadSeeker) Seek(offset int64, whence int) (int64, error) {
+    switch whence {
+    case 0:
+        b.pos = offset
+    case 1:
+        b.pos += offset
+    case 2:
+        pos, err := b.reader.Seek(offset, 2)
+        if err != nil {
+            b.upos = -1
+            return b.pos, err
+        }
+        b.upos = pos
+        b.pos = pos
+        b.n = 0
+    default:
+        return b.pos, fmt.Errorf("Invalid whence: %d", whence)
+    }
+
+    if b.pos < 0 {
+        return b.pos, fmt.Errorf("Invalid negative offset: %d", b.pos)
+    }
+
+    return b.pos, nil
+}
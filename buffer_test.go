@@ -0,0 +1,162 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "io"
+    "strings"
+)
+
+// countingReadSeeker counts calls to Read on the underlying reader
+type countingReadSeeker struct {
+    io.ReadSeeker
+    reads   int
+    largest int // largest read requested
+}
+
+func (c *countingReadSeeker) Read(p []byte) (int, error) {
+    c.reads++
+    if len(p) > c.largest {
+        c.largest = len(p)
+    }
+    return c.ReadSeeker.Read(p)
+}
+
+func TestBufferedReadSeeker(t *testing.T) {
+    data := []byte("0123456789abcdefghij")
+    c := &countingReadSeeker{ReadSeeker: bytes.NewReader(data)}
+    b := newBufferedReadSeeker(c, 8)
+
+    p := make([]byte, 2)
+    for i := 0; i < 4; i++ {
+        _, err := io.ReadFull(b, p)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+    }
+    if string(p) != "67" || c.reads != 1 {
+        t.Errorf("Invalid buffered read: %s after %d reads", p, c.reads)
+    }
+
+    b.Seek(3, 0)
+    io.ReadFull(b, p)
+    if string(p) != "34" || c.reads != 1 {
+        t.Errorf("Invalid read after seek within buffer: %s after %d reads", p, c.reads)
+    }
+
+    b.Seek(15, 0)
+    io.ReadFull(b, p)
+    if string(p) != "fg" || c.reads != 2 {
+        t.Errorf("Invalid read after seek outside buffer: %s after %d reads", p, c.reads)
+    }
+
+    pos, _ := b.Seek(0, 1)
+    if pos != 17 {
+        t.Errorf("Invalid position: %d != %d", pos, 17)
+    }
+
+    large := make([]byte, 10)
+    b.Seek(0, 0)
+    io.ReadFull(b, large)
+    if string(large) != "0123456789" {
+        t.Errorf("Invalid large read: %s", large)
+    }
+}
+
+func TestBufferSize(t *testing.T) {
+    data := []byte("ACTgcctttnnnNantnaCgc")
+    w := NewWriter()
+    w.Add("ex1", string(data))
+    var out bytes.Buffer
+    w.WriteTo(&out)
+
+    for _, size := range []int{0, 1, 3, 64} {
+        tb, err := NewReader(bytes.NewReader(out.Bytes()), BufferSize(size))
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+
+        seq, err := tb.ReadRange("ex1", 2, 19)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        if string(seq) != string(data[2:19]) {
+            t.Errorf("Invalid sequence with buffer size %d: %s", size, seq)
+        }
+    }
+}
+
+func TestReadAhead(t *testing.T) {
+    w := NewWriter()
+    w.Add("random", strings.Repeat("ACGTTGCA", 1<<14))
+    var out bytes.Buffer
+    w.WriteTo(&out)
+
+    reads := func(opts ...ReadOption) (int) {
+        c := &countingReadSeeker{ReadSeeker: bytes.NewReader(out.Bytes())}
+        tb, err := NewReader(c, opts...)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+
+        c.reads = 0
+        seq, err := tb.Read("random")
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        if len(seq) != 8<<14 || string(seq[0:8]) != "ACGTTGCA" {
+            t.Errorf("Invalid sequence read with read ahead")
+        }
+
+        return c.reads
+    }
+
+    without := reads()
+    with := reads(ReadAhead(1<<16))
+    if with >= without {
+        t.Errorf("Read ahead did not reduce reads: %d >= %d", with, without)
+    }
+
+    if reads(BufferSize(0), ReadAhead(1<<16)) >= without {
+        t.Errorf("Read ahead without buffer did not reduce reads")
+    }
+}
+
+func TestReadAheadRandomAccess(t *testing.T) {
+    w := NewWriter()
+    w.Add("random", strings.Repeat("ACGTTGCA", 1<<14))
+    var out bytes.Buffer
+    w.WriteTo(&out)
+
+    c := &countingReadSeeker{ReadSeeker: bytes.NewReader(out.Bytes())}
+    tb, err := NewReader(c, BufferSize(64), ReadAhead(1<<16))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    c.largest = 0
+    for _, pos := range []int{50000, 10, 90000, 30000, 70000} {
+        base, err := tb.Base("random", pos)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        if base != "ACGTTGCA"[pos%8] {
+            t.Errorf("Invalid base at %d: %c", pos, base)
+        }
+    }
+    if c.largest > 64 {
+        t.Errorf("Random access read ahead: largest read %d bytes", c.largest)
+    }
+
+    _, err = tb.Read("random")
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if c.largest != 1<<16 {
+        t.Errorf("Sequential scan did not read ahead: largest read %d bytes", c.largest)
+    }
+}
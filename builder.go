@@ -0,0 +1,222 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "crypto/sha256"
+    "fmt"
+    "hash"
+    "math"
+)
+
+// seqBuilder packs a sequence incrementally as chunks are appended
+type seqBuilder struct {
+    name       string
+    rec        *seqRecord
+    size       int
+    partial    byte
+    nPartial   int
+    digest     hash.Hash
+    nCount     int // N bases
+    other      int // bases other than ACGTN
+    aminoAcid  int // letters only used for amino acids, counted in other
+    sampled    bool // checked by sampleNucleotide
+}
+
+// Extend blocks with a block of count bases at pos, merging it into the last
+// block if they are adjacent
+func extendBlocks(blocks Blocks, pos, count int) (Blocks) {
+    if n := len(blocks); n > 0 && blocks[n-1].Start+blocks[n-1].Length == pos {
+        blocks[n-1].Length += count
+        return blocks
+    }
+
+    return append(blocks, &Block{Start: pos, Length: count})
+}
+
+// Append the bases in seq
+func (b *seqBuilder) append(seq string) {
+    if b.digest != nil {
+        b.digest.Write([]byte(seq))
+    }
+
+    for i := 0; i < len(seq); i++ {
+        c := seq[i]
+        if c == 'N' || c == 'n' {
+            b.rec.nBlocks = extendBlocks(b.rec.nBlocks, b.size, 1)
+            b.nCount++
+        } else if !acgtn[c] {
+            b.other++
+            if aminoAcid[c] {
+                b.aminoAcid++
+            }
+        }
+        if c >= 'a' && c <= 'z' {
+            b.rec.mBlocks = extendBlocks(b.rec.mBlocks, b.size, 1)
+        }
+
+        b.partial = b.partial<<2 | NT2BYTES[c]
+        b.nPartial++
+        if b.nPartial == BASES_PER_BYTE {
+            b.rec.sequence = append(b.rec.sequence, b.partial)
+            b.partial = 0
+            b.nPartial = 0
+        }
+        b.size++
+    }
+}
+
+// StartSequence begins a new sequence with name which is built from pieces
+// with AppendChunk and finished with EndSequence. This allows building large
+// sequences, for example chromosomes assembled from ordered contigs, without
+// concatenating them in memory first.
+func (w *Writer) StartSequence(name string) (error) {
+    if w.closed {
+        return ErrClosed
+    }
+    if w.building != nil {
+        return fmt.Errorf("Sequence %s was started but not ended", w.building.name)
+    }
+    err := w.checkName(name)
+    if err != nil {
+        return err
+    }
+
+    w.building = w.newBuilder(name)
+
+    return nil
+}
+
+// Return a builder for a new sequence name
+func (w *Writer) newBuilder(name string) (*seqBuilder) {
+    b := &seqBuilder{
+        name: name,
+        rec: &seqRecord{nBlocks: make(Blocks, 0), mBlocks: make(Blocks, 0)},
+    }
+
+    // content digests are only needed to find duplicates
+    if w.dedup != dedupOff {
+        b.digest = sha256.New()
+    }
+
+    return b
+}
+
+// AppendChunk appends seq to the sequence started with StartSequence
+func (w *Writer) AppendChunk(seq string) (error) {
+    if w.building == nil {
+        return fmt.Errorf("No sequence started")
+    }
+    if uint64(w.building.size)+uint64(len(seq)) > math.MaxUint32 {
+        return fmt.Errorf("Sequence %s is longer than %d bases", w.building.name, uint32(math.MaxUint32))
+    }
+
+    w.building.append(seq)
+
+    // stop early if the start of the sequence looks like protein
+    err := w.sampleNucleotide(w.building, false)
+    if err != nil {
+        w.building = nil
+        return err
+    }
+
+    return nil
+}
+
+// EndSequence finishes the sequence started with StartSequence and adds it to
+// the Writer
+func (w *Writer) EndSequence() (error) {
+    b := w.building
+    if b == nil {
+        return fmt.Errorf("No sequence started")
+    }
+    w.building = nil
+
+    return w.endBuilder(b)
+}
+
+// Check and add the sequence built by b
+func (w *Writer) endBuilder(b *seqBuilder) (error) {
+    err := w.sampleNucleotide(b, true)
+    if err != nil {
+        return err
+    }
+
+    err = w.checkQC(b.name, b.size, b.nCount, b.other)
+    if err != nil {
+        return err
+    }
+
+    if b.digest != nil {
+        var sum [sha256.Size]byte
+        copy(sum[:], b.digest.Sum(nil))
+        err = w.checkDuplicate(b.name, sum)
+        if err != nil {
+            return err
+        }
+    }
+
+    if b.nPartial > 0 {
+        b.rec.sequence = append(b.rec.sequence, b.partial<<uint(2*(BASES_PER_BYTE-b.nPartial)))
+    }
+    b.rec.dnaSize = uint32(b.size)
+
+    return w.addRecord(b.name, b.rec)
+}
+
+// Append a gap of n Ns. The gap is stored as an N block and packed as zero
+// bytes without materializing a string of Ns.
+func (b *seqBuilder) appendGap(n int) {
+    if b.digest != nil {
+        gap := make([]byte, defaultBufSize)
+        fill(gap, BASE_N)
+        for left := n; left > 0; left -= len(gap) {
+            if left < len(gap) {
+                gap = gap[:left]
+            }
+            b.digest.Write(gap)
+        }
+    }
+
+    b.rec.nBlocks = extendBlocks(b.rec.nBlocks, b.size, n)
+    b.nCount += n
+    b.size += n
+
+    // finish the partial byte
+    for n > 0 && b.nPartial > 0 {
+        b.partial <<= 2
+        b.nPartial++
+        n--
+        if b.nPartial == BASES_PER_BYTE {
+            b.rec.sequence = append(b.rec.sequence, b.partial)
+            b.partial = 0
+            b.nPartial = 0
+        }
+    }
+
+    b.rec.sequence = append(b.rec.sequence, make([]byte, n/BASES_PER_BYTE)...)
+    b.nPartial = n%BASES_PER_BYTE
+}
+
+// AppendGap appends a gap of n Ns to the sequence started with StartSequence
+// without materializing a string of length n, so large gaps such as
+// centromeres are cheap to insert.
+func (w *Writer) AppendGap(n int) (error) {
+    if w.building == nil {
+        return fmt.Errorf("No sequence started")
+    }
+    if n < 0 {
+        return fmt.Errorf("Invalid gap size: %d", n)
+    }
+    if uint64(w.building.size)+uint64(n) > math.MaxUint32 {
+        return fmt.Errorf("Sequence %s is longer than %d bases", w.building.name, uint32(math.MaxUint32))
+    }
+
+    if n > 0 {
+        w.building.appendGap(n)
+    }
+
+    return nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "reflect"
+    "strings"
+)
+
+func TestSequenceBuilder(t *testing.T) {
+    seq := "ACTgcctttnnnNantnaCgc"
+
+    w := NewWriter()
+    w.Add("whole", seq)
+
+    err := w.StartSequence("pieces")
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    for _, chunk := range []string{"ACTg", "c", "", "ctttnn", "nNantnaCg", "c"} {
+        err = w.AppendChunk(chunk)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+    }
+
+    err = w.StartSequence("other")
+    if err == nil {
+        t.Errorf("Expected error starting a sequence before ending the last")
+    }
+
+    err = w.EndSequence()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    whole := w.records["whole"]
+    pieces := w.records["pieces"]
+    if !reflect.DeepEqual(whole, pieces) {
+        t.Errorf("Built sequence differs from added sequence: %#v != %#v", pieces, whole)
+    }
+
+    err = w.AppendChunk("ACGT")
+    if err == nil {
+        t.Errorf("Expected error appending without a started sequence")
+    }
+}
+
+func TestAppendGap(t *testing.T) {
+    w := NewWriter(FlagDuplicates())
+    w.Add("whole", "ACgNNNNNNNNNNnnnT" + strings.Repeat("N", 4099) + "A")
+
+    w.StartSequence("pieces")
+    w.AppendChunk("ACgN")
+    w.AppendGap(9)
+    w.AppendChunk("nnnT")
+    w.AppendGap(0)
+    w.AppendGap(4000)
+    w.AppendGap(99)
+    w.AppendChunk("A")
+    err := w.EndSequence()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    if !reflect.DeepEqual(w.records["whole"], w.records["pieces"]) {
+        t.Errorf("Sequence with gaps differs from added sequence")
+    }
+
+    if w.Duplicates()["pieces"] != "whole" {
+        t.Errorf("Sequence with gaps not flagged as duplicate")
+    }
+}
@@ -0,0 +1,93 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "fmt"
+    "strings"
+)
+
+// Convert lower case ASCII letters in seq to upper case in place, removing
+// any masking
+func ToUpper(seq []byte) {
+    for i, b := range seq {
+        if b >= 'a' && b <= 'z' {
+            seq[i] = b - 32
+        }
+    }
+}
+
+// Convert upper case ASCII letters in seq to lower case in place, masking
+// the whole sequence
+func ToLower(seq []byte) {
+    toLower(seq)
+}
+
+// Render seq with mask applied in place: bases within mask blocks are lower
+// case and all others upper case. seq holds a decoded range starting at
+// position start of the sequence the mask coordinates refer to, for example
+// the result of ReadRange(name, start, end). Mask blocks may be in any order
+// and overlap. This allows trying alternative masks (see ReadBED and the
+// Blocks set operations) without rewriting the file.
+func ApplyCaseFromBlocks(seq []byte, start int, mask Blocks) {
+    ToUpper(seq)
+
+    end := start+len(seq)
+    for _, b := range mask {
+        lo, hi := b.clip(start, end)
+        if lo < hi {
+            toLower(seq[lo-start:hi-start])
+        }
+    }
+}
+
+// CasePolicy selects the case of bases written when exporting sequences
+type CasePolicy int
+
+const (
+    CASE_AS_STORED CasePolicy = iota // soft masked bases lower case, as read
+    CASE_UPPER                       // all upper case, dropping the mask (twoBitToFa -noMask)
+    CASE_LOWER                       // all lower case
+    CASE_MASK_ONLY                   // soft masked A, C, G and T lower case, N and gaps always upper case
+)
+
+var casePolicyNames = []string{"stored", "upper", "lower", "mask"}
+
+func (p CasePolicy) String() (string) {
+    if p < 0 || int(p) >= len(casePolicyNames) {
+        return fmt.Sprintf("CasePolicy(%d)", int(p))
+    }
+
+    return casePolicyNames[p]
+}
+
+// ParseCasePolicy returns the CasePolicy named s: stored, upper, lower or
+// mask
+func ParseCasePolicy(s string) (CasePolicy, error) {
+    for i, name := range casePolicyNames {
+        if s == name {
+            return CasePolicy(i), nil
+        }
+    }
+
+    return CASE_AS_STORED, fmt.Errorf("Invalid case policy %q, expected one of %s", s, strings.Join(casePolicyNames, ", "))
+}
+
+// Apply converts seq, as returned by Read or ReadRange, to the case of p in
+// place
+func (p CasePolicy) Apply(seq []byte) {
+    switch p {
+    case CASE_UPPER:
+        ToUpper(seq)
+    case CASE_LOWER:
+        toLower(seq)
+    case CASE_MASK_ONLY:
+        for i, b := range seq {
+            if b >= 'a' && b <= 'z' && b != 'a' && b != 'c' && b != 'g' && b != 't' {
+                seq[i] = b - 32
+            }
+        }
+    }
+}
@@ -0,0 +1,71 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+)
+
+func TestCase(t *testing.T) {
+    seq := []byte("ACgtNn-acGT")
+    ToUpper(seq)
+    if string(seq) != "ACGTNN-ACGT" {
+        t.Errorf("Invalid upper case: %s", seq)
+    }
+    ToLower(seq)
+    if string(seq) != "acgtnn-acgt" {
+        t.Errorf("Invalid lower case: %s", seq)
+    }
+
+    tb := newTestReader(t, map[string]string{"chr1": "acgtACGTacgtACGTacgt"})
+    seq, err := tb.ReadRange("chr1", 4, 16)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    mask := Blocks{{Start: 14, Length: 10}, {Start: 0, Length: 6}, {Start: 9, Length: 2}}
+    ApplyCaseFromBlocks(seq, 4, mask)
+    if string(seq) != "acGTAcgTACgt" {
+        t.Errorf("Invalid masked sequence: %s", seq)
+    }
+
+    ApplyCaseFromBlocks(seq, 4, nil)
+    if string(seq) != "ACGTACGTACGT" {
+        t.Errorf("Invalid unmasked sequence: %s", seq)
+    }
+}
+
+func TestCasePolicy(t *testing.T) {
+    tests := []struct {
+        policy  string
+        out     string
+    }{
+        {"stored", "ACgtNnn-acGT"},
+        {"upper", "ACGTNNN-ACGT"},
+        {"lower", "acgtnnn-acgt"},
+        {"mask", "ACgtNNN-acGT"},
+    }
+
+    for _, test := range tests {
+        p, err := ParseCasePolicy(test.policy)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        if p.String() != test.policy {
+            t.Errorf("Invalid policy name: %s != %s", p, test.policy)
+        }
+
+        seq := []byte("ACgtNnn-acGT")
+        p.Apply(seq)
+        if string(seq) != test.out {
+            t.Errorf("%s: %s != %s", test.policy, seq, test.out)
+        }
+    }
+
+    _, err := ParseCasePolicy("title")
+    if err == nil {
+        t.Errorf("Invalid policy accepted")
+    }
+}
@@ -0,0 +1,328 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "io"
+    "os"
+    "bufio"
+    "crypto/sha256"
+    "encoding/binary"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "hash/crc32"
+    "path/filepath"
+    "time"
+)
+
+// Minimum time between syncs of an import checkpoint to disk
+const CHECKPOINT_INTERVAL = 30*time.Second
+
+// Files in a checkpoint directory
+const (
+    CHECKPOINT_LOG     = "checkpoint.jsonl"
+    CHECKPOINT_RECORDS = "records"
+)
+
+// Bytes at the start of the input hashed to recognize it on resume
+const checkpointHeadSize = 64*1024
+
+// checkpointInput identifies the FASTA file a checkpoint was made from. It
+// is the first line of the log.
+type checkpointInput struct {
+    Size  int64  `json:"size"`
+    Head  string `json:"head"` // sha256 of the first checkpointHeadSize bytes
+}
+
+// checkpointEntry logs one sequence packed by ImportFastaCheckpoint
+type checkpointEntry struct {
+    Name    string `json:"name"`
+    Header  string `json:"header"`
+    Offset  int64  `json:"offset"` // of the encoded record in the records file
+    Size    int    `json:"size"`
+    CRC     uint32 `json:"crc"`    // CRC-32 (IEEE) of the encoded record
+    N       int    `json:"n"`
+    Other   int    `json:"other"`
+    SHA256  string `json:"sha256,omitempty"` // content digest if duplicates are checked
+    Next    int64  `json:"next"`   // input offset just past the sequence
+}
+
+// Identify the input read from in
+func identifyInput(in io.ReadSeeker) (checkpointInput, error) {
+    size, err := in.Seek(0, io.SeekEnd)
+    if err != nil {
+        return checkpointInput{}, err
+    }
+    _, err = in.Seek(0, io.SeekStart)
+    if err != nil {
+        return checkpointInput{}, err
+    }
+
+    h := sha256.New()
+    _, err = io.CopyN(h, in, checkpointHeadSize)
+    if err != nil && err != io.EOF {
+        return checkpointInput{}, err
+    }
+
+    return checkpointInput{Size: size, Head: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// Decode a record encoded by seqRecord.encode
+func decodeRecord(name string, buf []byte) (*seqRecord, error) {
+    rec := new(seqRecord)
+    next := func() (uint32, error) {
+        if len(buf) < 4 {
+            return 0, fmt.Errorf("%w: record of %s is truncated", ErrCorrupt, name)
+        }
+        v := binary.LittleEndian.Uint32(buf)
+        buf = buf[4:]
+        return v, nil
+    }
+    blocks := func() (Blocks, error) {
+        count, err := next()
+        if err != nil {
+            return nil, err
+        }
+        if uint64(len(buf)) < 2*RECORD_BLOCK_FIELD_LEN*uint64(count) {
+            return nil, fmt.Errorf("%w: record of %s is truncated", ErrCorrupt, name)
+        }
+        bs := make(Blocks, count)
+        for i := range bs {
+            bs[i] = &Block{
+                Start: int(binary.LittleEndian.Uint32(buf[RECORD_BLOCK_FIELD_LEN*i:])),
+                Length: int(binary.LittleEndian.Uint32(buf[RECORD_BLOCK_FIELD_LEN*(len(bs)+i):])),
+            }
+        }
+        buf = buf[2*RECORD_BLOCK_FIELD_LEN*len(bs):]
+        return bs, nil
+    }
+
+    size, err := next()
+    if err != nil {
+        return nil, err
+    }
+    rec.dnaSize = size
+    rec.nBlocks, err = blocks()
+    if err != nil {
+        return nil, err
+    }
+    rec.mBlocks, err = blocks()
+    if err != nil {
+        return nil, err
+    }
+    _, err = next()
+    if err != nil {
+        return nil, err
+    }
+    if int64(len(buf)) != packedSize64(int64(rec.dnaSize)) {
+        return nil, fmt.Errorf("%w: record of %s has %d packed bytes for %d bases", ErrCorrupt, name, len(buf), rec.dnaSize)
+    }
+    rec.sequence = buf
+
+    err = rec.checkBlocks(name)
+    if err != nil {
+        return nil, err
+    }
+
+    return rec, nil
+}
+
+// Add the sequences logged in a checkpoint to w, returning the input offset
+// to resume from and the valid lengths of the log and records files
+func (w *Writer) restoreCheckpoint(logFile, records *os.File, input checkpointInput) (int64, int64, int64, error) {
+    r := bufio.NewReader(logFile)
+    line, err := r.ReadBytes('\n')
+    if err == io.EOF && len(line) == 0 {
+        return 0, 0, 0, nil
+    }
+
+    var logged checkpointInput
+    if err != nil || json.Unmarshal(line, &logged) != nil {
+        // the first line was never completed
+        return 0, 0, 0, nil
+    }
+    if logged != input {
+        return 0, 0, 0, fmt.Errorf("Checkpoint %s is for a different input", logFile.Name())
+    }
+
+    resume, logEnd, recEnd := int64(0), int64(len(line)), int64(0)
+    for {
+        line, err = r.ReadBytes('\n')
+        if err != nil {
+            // a line without a newline was cut short by a crash
+            break
+        }
+
+        var e checkpointEntry
+        if json.Unmarshal(line, &e) != nil || e.Offset != recEnd || e.Size < 0 {
+            break
+        }
+        buf := make([]byte, e.Size)
+        _, err = records.ReadAt(buf, e.Offset)
+        if err != nil || crc32.ChecksumIEEE(buf) != e.CRC {
+            break
+        }
+        rec, err := decodeRecord(e.Name, buf)
+        if err != nil {
+            break
+        }
+
+        err = w.checkQC(e.Name, int(rec.dnaSize), e.N, e.Other)
+        if err != nil {
+            return 0, 0, 0, err
+        }
+        if len(e.SHA256) > 0 {
+            var sum [sha256.Size]byte
+            hex.Decode(sum[:], []byte(e.SHA256))
+            err = w.checkDuplicate(e.Name, sum)
+            if err != nil {
+                return 0, 0, 0, err
+            }
+        }
+        err = w.addRecord(e.Name, rec)
+        if err != nil {
+            return 0, 0, 0, err
+        }
+        w.describe(e.Name, e.Header)
+
+        resume, logEnd, recEnd = e.Next, logEnd+int64(len(line)), e.Offset+int64(e.Size)
+    }
+
+    return resume, logEnd, recEnd, nil
+}
+
+// ImportFastaCheckpoint adds every sequence in the FASTA read from in to w as
+// ImportFasta, keeping a checkpoint in dir so an import killed part way, for
+// example by a crash or a batch time limit, can be resumed. Each packed
+// sequence is appended to a records file in dir and logged, and both are
+// synced to disk at most every CHECKPOINT_INTERVAL. Calling
+// ImportFastaCheckpoint again with the same input and dir restores the
+// sequences already packed, checking them against their logged CRCs, and
+// continues from the first sequence not yet logged. A checkpoint made from a
+// different input is an error. Remove dir once the output has been written.
+func ImportFastaCheckpoint(in io.ReadSeeker, w *Writer, dir string) (error) {
+    if w.closed {
+        return ErrClosed
+    }
+    if w.reserved != nil {
+        return fmt.Errorf("Checkpoints can't be used with reserved sequences")
+    }
+
+    input, err := identifyInput(in)
+    if err != nil {
+        return err
+    }
+
+    err = os.MkdirAll(dir, 0755)
+    if err != nil {
+        return err
+    }
+    logFile, err := os.OpenFile(filepath.Join(dir, CHECKPOINT_LOG), os.O_RDWR|os.O_CREATE, 0644)
+    if err != nil {
+        return err
+    }
+    defer logFile.Close()
+    records, err := os.OpenFile(filepath.Join(dir, CHECKPOINT_RECORDS), os.O_RDWR|os.O_CREATE, 0644)
+    if err != nil {
+        return err
+    }
+    defer records.Close()
+
+    resume, logEnd, recEnd, err := w.restoreCheckpoint(logFile, records, input)
+    if err != nil {
+        return err
+    }
+
+    // drop anything written after the last complete entry
+    err = logFile.Truncate(logEnd)
+    if err == nil {
+        err = records.Truncate(recEnd)
+    }
+    if err == nil {
+        _, err = logFile.Seek(logEnd, io.SeekStart)
+    }
+    if err != nil {
+        return err
+    }
+    if logEnd == 0 {
+        line, _ := json.Marshal(input)
+        _, err = logFile.Write(append(line, '\n'))
+        if err != nil {
+            return err
+        }
+    }
+
+    _, err = in.Seek(resume, io.SeekStart)
+    if err != nil {
+        return err
+    }
+
+    synced := time.Now()
+    sync := func() (error) {
+        err := records.Sync()
+        if err != nil {
+            return err
+        }
+        synced = time.Now()
+        return logFile.Sync()
+    }
+
+    var e checkpointEntry
+    start := func(name, header string, at int64) (error) {
+        e = checkpointEntry{Name: name, Header: header}
+        return w.StartSequence(name)
+    }
+    chunk := func(seq []byte) (error) {
+        return w.AppendChunk(string(seq))
+    }
+    end := func(at int64) (error) {
+        b := w.building
+        if b != nil {
+            e.N, e.Other = b.nCount, b.other
+            if b.digest != nil {
+                e.SHA256 = hex.EncodeToString(b.digest.Sum(nil))
+            }
+        }
+        err := w.EndSequence()
+        if err != nil {
+            return err
+        }
+        w.describe(e.Name, e.Header)
+
+        rec, err := w.records[e.Name].loaded()
+        if err != nil {
+            return err
+        }
+        buf := rec.encode()
+        e.Offset, e.Size, e.CRC, e.Next = recEnd, len(buf), crc32.ChecksumIEEE(buf), at
+        _, err = records.WriteAt(buf, recEnd)
+        if err != nil {
+            return err
+        }
+        recEnd += int64(len(buf))
+
+        line, err := json.Marshal(&e)
+        if err != nil {
+            return err
+        }
+        _, err = logFile.Write(append(line, '\n'))
+        if err != nil {
+            return err
+        }
+
+        if time.Since(synced) >= CHECKPOINT_INTERVAL {
+            return sync()
+        }
+        return nil
+    }
+
+    err = scanFasta(in, resume, start, chunk, end)
+    if err != nil {
+        return err
+    }
+
+    return sync()
+}
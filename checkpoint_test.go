@@ -0,0 +1,102 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "errors"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+const checkpointFasta = "\xef\xbb\xbf>chr1 first\nACGTNNNN\nacgt\n>chr2\nGGGGCCCC\n>chr3 third\nNNNNacgtAC\n>chr4\nT\n"
+
+func TestImportFastaCheckpoint(t *testing.T) {
+    var good bytes.Buffer
+    w := NewWriter()
+    err := ImportFasta(strings.NewReader(checkpointFasta), w)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    w.WriteTo(&good)
+
+    dir := filepath.Join(t.TempDir(), "checkpoint")
+    in := strings.NewReader(checkpointFasta)
+
+    // the import is killed after two sequences are logged
+    crash := errors.New("killed")
+    packed := 0
+    w = NewWriter(OnPacked(func(e *SequenceEvent) (error) {
+        packed++
+        if packed == 3 {
+            return crash
+        }
+        return nil
+    }))
+    err = ImportFastaCheckpoint(in, w, dir)
+    if !errors.Is(err, crash) {
+        t.Fatalf("Expected crash: %v", err)
+    }
+
+    // a torn entry and record written as the process died are dropped
+    f, _ := os.OpenFile(filepath.Join(dir, CHECKPOINT_LOG), os.O_APPEND|os.O_WRONLY, 0644)
+    f.WriteString(`{"name":"chr3","off`)
+    f.Close()
+    f, _ = os.OpenFile(filepath.Join(dir, CHECKPOINT_RECORDS), os.O_APPEND|os.O_WRONLY, 0644)
+    f.Write([]byte{1, 2, 3})
+    f.Close()
+
+    for i := 0; i < 2; i++ {
+        restored := make([]string, 0)
+        w = NewWriter(OnPacked(func(e *SequenceEvent) (error) {
+            restored = append(restored, e.Name)
+            return nil
+        }))
+        err = ImportFastaCheckpoint(in, w, dir)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        if strings.Join(restored, ",") != "chr1,chr2,chr3,chr4" {
+            t.Errorf("Invalid sequences after resume: %v", restored)
+        }
+        if w.Descriptions()["chr1"] != "chr1 first" || w.Descriptions()["chr3"] != "chr3 third" {
+            t.Errorf("Invalid descriptions after resume: %v", w.Descriptions())
+        }
+
+        var out bytes.Buffer
+        w.WriteTo(&out)
+        if !bytes.Equal(out.Bytes(), good.Bytes()) {
+            t.Errorf("Resumed import %d differs from ImportFasta", i)
+        }
+    }
+
+    err = ImportFastaCheckpoint(strings.NewReader(">chrX\nACGT\n"), NewWriter(), dir)
+    if err == nil {
+        t.Errorf("Checkpoint of a different input accepted")
+    }
+}
+
+func TestDecodeRecord(t *testing.T) {
+    w := NewWriter()
+    w.Add("chr1", "ACGTNNacgtA")
+    buf := w.records["chr1"].encode()
+
+    rec, err := decodeRecord("chr1", buf)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if !bytes.Equal(rec.encode(), buf) {
+        t.Errorf("Decoded record differs")
+    }
+
+    for _, n := range []int{3, 10, len(buf)-1} {
+        _, err = decodeRecord("chr1", buf[:n])
+        if !errors.Is(err, ErrCorrupt) {
+            t.Errorf("Truncated record of %d bytes accepted: %v", n, err)
+        }
+    }
+}
@@ -6,13 +6,40 @@ package main
 
 import (
     "os"
+    "io"
     "bufio"
     "log"
+    "runtime"
     "github.com/aebruno/twobit"
-    "github.com/aebruno/gofasta"
 )
 
-func ToFasta(in, out string) {
+// Open in for reading. "-" is stdin.
+func openInput(in string) (io.ReadCloser, error) {
+    if in == "-" {
+        return os.Stdin, nil
+    }
+
+    return os.Open(in)
+}
+
+// Create out for writing. "-" is stdout, which is not closed.
+func createOutput(out string) (io.Writer, func() (error), error) {
+    if out == "-" {
+        return os.Stdout, func() (error) { return nil }, nil
+    }
+
+    f, err := os.Create(out)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    return f, f.Close, nil
+}
+
+// Convert a .2bit file to FASTA. "-" reads stdin or writes stdout. Input is
+// read sequentially so pipes are supported. casePolicy names the output
+// case (see twobit.ParseCasePolicy), empty for as stored.
+func ToFasta(in, out, casePolicy string) {
     if len(in) == 0 {
         log.Fatalln("Please provide an input file (.2bit)")
     }
@@ -20,90 +47,198 @@ func ToFasta(in, out string) {
         log.Fatalln("Please provide an output file (.fa)")
     }
 
-    inFile, err := os.Open(in)
+    policy := twobit.CASE_AS_STORED
+    if len(casePolicy) > 0 {
+        var err error
+        policy, err = twobit.ParseCasePolicy(casePolicy)
+        if err != nil {
+            log.Fatal(err)
+        }
+    }
+
+    input, err := openInput(in)
     if err != nil {
         log.Fatal(err)
     }
+    defer input.Close()
 
-    defer inFile.Close()
-
-    tb, err := twobit.NewReader(inFile)
+    output, closeOutput, err := createOutput(out)
     if err != nil {
         log.Fatal(err)
     }
 
-    outFile, err := os.Create(out)
+    err = toFasta(input, output, policy)
+    if err == nil {
+        err = closeOutput()
+    }
     if err != nil {
         log.Fatal(err)
     }
+}
 
-    defer outFile.Close()
-
-    w := bufio.NewWriter(outFile)
+// Write every sequence of the 2bit file in to out as FASTA in file order, in
+// the case selected by policy
+func toFasta(in io.Reader, out io.Writer, policy twobit.CasePolicy) (error) {
+    s, err := twobit.NewScanner(bufio.NewReader(in))
+    if err != nil {
+        return err
+    }
 
-    for _, n := range tb.Names() {
+    w := bufio.NewWriter(out)
+    for s.Scan() {
         w.WriteString(">")
-        w.WriteString(n)
+        w.WriteString(s.Name())
         w.WriteString("\n")
-        seq, err := tb.Read(n)
-        if err != nil {
-            log.Fatal(err)
-        }
 
-        n := len(seq)
+        seq := s.Bytes()
+        policy.Apply(seq)
         cols := 50
-        rows := n/cols
-        if n % cols > 0 {
-            rows++
-        }
-
-        for r := 0; r < rows; r++ {
-            end := (r*cols)+cols
-            if end > n {
-                end = n
+        for i := 0; i < len(seq); i += cols {
+            end := i+cols
+            if end > len(seq) {
+                end = len(seq)
             }
 
-            w.Write(seq[(r*cols):end])
-            w.Write([]byte("\n"))
+            w.Write(seq[i:end])
+            w.WriteString("\n")
         }
     }
 
-    w.Flush()
+    if s.Err() != nil {
+        return s.Err()
+    }
+
+    return w.Flush()
 }
 
-func To2bit(in, out string) {
+// Convert a FASTA file to .2bit. "-" reads stdin or writes stdout. The
+// Writer holds the packed sequences until the whole input has been read, so
+// the output is written front to back and needs no seeking. When reading
+// stdin or writing stdout the packed sequences are spilled to a temp file in
+// tmpdir (default $TMPDIR) instead of memory. If the input has a samtools
+// faidx index (in.fai) the sequences are instead streamed to the output as
+// they are read. Sequences are packed by workers
+// goroutines, 0 for one per CPU. With a checkpoint directory the input is
+// read sequentially and the import resumes from the checkpoint if it was
+// interrupted, the directory is removed once the output is written.
+func To2bit(in, out string, workers int, checkpoint, tmpdir string) {
     if len(in) == 0 {
         log.Fatalln("Please provide an input file (.fa)")
     }
     if len(out) == 0 {
         log.Fatalln("Please provide an output file (.2bit)")
     }
+    if workers <= 0 {
+        workers = runtime.NumCPU()
+    }
 
-    inFile, err := os.Open(in)
+    if len(checkpoint) > 0 && in == "-" {
+        log.Fatalln("Checkpoints need a FASTA file, not stdin")
+    }
+
+    if in != "-" && len(checkpoint) == 0 {
+        if _, err := os.Stat(in+".fai"); err == nil {
+            err = indexedTo2bit(in, in+".fai", out, workers)
+            if err != nil {
+                log.Fatal(err)
+            }
+            return
+        }
+    }
+
+    input, err := openInput(in)
     if err != nil {
         log.Fatal(err)
     }
+    defer input.Close()
 
-    defer inFile.Close()
-
-    tb := twobit.NewWriter()
+    var opts []twobit.WriterOption
+    if in == "-" || out == "-" {
+        opts = append(opts, twobit.SpillToDisk(twobit.TempStorage{Dir: tmpdir}))
+    }
 
-    for rec := range gofasta.SimpleParser(inFile) {
-        err := tb.Add(rec.Id, rec.Seq)
+    // read all input before creating the output so a failed conversion
+    // leaves no partial file
+    var tb *twobit.Writer
+    if len(checkpoint) > 0 {
+        tb = twobit.NewWriter(opts...)
+        err = twobit.ImportFastaCheckpoint(input.(io.ReadSeeker), tb, checkpoint)
         if err != nil {
-            log.Fatal(err)
+            tb.Close()
         }
+    } else {
+        tb, err = read2bit(input, workers, opts...)
     }
-
-    outFile, err := os.Create(out)
     if err != nil {
         log.Fatal(err)
     }
 
-    defer outFile.Close()
+    output, closeOutput, err := createOutput(out)
+    if err != nil {
+        log.Fatal(err)
+    }
 
-    err = tb.WriteTo(outFile)
+    err = tb.WriteTo(output)
+    if cerr := tb.Close(); err == nil {
+        err = cerr
+    }
+    if err == nil {
+        err = closeOutput()
+    }
+    if err == nil && len(checkpoint) > 0 {
+        err = os.RemoveAll(checkpoint)
+    }
     if err != nil {
         log.Fatal(err)
     }
 }
+
+// Convert the FASTA file in indexed by fai to .2bit, removing a partial
+// output file if conversion fails
+func indexedTo2bit(in, fai, out string, workers int) (error) {
+    f, err := os.Open(fai)
+    if err != nil {
+        return err
+    }
+    records, err := twobit.ReadFai(f)
+    f.Close()
+    if err != nil {
+        return err
+    }
+
+    input, err := os.Open(in)
+    if err != nil {
+        return err
+    }
+    defer input.Close()
+
+    output, closeOutput, err := createOutput(out)
+    if err != nil {
+        return err
+    }
+
+    err = twobit.ImportIndexedFasta(input, records, twobit.NewWriter(), output, workers)
+    if err == nil {
+        err = closeOutput()
+    } else {
+        closeOutput()
+    }
+    if err != nil && out != "-" {
+        os.Remove(out)
+    }
+
+    return err
+}
+
+// Read FASTA from in into a new Writer created with opts, packing with
+// workers goroutines
+func read2bit(in io.Reader, workers int, opts ...twobit.WriterOption) (*twobit.Writer, error) {
+    tb := twobit.NewWriter(opts...)
+    err := twobit.ImportFastaParallel(in, tb, workers)
+    if err != nil {
+        tb.Close()
+        return nil, err
+    }
+
+    return tb, nil
+}
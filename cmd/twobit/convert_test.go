@@ -0,0 +1,131 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+    "testing"
+    "bytes"
+    "io"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+    "github.com/aebruno/twobit"
+)
+
+func TestToFasta(t *testing.T) {
+    w := twobit.NewWriter()
+    w.Add("chr2", strings.Repeat("ACGT", 15))
+    w.Add("chr1", "ACGTacgtNNNN")
+    var in bytes.Buffer
+    w.WriteTo(&in)
+
+    // hide Seek, like stdin reading from a pipe
+    var out bytes.Buffer
+    file := in.Bytes()
+    err := toFasta(struct{ io.Reader }{&in}, &out, twobit.CASE_AS_STORED)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    want := ">chr2\n"+strings.Repeat("ACGT", 12)+"AC\n"+"GTACGTACGT\n>chr1\nACGTacgtNNNN\n"
+    if out.String() != want {
+        t.Errorf("Invalid FASTA: %q != %q", out.String(), want)
+    }
+
+    out.Reset()
+    err = toFasta(bytes.NewReader(file), &out, twobit.CASE_MASK_ONLY)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if !strings.HasSuffix(out.String(), ">chr1\nACGTacgtNNNN\n") {
+        t.Errorf("Invalid mask only FASTA: %q", out.String())
+    }
+
+    err = toFasta(strings.NewReader("not a 2bit file"), &out, twobit.CASE_AS_STORED)
+    if err == nil {
+        t.Errorf("Invalid input accepted")
+    }
+}
+
+func TestIndexedTo2bit(t *testing.T) {
+    dir := t.TempDir()
+    fa := filepath.Join(dir, "in.fa")
+    out := filepath.Join(dir, "out.2bit")
+    ioutil.WriteFile(fa, []byte(">chr1\nACGTA\nCGnn\n>chr2\nGG\n"), 0644)
+    ioutil.WriteFile(fa+".fai", []byte("chr1\t9\t6\t5\t6\nchr2\t2\t23\t2\t3\n"), 0644)
+
+    err := indexedTo2bit(fa, fa+".fai", out, 2)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    seqs, err := twobit.ReadAll(out)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if seqs["chr1"] != "ACGTACGnn" || seqs["chr2"] != "GG" {
+        t.Errorf("Invalid sequences: %v", seqs)
+    }
+
+    // a stale index fails and leaves no output
+    ioutil.WriteFile(fa+".fai", []byte("chr1\t12\t6\t5\t6\n"), 0644)
+    os.Remove(out)
+    err = indexedTo2bit(fa, fa+".fai", out, 2)
+    if err == nil {
+        t.Errorf("Stale index accepted")
+    }
+    if _, err := os.Stat(out); err == nil {
+        t.Errorf("Partial output not removed")
+    }
+}
+
+func TestRead2bit(t *testing.T) {
+    tb, err := read2bit(strings.NewReader(">chr1 first\nACGT\nacNN\n>chr2\nGG\n"), 2)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    var out bytes.Buffer
+    tb.WriteTo(&out)
+    r, err := twobit.NewReader(bytes.NewReader(out.Bytes()))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    seq, err := r.Read("chr1")
+    if err != nil || string(seq) != "ACGTacNN" {
+        t.Errorf("Invalid sequence chr1: %s %v", seq, err)
+    }
+}
+
+func TestRead2bitSpill(t *testing.T) {
+    dir := t.TempDir()
+    fa := ">chr1 first\nACGT\nacNN\n>chr2\nGG\n"
+    tb, err := read2bit(strings.NewReader(fa), 2, twobit.SpillToDisk(twobit.TempStorage{Dir: dir}))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+        t.Errorf("Sequences not spilled to %s", dir)
+    }
+
+    var out bytes.Buffer
+    err = tb.WriteTo(&out)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    tb.Close()
+    if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+        t.Errorf("Temp file not removed")
+    }
+
+    r, err := twobit.NewReader(bytes.NewReader(out.Bytes()))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    seq, err := r.Read("chr1")
+    if err != nil || string(seq) != "ACGTacNN" {
+        t.Errorf("Invalid sequence chr1: %s %v", seq, err)
+    }
+}
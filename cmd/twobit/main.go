@@ -6,6 +6,8 @@ package main
 
 import (
     "os"
+    "log"
+    "path/filepath"
     "github.com/codegangsta/cli"
 //    "runtime/pprof"
 )
@@ -14,6 +16,19 @@ func main() {
     //p, _ := os.Create("twobit.cpuprofile")
     //pprof.StartCPUProfile(p)
     //defer pprof.StopCPUProfile()
+    // installed or invoked as twoBitToFa: UCSC compatible flags
+    if filepath.Base(os.Args[0]) == "twoBitToFa" || (len(os.Args) > 1 && os.Args[1] == "twoBitToFa") {
+        args := os.Args[1:]
+        if len(args) > 0 && args[0] == "twoBitToFa" {
+            args = args[1:]
+        }
+        err := TwoBitToFa(args, os.Stdout)
+        if err != nil {
+            log.Fatal(err)
+        }
+        return
+    }
+
     app := cli.NewApp()
     app.Name    = "twobit"
     app.Authors = []cli.Author{cli.Author{Name: "Andrew E. Bruno", Email: "aeb@qnot.org"}}
@@ -25,16 +40,56 @@ func main() {
             Usage: "Convert FASTA file to .2bit format.",
             Flags: []cli.Flag{
                 &cli.BoolFlag{Name: "to-fasta, f", Usage: "Convert .2bit file to FASTA"},
-                &cli.StringFlag{Name: "in, i", Usage: "Input file"},
-                &cli.StringFlag{Name: "out, o", Usage: "Output file"},
+                &cli.StringFlag{Name: "in, i", Usage: "Input file, - for stdin"},
+                &cli.StringFlag{Name: "out, o", Usage: "Output file, - for stdout"},
+                &cli.IntFlag{Name: "workers, j", Usage: "Goroutines packing sequences, 0 for one per CPU"},
+                &cli.StringFlag{Name: "checkpoint", Usage: "Directory to checkpoint the import in, resuming it if interrupted"},
+                &cli.StringFlag{Name: "tmpdir", Usage: "Directory for temp files when reading stdin or writing stdout, default $TMPDIR"},
+                &cli.StringFlag{Name: "case", Usage: "With --to-fasta, output case: stored, upper, lower or mask"},
             },
             Action: func(c *cli.Context) {
                 if c.Bool("to-fasta") {
-                    ToFasta(c.String("in"), c.String("out"))
+                    ToFasta(c.String("in"), c.String("out"), c.String("case"))
                     return
                 }
 
-                To2bit(c.String("in"), c.String("out"))
+                To2bit(c.String("in"), c.String("out"), c.Int("workers"), c.String("checkpoint"), c.String("tmpdir"))
+            },
+        },
+        {
+            Name: "fa2bit",
+            Usage: "Convert FASTA to .2bit: fa2bit in.fa out.2bit. Use - for stdin/stdout.",
+            Flags: []cli.Flag{
+                &cli.IntFlag{Name: "workers, j", Usage: "Goroutines packing sequences, 0 for one per CPU"},
+                &cli.StringFlag{Name: "checkpoint", Usage: "Directory to checkpoint the import in, resuming it if interrupted"},
+                &cli.StringFlag{Name: "tmpdir", Usage: "Directory for temp files when reading stdin or writing stdout, default $TMPDIR"},
+            },
+            Action: func(c *cli.Context) {
+                To2bit(c.Args().Get(0), c.Args().Get(1), c.Int("workers"), c.String("checkpoint"), c.String("tmpdir"))
+            },
+        },
+        {
+            Name: "2bitfa",
+            Usage: "Convert .2bit to FASTA: 2bitfa in.2bit out.fa. Use - for stdin/stdout.",
+            Flags: []cli.Flag{
+                &cli.StringFlag{Name: "case", Usage: "Output case: stored, upper, lower or mask"},
+            },
+            Action: func(c *cli.Context) {
+                ToFasta(c.Args().Get(0), c.Args().Get(1), c.String("case"))
+            },
+        },
+        {
+            Name: "stats",
+            Usage: "Print sequence statistics for .2bit file. Use - to read from stdin.",
+            Action: func(c *cli.Context) {
+                Stats(c.Args().First())
+            },
+        },
+        {
+            Name: "maskdiff",
+            Usage: "Print masked intervals gained and lost from old.2bit to new.2bit as BED.",
+            Action: func(c *cli.Context) {
+                MaskDiff(c.Args().Get(0), c.Args().Get(1))
             },
         },
     }
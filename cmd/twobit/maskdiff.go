@@ -0,0 +1,41 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+    "os"
+    "log"
+    "github.com/aebruno/twobit"
+)
+
+// Print the masked intervals gained and lost between two versions of the
+// same genome as BED. Only the block tables of each file are read.
+func MaskDiff(old, updated string) {
+    if len(old) == 0 || len(updated) == 0 {
+        log.Fatalln("Please provide the old and updated files (.2bit)")
+    }
+
+    a, err := twobit.Open(old)
+    if err != nil {
+        log.Fatal(err)
+    }
+    defer a.Close()
+
+    b, err := twobit.Open(updated)
+    if err != nil {
+        log.Fatal(err)
+    }
+    defer b.Close()
+
+    diff, err := twobit.DiffMasks(a, b)
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    err = diff.WriteBED(os.Stdout)
+    if err != nil {
+        log.Fatal(err)
+    }
+}
@@ -0,0 +1,61 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+    "os"
+    "io"
+    "bufio"
+    "fmt"
+    "log"
+    "github.com/aebruno/twobit"
+)
+
+// Print per sequence statistics. Input is read sequentially so "-" (stdin)
+// is supported.
+func Stats(in string) {
+    if len(in) == 0 {
+        log.Fatalln("Please provide an input file (.2bit)")
+    }
+
+    var input io.Reader = os.Stdin
+    if in != "-" {
+        inFile, err := os.Open(in)
+        if err != nil {
+            log.Fatal(err)
+        }
+
+        defer inFile.Close()
+        input = inFile
+    }
+
+    s, err := twobit.NewScanner(bufio.NewReader(input))
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    w := bufio.NewWriter(os.Stdout)
+    fmt.Fprintf(w, "name\tlength\tn\tmasked\n")
+
+    for s.Scan() {
+        nCount := 0
+        masked := 0
+        for _, b := range s.Bytes() {
+            if b == 'N' || b == 'n' {
+                nCount++
+            }
+            if b >= 'a' && b <= 'z' {
+                masked++
+            }
+        }
+        fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", s.Name(), len(s.Bytes()), nCount, masked)
+    }
+
+    w.Flush()
+
+    if s.Err() != nil {
+        log.Fatal(s.Err())
+    }
+}
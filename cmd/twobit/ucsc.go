@@ -0,0 +1,229 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+    "os"
+    "io"
+    "bufio"
+    "flag"
+    "fmt"
+    "sort"
+    "strings"
+    "github.com/aebruno/twobit"
+)
+
+// region is a sequence range to extract with the header to print for it
+type region struct {
+    header   string
+    name     string
+    start    int
+    end      int
+    bed      []string // columns of a BED record, see Reader.ReadBEDRecord
+}
+
+// Parse "name" or "name:start-end" against the sequence names in tb. Names
+// containing colons may be wrapped in braces, see Reader.ParseRegion.
+func parseRegion(tb *twobit.Reader, spec string) (region, error) {
+    iv, err := tb.ParseRegion(spec)
+    if err != nil {
+        return region{}, err
+    }
+
+    return region{header: spec, name: iv.Name, start: iv.Start, end: iv.End}, nil
+}
+
+// Read regions from a BED file. The header is the name column unless bedPos
+// is set or the name is missing, in which case it is chrom:start-end. As with
+// UCSC twoBitToFa, BED12 introns are excluded and minus strand records are
+// reverse complemented when read.
+func readBedRegions(in io.Reader, bedPos bool) ([]region, error) {
+    regions := make([]region, 0)
+    err := twobit.ScanBED(in, func(iv twobit.Interval, cols []string, line int) (error) {
+        header := iv.String()
+        if !bedPos && len(cols) > 3 && len(cols[3]) > 0 {
+            header = cols[3]
+        }
+        regions = append(regions, region{header: header, name: iv.Name, start: iv.Start, end: iv.End, bed: cols})
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    return regions, nil
+}
+
+// TwoBitToFa runs the flag compatible replacement for the UCSC twoBitToFa
+// tool:
+//
+//   twoBitToFa input.2bit[:seq[:start-end]] output.fa [options]
+//
+// Supported options are -seq, -start, -end, -seqList, -noMask, -bed and
+// -bedPos. -udcDir is accepted and ignored. As extensions -upper (the same
+// as -noMask), -lower and -case=stored|upper|lower|mask select the output
+// case, see twobit.CasePolicy. As with UCSC tools options may
+// appear before or after the file arguments and the output may be "stdout".
+func TwoBitToFa(args []string, stdout io.Writer) (error) {
+    fs := flag.NewFlagSet("twoBitToFa", flag.ContinueOnError)
+    fs.SetOutput(io.Discard)
+    seq := fs.String("seq", "", "Restrict to this sequence")
+    start := fs.Int("start", 0, "Start at given position in sequence (zero-based)")
+    end := fs.Int("end", 0, "End at given position in sequence (non-inclusive)")
+    seqList := fs.String("seqList", "", "File containing list of the desired sequence names")
+    noMask := fs.Bool("noMask", false, "Convert sequence to all upper case")
+    upper := fs.Bool("upper", false, "Convert sequence to all upper case")
+    lower := fs.Bool("lower", false, "Convert sequence to all lower case")
+    casePolicy := fs.String("case", "", "Output case: stored, upper, lower or mask")
+    bed := fs.String("bed", "", "Grab sequences specified by input.bed")
+    bedPos := fs.Bool("bedPos", false, "With -bed, use chrom:start-end as the fasta ID")
+    fs.String("udcDir", "", "Ignored")
+
+    var opts, files []string
+    for _, a := range args {
+        if len(a) > 1 && a[0] == '-' {
+            opts = append(opts, a)
+        } else {
+            files = append(files, a)
+        }
+    }
+
+    err := fs.Parse(opts)
+    if err != nil {
+        return err
+    }
+    if len(files) != 2 {
+        return fmt.Errorf("usage: twoBitToFa input.2bit output.fa [options]")
+    }
+
+    policy := twobit.CASE_AS_STORED
+    policies := 0
+    if *noMask || *upper {
+        policy = twobit.CASE_UPPER
+        policies++
+    }
+    if *lower {
+        policy = twobit.CASE_LOWER
+        policies++
+    }
+    if len(*casePolicy) > 0 {
+        policy, err = twobit.ParseCasePolicy(*casePolicy)
+        if err != nil {
+            return err
+        }
+        policies++
+    }
+    if policies > 1 {
+        return fmt.Errorf("Only one of -noMask/-upper, -lower and -case may be given")
+    }
+
+    in := files[0]
+    var regions []region
+
+    // input.2bit:seq or input.2bit:seq:start-end
+    spec := ""
+    if i := strings.Index(in, ".2bit:"); i >= 0 {
+        spec = in[i+6:]
+        in = in[:i+5]
+    }
+
+    tb, err := twobit.Open(in)
+    if err != nil {
+        return err
+    }
+    defer tb.Close()
+
+    if len(spec) > 0 {
+        rg, err := parseRegion(tb, spec)
+        if err != nil {
+            return err
+        }
+        regions = append(regions, rg)
+    }
+
+    switch {
+    case len(*seq) > 0:
+        rg := region{header: *seq, name: *seq, start: *start, end: *end}
+        if *start != 0 || *end != 0 {
+            if *end == 0 {
+                rg.end, err = tb.Length(*seq)
+                if err != nil {
+                    return err
+                }
+            }
+            rg.header = fmt.Sprintf("%s:%d-%d", *seq, rg.start, rg.end)
+        }
+        regions = append(regions, rg)
+    case len(*seqList) > 0:
+        data, err := os.ReadFile(*seqList)
+        if err != nil {
+            return err
+        }
+        for _, spec := range strings.Fields(string(data)) {
+            rg, err := parseRegion(tb, spec)
+            if err != nil {
+                return err
+            }
+            regions = append(regions, rg)
+        }
+    case len(*bed) > 0:
+        f, err := os.Open(*bed)
+        if err != nil {
+            return err
+        }
+        defer f.Close()
+        regions, err = readBedRegions(f, *bedPos)
+        if err != nil {
+            return err
+        }
+    case len(regions) == 0:
+        offsets, err := tb.Offsets()
+        if err != nil {
+            return err
+        }
+        for _, name := range tb.Names() {
+            regions = append(regions, region{header: name, name: name})
+        }
+        sort.Slice(regions, func(i, j int) bool {
+            return offsets[regions[i].name].Offset < offsets[regions[j].name].Offset
+        })
+    }
+
+    out := stdout
+    if files[1] != "stdout" {
+        f, err := os.Create(files[1])
+        if err != nil {
+            return err
+        }
+        defer f.Close()
+        out = f
+    }
+
+    w := bufio.NewWriter(out)
+    for _, rg := range regions {
+        var s []byte
+        if rg.bed != nil {
+            s, err = tb.ReadBEDRecord(twobit.Interval{Name: rg.name, Start: rg.start, End: rg.end}, rg.bed)
+        } else {
+            s, err = tb.ReadRange(rg.name, rg.start, rg.end)
+        }
+        if err != nil {
+            return err
+        }
+        policy.Apply(s)
+
+        w.WriteString(">" + rg.header + "\n")
+        for i := 0; i < len(s); i += 50 {
+            j := i+50
+            if j > len(s) {
+                j = len(s)
+            }
+            w.Write(s[i:j])
+            w.WriteByte('\n')
+        }
+    }
+
+    return w.Flush()
+}
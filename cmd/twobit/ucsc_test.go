@@ -0,0 +1,143 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+    "os"
+    "testing"
+    "bytes"
+    "path/filepath"
+    "reflect"
+    "github.com/aebruno/twobit"
+)
+
+// Expected output follows UCSC twoBitToFa for the same arguments
+func TestTwoBitToFaCompat(t *testing.T) {
+    dir := t.TempDir()
+    in := filepath.Join(dir, "test.2bit")
+
+    w, err := twobit.Create(in)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    w.Add("chr1", "ACGTacgtNNNNACGT")
+    w.Add("chr2", "ggggCCCC")
+    err = w.Close()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    seqList := filepath.Join(dir, "seqs.txt")
+    os.WriteFile(seqList, []byte("chr2\nchr1:4-8\n"), 0644)
+    bed := filepath.Join(dir, "regions.bed")
+    os.WriteFile(bed, []byte("chr1\t0\t4\tfirst\nchr2\t2\t6\n"), 0644)
+    bed6 := filepath.Join(dir, "regions6.bed")
+    os.WriteFile(bed6, []byte("chr1\t0\t6\tplus\t0\t+\nchr1\t0\t6\tminus\t0\t-\n"), 0644)
+    bed12 := filepath.Join(dir, "regions12.bed")
+    os.WriteFile(bed12, []byte("chr1\t0\t16\ttx1\t0\t+\t0\t16\t0\t2\t2,4,\t0,12,\n" +
+        "chr1\t0\t16\ttx2\t0\t-\t0\t16\t0\t2\t2,4,\t0,12,\n"), 0644)
+
+    tests := []struct {
+        args    []string
+        out     string
+    }{
+        {[]string{in, "stdout", "-seq=chr2"}, ">chr2\nggggCCCC\n"},
+        {[]string{"-seq=chr1", "-start=2", "-end=6", in, "stdout"}, ">chr1:2-6\nGTac\n"},
+        {[]string{in, "stdout", "-seq=chr1", "-start=12"}, ">chr1:12-16\nACGT\n"},
+        {[]string{in, "stdout", "-seq=chr2", "-noMask"}, ">chr2\nGGGGCCCC\n"},
+        {[]string{in, "stdout", "-seq=chr2", "-upper"}, ">chr2\nGGGGCCCC\n"},
+        {[]string{in, "stdout", "-seq=chr1", "-lower"}, ">chr1\nacgtacgtnnnnacgt\n"},
+        {[]string{in, "stdout", "-seq=chr1", "-case=mask"}, ">chr1\nACGTacgtNNNNACGT\n"},
+        {[]string{in, "stdout", "-seqList=" + seqList}, ">chr2\nggggCCCC\n>chr1:4-8\nacgt\n"},
+        {[]string{in, "stdout", "-bed=" + bed}, ">first\nACGT\n>chr2:2-6\nggCC\n"},
+        {[]string{in, "stdout", "-bed=" + bed, "-bedPos"}, ">chr1:0-4\nACGT\n>chr2:2-6\nggCC\n"},
+        {[]string{in, "stdout", "-bed=" + bed6}, ">plus\nACGTac\n>minus\ngtACGT\n"},
+        {[]string{in, "stdout", "-bed=" + bed6, "-bedPos", "-noMask"}, ">chr1:0-6\nACGTAC\n>chr1:0-6\nGTACGT\n"},
+        {[]string{in, "stdout", "-bed=" + bed12}, ">tx1\nACACGT\n>tx2\nACGTGT\n"},
+        {[]string{in + ":chr2", "stdout"}, ">chr2\nggggCCCC\n"},
+        {[]string{in + ":chr1:8-12", "stdout"}, ">chr1:8-12\nNNNN\n"},
+        {[]string{in, "stdout", "-seq=chr2", "-udcDir=/tmp/udc"}, ">chr2\nggggCCCC\n"},
+    }
+
+    for _, test := range tests {
+        var out bytes.Buffer
+        err := TwoBitToFa(test.args, &out)
+        if err != nil {
+            t.Errorf("%v: %s", test.args, err)
+            continue
+        }
+        if out.String() != test.out {
+            t.Errorf("%v: %q != %q", test.args, out.String(), test.out)
+        }
+    }
+
+    // whole file is written in file order
+    var all bytes.Buffer
+    err = TwoBitToFa([]string{in, "stdout"}, &all)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if all.Len() != 38 || !bytes.Contains(all.Bytes(), []byte(">chr1\nACGTacgtNNNNACGT\n")) || !bytes.Contains(all.Bytes(), []byte(">chr2\nggggCCCC\n")) {
+        t.Errorf("Invalid whole file output: %q", all.String())
+    }
+
+    outFile := filepath.Join(dir, "out.fa")
+    err = TwoBitToFa([]string{in, outFile, "-seq=chr2"}, nil)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    data, _ := os.ReadFile(outFile)
+    if string(data) != ">chr2\nggggCCCC\n" {
+        t.Errorf("Invalid output file: %q", data)
+    }
+
+    err = TwoBitToFa([]string{in}, nil)
+    if err == nil {
+        t.Errorf("Expected usage error")
+    }
+
+    err = TwoBitToFa([]string{in, "stdout", "-upper", "-lower"}, nil)
+    if err == nil {
+        t.Errorf("Expected error for conflicting case options")
+    }
+}
+
+func TestParseRegion(t *testing.T) {
+    w := twobit.NewWriter()
+    w.Add("chr1", "ACGTACGTACGTACGT")
+    var file bytes.Buffer
+    w.WriteTo(&file)
+    tb, err := twobit.NewReader(bytes.NewReader(file.Bytes()))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    tests := []struct {
+        spec   string
+        want   region
+        valid  bool
+    }{
+        {"chr1", region{header: "chr1", name: "chr1", end: 16}, true},
+        {"chr1:0-4", region{header: "chr1:0-4", name: "chr1", start: 0, end: 4}, true},
+        {"chr1:3-4", region{header: "chr1:3-4", name: "chr1", start: 3, end: 4}, true},
+        {"chr1:4-4", region{}, false},
+        {"chr1:5-4", region{}, false},
+        {"chr1:-1-4", region{}, false},
+        {"chr1:0-17", region{}, false},
+    }
+
+    for _, tt := range tests {
+        rg, err := parseRegion(tb, tt.spec)
+        if !tt.valid {
+            if err == nil {
+                t.Errorf("Region %s should be invalid", tt.spec)
+            }
+            continue
+        }
+        if err != nil || !reflect.DeepEqual(rg, tt.want) {
+            t.Errorf("Invalid region for %s: %+v %v", tt.spec, rg, err)
+        }
+    }
+}
@@ -0,0 +1,66 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "io"
+    "context"
+    "fmt"
+)
+
+// Compact rewrites every sequence in src to dst in the given order with no
+// padding between records, dropping any unused space left by earlier edits
+// or appends. Packed data is copied without decoding. When dst is also an
+// io.ReaderAt (as files returned by os.Create are) the written file is read
+// back and the digest of every sequence is compared with src.
+func Compact(src *Reader, dst io.Writer, order Order) (error) {
+    before, err := src.Digests(context.Background(), 1)
+    if err != nil {
+        return err
+    }
+
+    w := NewWriter(FileVersion(int(src.hdr.version)), WithLayout(Layout{Order: order}))
+    err = w.copyFrom(src)
+    if err != nil {
+        return err
+    }
+
+    err = w.WriteTo(dst)
+    if err != nil {
+        return err
+    }
+
+    ra, ok := dst.(io.ReaderAt)
+    if !ok {
+        return nil
+    }
+
+    out, err := NewReaderAt(ra, w.Report().Bytes)
+    if err != nil {
+        return fmt.Errorf("Failed to read compacted file: %s", err)
+    }
+
+    after, err := out.Digests(context.Background(), 1)
+    if err != nil {
+        return fmt.Errorf("Failed to read compacted file: %s", err)
+    }
+
+    return compareDigests(before, after)
+}
+
+// Returns an error unless before and after hold the same digests
+func compareDigests(before, after map[string]string) (error) {
+    if len(before) != len(after) {
+        return fmt.Errorf("Compacted file has %d sequences, expected %d", len(after), len(before))
+    }
+
+    for name, d := range before {
+        if after[name] != d {
+            return fmt.Errorf("Digest mismatch for sequence %s after compaction", name)
+        }
+    }
+
+    return nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "os"
+    "path/filepath"
+    "reflect"
+)
+
+func TestCompact(t *testing.T) {
+    w := NewWriter(WithLayout(Layout{Alignment: 64, IndexPadding: 100}))
+    w.Add("chrB", "ACGTACGT")
+    w.Add("chrA", "NNNNacgtAC")
+    w.Add("chrC", "GG")
+
+    var padded bytes.Buffer
+    w.WriteTo(&padded)
+    src, err := NewReader(bytes.NewReader(padded.Bytes()))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    path := filepath.Join(t.TempDir(), "compact.2bit")
+    f, err := os.Create(path)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    err = Compact(src, f, LENGTH_ORDER)
+    f.Close()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    out, err := Open(path)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    defer out.Close()
+
+    if got := out.namesByOffset(); !reflect.DeepEqual(got, []string{"chrA", "chrB", "chrC"}) {
+        t.Errorf("Invalid compacted order: %v", got)
+    }
+    seq, _ := out.Read("chrA")
+    if string(seq) != "NNNNacgtAC" {
+        t.Errorf("Invalid compacted sequence: %s", seq)
+    }
+
+    info, _ := os.Stat(path)
+    if info.Size() >= int64(padded.Len()) {
+        t.Errorf("Compacted file is not smaller: %d >= %d", info.Size(), padded.Len())
+    }
+
+    var plain bytes.Buffer
+    err = Compact(src, &plain, NAME_ORDER)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if int64(plain.Len()) != info.Size() {
+        t.Errorf("Compacted sizes differ: %d != %d", plain.Len(), info.Size())
+    }
+
+    err = compareDigests(map[string]string{"a": "x"}, map[string]string{"a": "y"})
+    if err == nil {
+        t.Errorf("Digest mismatch not detected")
+    }
+}
@@ -0,0 +1,155 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+// complement maps each base to its complement preserving case. N, gap
+// characters and anything else without a complement map to themselves, so
+// masking and N blocks survive complementing.
+var complement [256]byte
+
+func init() {
+    for i := range complement {
+        complement[i] = byte(i)
+    }
+    // IUPAC ambiguity codes complement in pairs, S, W and N are their own
+    // complement
+    pairs := []string{"AT", "CG", "RY", "KM", "BV", "DH"}
+    for _, p := range pairs {
+        for _, c := range []string{p, string([]byte{p[0]+32, p[1]+32})} {
+            complement[c[0]] = c[1]
+            complement[c[1]] = c[0]
+        }
+    }
+}
+
+// ComplementInPlace replaces every base of seq with its complement. Case is
+// preserved so masked bases stay masked, N stays N and characters which are
+// not nucleotides are left unchanged.
+func ComplementInPlace(seq []byte) {
+    for i, c := range seq {
+        seq[i] = complement[c]
+    }
+}
+
+// Complement returns the complement of seq as a new slice. See
+// ComplementInPlace.
+func Complement(seq []byte) ([]byte) {
+    out := make([]byte, len(seq))
+    copy(out, seq)
+    ComplementInPlace(out)
+
+    return out
+}
+
+// ReverseComplementInPlace reverses seq and complements every base, giving
+// the sequence of the opposite strand. See ComplementInPlace.
+func ReverseComplementInPlace(seq []byte) {
+    for i, j := 0, len(seq)-1; i <= j; i, j = i+1, j-1 {
+        seq[i], seq[j] = complement[seq[j]], complement[seq[i]]
+    }
+}
+
+// ReverseComplement returns the reverse complement of seq as a new slice. See
+// ReverseComplementInPlace.
+func ReverseComplement(seq []byte) ([]byte) {
+    out := make([]byte, len(seq))
+    copy(out, seq)
+    ReverseComplementInPlace(out)
+
+    return out
+}
+
+// rcPacked maps a packed byte to the packed reverse complement of its four
+// bases. With T=0, C=1, A=2 and G=3 a base is complemented by flipping its
+// high bit.
+var rcPacked [256]byte
+
+func init() {
+    for i := range rcPacked {
+        b := byte(i)
+        var out byte
+        for j := 0; j < BASES_PER_BYTE; j++ {
+            out = out<<2 | (b&0x3)^0x2
+            b >>= 2
+        }
+        rcPacked[i] = out
+    }
+}
+
+// Return the packed reverse complement of the n bases in packed
+func reverseComplementPacked(packed []byte, n int) ([]byte) {
+    out := make([]byte, len(packed))
+    for i, b := range packed {
+        out[len(out)-1-i] = rcPacked[b]
+    }
+
+    // the padding at the end of packed is now at the start, shift it out
+    pad := uint(2*(len(packed)*BASES_PER_BYTE-n))
+    if pad > 0 {
+        for i := range out {
+            out[i] <<= pad
+            if i+1 < len(out) {
+                out[i] |= out[i+1] >> (8-pad)
+            }
+        }
+    }
+
+    return out
+}
+
+// Return blocks mirrored onto the opposite strand of a sequence of n bases
+func (bs Blocks) reverse(n int) (Blocks) {
+    out := make(Blocks, len(bs))
+    for i, b := range bs {
+        out[len(bs)-1-i] = &Block{Start: n-b.End(), Length: b.Length}
+    }
+
+    return out
+}
+
+// AddReverseComplement adds the reverse complement of sequence srcName in src
+// to w as name, for example to flip a contig assembled in the wrong
+// orientation. The packed data is flipped without decoding and N and mask
+// blocks are mirrored onto the new strand.
+func (w *Writer) AddReverseComplement(name string, src *Reader, srcName string) (error) {
+    if w.closed {
+        return ErrClosed
+    }
+    err := w.checkName(name)
+    if err != nil {
+        return err
+    }
+
+    rec, err := src.parseRecord(srcName, true)
+    if err != nil {
+        return err
+    }
+
+    // mirrored blocks index the packed data
+    err = rec.checkBlocks(srcName)
+    if err != nil {
+        return err
+    }
+
+    packed, err := src.ReadPackedRange(srcName, 0, 0)
+    if err != nil {
+        return err
+    }
+
+    n := int(rec.dnaSize)
+    out := &seqRecord{
+        dnaSize: rec.dnaSize,
+        nBlocks: rec.nBlocks.reverse(n),
+        mBlocks: rec.mBlocks.reverse(n),
+        sequence: reverseComplementPacked(packed, n),
+    }
+
+    // N is packed as T, not as the complement of T
+    for _, b := range out.nBlocks {
+        clearPacked(out.sequence, b.Start, b.End())
+    }
+
+    return w.addRecord(name, out)
+}
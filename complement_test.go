@@ -0,0 +1,85 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+)
+
+func TestComplement(t *testing.T) {
+    tests := []struct {
+        seq   string
+        comp  string
+        rc    string
+    }{
+        {"", "", ""},
+        {"A", "T", "T"},
+        {"ACGT", "TGCA", "ACGT"},
+        {"AACGn", "TTGCn", "nCGTT"},
+        {"acgtNNAC", "tgcaNNTG", "GTNNacgt"},
+        {"RYKMBVDHSW-", "YRMKVBHDSW-", "-WSDHBVKMRY"},
+    }
+
+    for _, tt := range tests {
+        seq := []byte(tt.seq)
+        if got := string(Complement(seq)); got != tt.comp {
+            t.Errorf("Complement(%s) = %s != %s", tt.seq, got, tt.comp)
+        }
+        if got := string(ReverseComplement(seq)); got != tt.rc {
+            t.Errorf("ReverseComplement(%s) = %s != %s", tt.seq, got, tt.rc)
+        }
+        if string(seq) != tt.seq {
+            t.Errorf("Input modified: %s", seq)
+        }
+
+        ReverseComplementInPlace(seq)
+        if string(seq) != tt.rc {
+            t.Errorf("ReverseComplementInPlace(%s) = %s", tt.seq, seq)
+        }
+        ReverseComplementInPlace(seq)
+        ComplementInPlace(seq)
+        if string(seq) != tt.comp {
+            t.Errorf("ComplementInPlace(%s) = %s", tt.seq, seq)
+        }
+    }
+}
+
+func TestAddReverseComplement(t *testing.T) {
+    seqs := map[string]string{
+        "even": "ACGTacgtNNNNGGCC",
+        "odd": "AACGTnnNNacGTA",
+        "pad1": "AACGTnnNNacGTAg",
+        "one": "c",
+        "empty": "",
+    }
+    tb := newTestReader(t, seqs)
+
+    for name, seq := range seqs {
+        w := NewWriter()
+        err := w.AddReverseComplement(name+"_rc", tb, name)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+
+        // packed data and blocks match adding the flipped string directly
+        rc := string(ReverseComplement([]byte(seq)))
+        good := NewWriter()
+        good.Add(name+"_rc", rc)
+
+        var got, want bytes.Buffer
+        w.WriteTo(&got)
+        good.WriteTo(&want)
+        if !bytes.Equal(got.Bytes(), want.Bytes()) {
+            t.Errorf("Reverse complement of %s differs from adding %s", seq, rc)
+        }
+    }
+
+    w := NewWriter()
+    err := w.AddReverseComplement("x", tb, "missing")
+    if err == nil {
+        t.Errorf("Missing source sequence accepted")
+    }
+}
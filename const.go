@@ -6,6 +6,31 @@ package twobit
 
 const SIG = 0x1A412743
 
+// Supported 2bit file versions. Version 1 files use 64-bit offsets in the
+// file index.
+const VERSION = 0
+const VERSION_LONG = 1
+
+// On-disk layout of the file header: sig, version, count, reserved
+const HEADER_SIZE = 16
+
+// On-disk layout of a file index entry: name size (1), name, offset (4)
+const INDEX_NAME_SIZE_LEN = 1
+const INDEX_OFFSET_LEN = 4
+const INDEX_OFFSET_LEN_LONG = 8
+const MAX_NAME_LEN = 255
+
+// On-disk layout of a sequence record. dnaSize, nBlockCount, nBlockStarts,
+// nBlockSizes, mBlockCount, mBlockStarts, mBlockSizes, reserved, packedDNA.
+// Each count, start, size and reserved field is a 32-bit integer.
+const RECORD_DNA_SIZE_LEN = 4
+const RECORD_BLOCK_COUNT_LEN = 4
+const RECORD_BLOCK_FIELD_LEN = 4
+const RECORD_RESERVED_LEN = 4
+
+// Number of bases packed in each byte of DNA
+const BASES_PER_BYTE = 4
+
 const defaultBufSize = 4096
 
 const BASE_N = 'N'
@@ -14,6 +39,12 @@ const BASE_C = 'C'
 const BASE_A = 'A'
 const BASE_G = 'G'
 
+// 2-bit encoding values for each base
+const ENCODE_T = 0
+const ENCODE_C = 1
+const ENCODE_A = 2
+const ENCODE_G = 3
+
 var BYTES2NT = []byte{
     BASE_T,
     BASE_C,
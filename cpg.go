@@ -0,0 +1,170 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "fmt"
+)
+
+// CpGCriteria are the thresholds a window must meet to be part of a CpG
+// island
+type CpGCriteria struct {
+    Length   int     // window length
+    GC       float64 // minimum G+C fraction
+    ObsExp   float64 // minimum observed/expected CpG ratio
+}
+
+// Criteria from Gardiner-Garden and Frommer (1987)
+var GardinerGarden = CpGCriteria{Length: 200, GC: 0.5, ObsExp: 0.6}
+
+// Criteria from Takai and Jones (2002)
+var TakaiJones = CpGCriteria{Length: 500, GC: 0.55, ObsExp: 0.65}
+
+// Size of the chunks decoded while scanning a sequence
+const scanChunkSize = 1 << 20
+
+// windowScanner decodes a sequence in chunks and gives access to bases in a
+// window sliding forward over the sequence. Bases are upper cased.
+type windowScanner struct {
+    r        *Reader
+    name     string
+    length   int
+    window   int
+    start    int
+    buf      []byte
+    n        int
+}
+
+func newWindowScanner(r *Reader, name string, window int) (*windowScanner, error) {
+    length, err := r.Length(name)
+    if err != nil {
+        return nil, err
+    }
+
+    size := scanChunkSize
+    if size < 4*window {
+        size = 4*window
+    }
+
+    return &windowScanner{r: r, name: name, length: length, window: window, buf: make([]byte, size)}, nil
+}
+
+// Return the base at i. Bases are only available from i-window onward, where
+// i is the largest position requested so far.
+func (s *windowScanner) at(i int) (byte, error) {
+    if i >= s.start+s.n {
+        s.start = i-s.window
+        if s.start < 0 {
+            s.start = 0
+        }
+        end := s.start+len(s.buf)
+        if end > s.length {
+            end = s.length
+        }
+        n, err := s.r.ReadRangeInto(s.buf, s.name, s.start, end)
+        if err != nil {
+            return 0, err
+        }
+        s.n = n
+    }
+
+    b := s.buf[i-s.start]
+    if b >= 'a' && b <= 'z' {
+        b -= 32
+    }
+
+    return b, nil
+}
+
+// Find CpG islands in sequence with name. Every window of c.Length bases
+// meeting the G+C and observed/expected CpG thresholds is found and
+// overlapping windows are merged into islands.
+func (r *Reader) CpGIslands(name string, c CpGCriteria) ([]Range, error) {
+    if c.Length < 2 {
+        return nil, fmt.Errorf("Invalid window length: %d", c.Length)
+    }
+
+    s, err := newWindowScanner(r, name, c.Length)
+    if err != nil {
+        return nil, err
+    }
+
+    islands := make([]Range, 0)
+    if s.length < c.Length {
+        return islands, nil
+    }
+
+    cCount, gCount, cpg := 0, 0, 0
+    var prev byte
+    island := Range{Name: name, Start: -1}
+    for i := 0; i < s.length; i++ {
+        b, err := s.at(i)
+        if err != nil {
+            return nil, err
+        }
+
+        switch b {
+        case 'C':
+            cCount++
+        case 'G':
+            gCount++
+            if prev == 'C' {
+                cpg++
+            }
+        }
+        prev = b
+
+        if i >= c.Length {
+            out, err := s.at(i-c.Length)
+            if err != nil {
+                return nil, err
+            }
+            next, err := s.at(i-c.Length+1)
+            if err != nil {
+                return nil, err
+            }
+            switch out {
+            case 'C':
+                cCount--
+                if next == 'G' {
+                    cpg--
+                }
+            case 'G':
+                gCount--
+            }
+        }
+
+        if i < c.Length-1 {
+            continue
+        }
+
+        start := i-c.Length+1
+        gc := float64(cCount+gCount) / float64(c.Length)
+        oe := 0.0
+        if cCount > 0 && gCount > 0 {
+            oe = float64(cpg*c.Length) / float64(cCount*gCount)
+        }
+
+        if gc < c.GC || oe < c.ObsExp {
+            continue
+        }
+
+        if island.Start >= 0 && start <= island.End {
+            island.End = i+1
+            continue
+        }
+
+        if island.Start >= 0 {
+            islands = append(islands, island)
+        }
+        island = Range{Name: name, Start: start, End: i+1}
+    }
+
+    if island.Start >= 0 {
+        islands = append(islands, island)
+    }
+
+    return islands, nil
+}
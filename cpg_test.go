@@ -0,0 +1,57 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "strings"
+)
+
+func TestCpGIslands(t *testing.T) {
+    flank := strings.Repeat("AT", 300)
+    seq := flank + strings.Repeat("cg", 150) + flank
+
+    w := NewWriter()
+    err := w.Add("chr1", seq)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    var out bytes.Buffer
+    err = w.WriteTo(&out)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    tb, err := NewReader(bytes.NewReader(out.Bytes()))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    islands, err := tb.CpGIslands("chr1", GardinerGarden)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    if len(islands) != 1 {
+        t.Fatalf("Invalid island count: %d != %d", len(islands), 1)
+    }
+
+    // windows need at least 100 G+C bases to pass the 50% threshold
+    if islands[0].Start != 500 || islands[0].End != 1000 {
+        t.Errorf("Invalid island: %d-%d", islands[0].Start, islands[0].End)
+    }
+
+    var bed bytes.Buffer
+    err = WriteBED(&bed, islands)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    if bed.String() != "chr1\t500\t1000\n" {
+        t.Errorf("Invalid BED output: %q", bed.String())
+    }
+}
@@ -0,0 +1,208 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "fmt"
+)
+
+// Assembly curation helpers. Each returns a new Writer holding a copy of src
+// with one edit applied. Sequences not touched by the edit keep their
+// position and are copied without decoding. Edits can be chained by writing
+// the result and opening it again.
+
+// JoinPart is one sequence joined by JoinRecords
+type JoinPart struct {
+    Name     string
+    Reverse  bool // reverse complement the part before joining
+    Gap      int  // Ns inserted between this part and the next
+}
+
+// Read sequence name with N blocks rendered as N whatever the gap character
+// of r, so it can be added to a Writer
+func (r *Reader) readForWrite(name string) ([]byte, error) {
+    rec, err := r.parseRecord(name, true)
+    if err != nil {
+        return nil, err
+    }
+
+    seq, err := r.Read(name)
+    if err != nil {
+        return nil, err
+    }
+    rec.applyBlocks(seq, 0, len(seq), BASE_N)
+
+    return seq, nil
+}
+
+// Copy every sequence of src to w in file order, calling edit instead for
+// the sequences in edited
+func (w *Writer) copyExcept(src *Reader, edited map[string]func() (error)) (error) {
+    for _, name := range src.namesByOffset() {
+        var err error
+        if edit, ok := edited[name]; ok {
+            err = edit()
+        } else {
+            err = w.copySequence(src, name, name)
+        }
+        if err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+// FlipRecord returns a copy of src in which sequence name is replaced by its
+// reverse complement, for example to fix a contig assembled in the wrong
+// orientation
+func FlipRecord(src *Reader, name string, opts ...WriterOption) (*Writer, error) {
+    _, ok, err := src.lookup(name)
+    if err != nil {
+        return nil, err
+    }
+    if !ok {
+        return nil, fmt.Errorf("Invalid sequence name: %s", name)
+    }
+
+    w := NewWriter(opts...)
+    err = w.copyExcept(src, map[string]func() (error){
+        name: func() (error) {
+            return w.AddReverseComplement(name, src, name)
+        },
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    return w, nil
+}
+
+// SplitRecord returns a copy of src in which sequence name is split at the
+// given 0-based positions, each the first base of a new piece. The pieces are
+// named by names, one more than the positions, and take the place of the
+// original sequence.
+func SplitRecord(src *Reader, name string, at []int, names []string, opts ...WriterOption) (*Writer, error) {
+    if len(names) != len(at)+1 {
+        return nil, fmt.Errorf("Splitting at %d positions needs %d names, got %d", len(at), len(at)+1, len(names))
+    }
+
+    size, err := src.Length(name)
+    if err != nil {
+        return nil, err
+    }
+
+    prev := 0
+    for _, pos := range at {
+        if pos <= prev || pos >= size {
+            return nil, fmt.Errorf("Invalid split position %d for %s: positions must be increasing and within 1-%d", pos, name, size-1)
+        }
+        prev = pos
+    }
+
+    w := NewWriter(opts...)
+    err = w.copyExcept(src, map[string]func() (error){
+        name: func() (error) {
+            seq, err := src.readForWrite(name)
+            if err != nil {
+                return err
+            }
+
+            bounds := append(append([]int{0}, at...), size)
+            for i, piece := range names {
+                err = w.Add(piece, string(seq[bounds[i]:bounds[i+1]]))
+                if err != nil {
+                    return err
+                }
+            }
+            return nil
+        },
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    return w, nil
+}
+
+// JoinRecords returns a copy of src in which the parts are replaced by a
+// single sequence name, built from the parts in the order given with each
+// part optionally reverse complemented and followed by a gap of Ns. The gap
+// of the last part is ignored. The joined sequence takes the place of
+// whichever part comes first in the file.
+func JoinRecords(src *Reader, name string, parts []JoinPart, opts ...WriterOption) (*Writer, error) {
+    if len(parts) == 0 {
+        return nil, fmt.Errorf("No sequences to join")
+    }
+
+    seen := make(map[string]bool)
+    for _, p := range parts {
+        _, ok, err := src.lookup(p.Name)
+        if err != nil {
+            return nil, err
+        }
+        if !ok {
+            return nil, fmt.Errorf("Invalid sequence name: %s", p.Name)
+        }
+        if seen[p.Name] {
+            return nil, fmt.Errorf("Sequence %s is joined more than once", p.Name)
+        }
+        if p.Gap < 0 {
+            return nil, fmt.Errorf("Invalid gap size after %s: %d", p.Name, p.Gap)
+        }
+        seen[p.Name] = true
+    }
+
+    w := NewWriter(opts...)
+    join := func() (error) {
+        err := w.StartSequence(name)
+        if err != nil {
+            return err
+        }
+
+        for i, p := range parts {
+            seq, err := src.readForWrite(p.Name)
+            if err != nil {
+                return err
+            }
+            if p.Reverse {
+                ReverseComplementInPlace(seq)
+            }
+
+            err = w.AppendChunk(string(seq))
+            if err == nil && i < len(parts)-1 {
+                err = w.AppendGap(p.Gap)
+            }
+            if err != nil {
+                return err
+            }
+        }
+
+        return w.EndSequence()
+    }
+
+    // the joined sequence is written in place of the first part in file
+    // order and the other parts are dropped
+    edited := make(map[string]func() (error))
+    first := true
+    for _, name := range src.namesByOffset() {
+        if !seen[name] {
+            continue
+        }
+        if first {
+            edited[name] = join
+            first = false
+        } else {
+            edited[name] = func() (error) { return nil }
+        }
+    }
+
+    err := w.copyExcept(src, edited)
+    if err != nil {
+        return nil, err
+    }
+
+    return w, nil
+}
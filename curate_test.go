@@ -0,0 +1,96 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "reflect"
+)
+
+// Write w and return a Reader over the result
+func reopen(t *testing.T, w *Writer) (*Reader) {
+    var out bytes.Buffer
+    err := w.WriteTo(&out)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    tb, err := NewReader(bytes.NewReader(out.Bytes()))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    return tb
+}
+
+func TestCuration(t *testing.T) {
+    w := NewWriter()
+    w.Add("ctg1", "ACGTNNaacc")
+    w.Add("ctg2", "GGGTTT")
+    w.Add("ctg3", "CCCC")
+    src := reopen(t, w)
+
+    flipped, err := FlipRecord(src, "ctg1")
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    tb := reopen(t, flipped)
+    if seq, _ := tb.Read("ctg1"); string(seq) != "ggttNNACGT" {
+        t.Errorf("Invalid flipped sequence: %s", seq)
+    }
+    if got := tb.namesByOffset(); !reflect.DeepEqual(got, []string{"ctg1", "ctg2", "ctg3"}) {
+        t.Errorf("Invalid order after flip: %v", got)
+    }
+
+    split, err := SplitRecord(src, "ctg1", []int{4, 6}, []string{"ctg1a", "ctg1b", "ctg1c"})
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    tb = reopen(t, split)
+    if got := tb.namesByOffset(); !reflect.DeepEqual(got, []string{"ctg1a", "ctg1b", "ctg1c", "ctg2", "ctg3"}) {
+        t.Errorf("Invalid order after split: %v", got)
+    }
+    for name, good := range map[string]string{"ctg1a": "ACGT", "ctg1b": "NN", "ctg1c": "aacc"} {
+        if seq, _ := tb.Read(name); string(seq) != good {
+            t.Errorf("Invalid split piece %s: %s != %s", name, seq, good)
+        }
+    }
+
+    for _, at := range [][]int{{0}, {10}, {6, 4}} {
+        _, err = SplitRecord(src, "ctg1", at, make([]string, len(at)+1))
+        if err == nil {
+            t.Errorf("Invalid split positions accepted: %v", at)
+        }
+    }
+    _, err = SplitRecord(src, "ctg1", []int{4}, []string{"a"})
+    if err == nil {
+        t.Errorf("Wrong number of names accepted")
+    }
+
+    joined, err := JoinRecords(src, "scaffold1", []JoinPart{
+        {Name: "ctg3", Gap: 3},
+        {Name: "ctg2", Reverse: true, Gap: 100},
+    })
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    tb = reopen(t, joined)
+    if got := tb.namesByOffset(); !reflect.DeepEqual(got, []string{"ctg1", "scaffold1"}) {
+        t.Errorf("Invalid order after join: %v", got)
+    }
+    if seq, _ := tb.Read("scaffold1"); string(seq) != "CCCCNNNAAACCC" {
+        t.Errorf("Invalid joined sequence: %s", seq)
+    }
+
+    _, err = JoinRecords(src, "x", []JoinPart{{Name: "ctg2"}, {Name: "ctg2"}})
+    if err == nil {
+        t.Errorf("Part joined twice accepted")
+    }
+    _, err = JoinRecords(src, "x", []JoinPart{{Name: "missing"}})
+    if err == nil {
+        t.Errorf("Missing part accepted")
+    }
+}
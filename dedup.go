@@ -0,0 +1,76 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "crypto/sha256"
+    "errors"
+    "fmt"
+)
+
+// ErrDuplicate is returned by Add when RefuseDuplicates is set and the
+// sequence is byte-identical to one already added
+var ErrDuplicate = errors.New("twobit: duplicate sequence")
+
+const (
+    dedupOff = iota
+    dedupFlag
+    dedupRefuse
+)
+
+// FlagDuplicates makes the Writer record sequences that are byte-identical to
+// a sequence already added under a different name. Duplicates are still
+// written and are reported by Duplicates.
+func FlagDuplicates() (WriterOption) {
+    return func(w *Writer) {
+        w.dedup = dedupFlag
+    }
+}
+
+// RefuseDuplicates makes Add return ErrDuplicate for sequences that are
+// byte-identical to a sequence already added under a different name.
+// Refused sequences are reported by Duplicates.
+func RefuseDuplicates() (WriterOption) {
+    return func(w *Writer) {
+        w.dedup = dedupRefuse
+    }
+}
+
+// Returns the duplicate sequences found by Add keyed by name. The value is
+// the name of the first sequence added with identical content.
+func (w *Writer) Duplicates() (map[string]string) {
+    dups := make(map[string]string, len(w.duplicates))
+    for name, first := range w.duplicates {
+        dups[name] = first
+    }
+
+    return dups
+}
+
+// Check the sequence with SHA-256 digest sum against the sequences already
+// added
+func (w *Writer) checkDuplicate(name string, sum [sha256.Size]byte) (error) {
+    if w.dedup == dedupOff {
+        return nil
+    }
+
+    if w.digests == nil {
+        w.digests = make(map[[sha256.Size]byte]string)
+        w.duplicates = make(map[string]string)
+    }
+
+    first, ok := w.digests[sum]
+    if !ok || first == name {
+        w.digests[sum] = name
+        return nil
+    }
+
+    w.duplicates[name] = first
+    if w.dedup == dedupRefuse {
+        return fmt.Errorf("%w: %s is identical to %s", ErrDuplicate, name, first)
+    }
+
+    return nil
+}
@@ -0,0 +1,56 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "errors"
+    "reflect"
+    "runtime"
+    "strings"
+)
+
+func TestDuplicates(t *testing.T) {
+    w := NewWriter(FlagDuplicates())
+    w.Add("phiX", "ACGTACGT")
+    w.Add("phiX_copy", "ACGTACGT")
+    w.Add("other", "acgtacgt")
+
+    if len(w.records) != 3 {
+        t.Errorf("Invalid sequence count: %d != %d", len(w.records), 3)
+    }
+
+    if !reflect.DeepEqual(w.Duplicates(), map[string]string{"phiX_copy": "phiX"}) {
+        t.Errorf("Invalid duplicates: %v", w.Duplicates())
+    }
+
+    w = NewWriter(RefuseDuplicates())
+    w.Add("phiX", "ACGTACGT")
+    err := w.Add("phiX_copy", "ACGTACGT")
+    if !errors.Is(err, ErrDuplicate) {
+        t.Errorf("Expected ErrDuplicate, got: %v", err)
+    }
+
+    if len(w.records) != 1 {
+        t.Errorf("Invalid sequence count: %d != %d", len(w.records), 1)
+    }
+}
+
+func TestDuplicatesOffNoHash(t *testing.T) {
+    seq := strings.Repeat("ACGT", 1<<18)
+    w := NewWriter()
+
+    // without dedup Add must not copy the sequence to hash it
+    var before, after runtime.MemStats
+    runtime.ReadMemStats(&before)
+    err := w.Add("chr1", seq)
+    runtime.ReadMemStats(&after)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if n := after.TotalAlloc-before.TotalAlloc; n >= uint64(len(seq)) {
+        t.Errorf("Add allocated %d bytes for a %d base sequence", n, len(seq))
+    }
+}
@@ -0,0 +1,45 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "go/parser"
+    "go/token"
+    "path/filepath"
+    "strconv"
+    "strings"
+)
+
+// The core package must only use the standard library and must not pull in
+// networking, which belongs in the remote package
+func TestNoDependencies(t *testing.T) {
+    files, err := filepath.Glob("*.go")
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    fset := token.NewFileSet()
+    for _, file := range files {
+        if strings.HasSuffix(file, "_test.go") {
+            continue
+        }
+
+        f, err := parser.ParseFile(fset, file, nil, parser.ImportsOnly)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+
+        for _, imp := range f.Imports {
+            path, _ := strconv.Unquote(imp.Path.Value)
+            if strings.Contains(strings.Split(path, "/")[0], ".") {
+                t.Errorf("%s imports non standard library package %s", file, path)
+            }
+            if strings.HasPrefix(path, "net") {
+                t.Errorf("%s imports networking package %s", file, path)
+            }
+        }
+    }
+}
@@ -0,0 +1,163 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "context"
+    "crypto"
+    _ "crypto/md5"
+    "fmt"
+    "sync"
+)
+
+// Returns the hex encoded MD5 digest of the upper case sequence with name.
+// Masking does not change the digest so differently masked copies of the
+// same sequence compare equal.
+func (r *Reader) Digest(name string) (string, error) {
+    sum, err := r.RangeDigest(name, 0, 0, crypto.MD5)
+    if err != nil {
+        return "", err
+    }
+
+    return fmt.Sprintf("%x", sum), nil
+}
+
+// Returns the digest of the upper case sequence name from start to end (see
+// ReadRange for range semantics) using hash h. The range is decoded and hashed
+// in chunks so arbitrarily large ranges use constant memory. The package
+// implementing h must be linked into the binary.
+func (r *Reader) RangeDigest(name string, start, end int, h crypto.Hash) ([]byte, error) {
+    if !h.Available() {
+        return nil, fmt.Errorf("Hash function %d is not available", h)
+    }
+
+    rec, err := r.parseRecord(name, true)
+    if err != nil {
+        return nil, err
+    }
+
+    start, end, err = clampRange(start, end, int(rec.dnaSize))
+    if err != nil {
+        return nil, err
+    }
+
+    hash := h.New()
+    size := scanChunkSize
+    if end-start < size {
+        size = end-start
+    }
+    chunk := make([]byte, size)
+    for pos := start; pos < end; pos += len(chunk) {
+        if end-pos < len(chunk) {
+            chunk = chunk[:end-pos]
+        }
+
+        err = r.readInto(chunk, rec, pos, pos+len(chunk))
+        if err != nil {
+            return nil, err
+        }
+
+        ToUpper(chunk)
+        hash.Write(chunk)
+    }
+
+    return hash.Sum(nil), nil
+}
+
+// Returns the Digest of every sequence keyed by name, computed by
+// concurrency goroutines each reading the file independently. The underlying
+// reader must implement io.ReaderAt (as files opened with Open do) for more
+// than one goroutine to be used, otherwise digests are computed serially.
+// Cancelling ctx stops the computation and returns ctx.Err().
+func (r *Reader) Digests(ctx context.Context, concurrency int) (map[string]string, error) {
+    digests := make(map[string]string)
+    var mu sync.Mutex
+
+    err := r.parallel(ctx, concurrency, func(worker *Reader, name string) (error) {
+        d, err := worker.Digest(name)
+        if err != nil {
+            return err
+        }
+        mu.Lock()
+        digests[name] = d
+        mu.Unlock()
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    return digests, nil
+}
+
+// Call fn for every sequence in file order from concurrency goroutines, each
+// with its own fork of r. Falls back to a single goroutine using r when the
+// underlying reader does not implement io.ReaderAt. The first error returned
+// by fn or the cancellation of ctx stops the remaining calls.
+func (r *Reader) parallel(ctx context.Context, concurrency int, fn func(worker *Reader, name string) (error)) (error) {
+    names := r.namesByOffset()
+    for _, name := range names {
+        _, err := r.parseRecord(name, true)
+        if err != nil {
+            return err
+        }
+    }
+
+    if concurrency < 1 || r.src == nil {
+        concurrency = 1
+    }
+
+    jobs := make(chan string)
+    errs := make(chan error, concurrency)
+    var wg sync.WaitGroup
+
+    for i := 0; i < concurrency; i++ {
+        worker := r
+        if concurrency > 1 {
+            f, err := r.fork()
+            if err != nil {
+                return err
+            }
+            worker = f
+        }
+
+        wg.Add(1)
+        go func(worker *Reader) {
+            defer wg.Done()
+            for name := range jobs {
+                err := fn(worker, name)
+                if err != nil {
+                    errs <- err
+                    return
+                }
+            }
+        }(worker)
+    }
+
+    var err error
+send:
+    for _, name := range names {
+        select {
+        case jobs <- name:
+        case err = <-errs:
+            break send
+        case <-ctx.Done():
+            err = ctx.Err()
+            break send
+        }
+    }
+    close(jobs)
+    wg.Wait()
+
+    if err == nil {
+        select {
+        case err = <-errs:
+        default:
+            err = ctx.Err()
+        }
+    }
+
+    return err
+}
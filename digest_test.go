@@ -0,0 +1,77 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "crypto"
+    "crypto/sha256"
+    "bytes"
+    "context"
+    "fmt"
+)
+
+func TestRangeDigest(t *testing.T) {
+    tb, err := openTestTwoBit()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    sum, err := tb.RangeDigest("ex1", 3, 15, crypto.SHA256)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    good := sha256.Sum256([]byte("GCCTTTNNNNAN"))
+    if !bytes.Equal(sum, good[:]) {
+        t.Errorf("Invalid digest: %x != %x", sum, good)
+    }
+
+    other := newTestReader(t, map[string]string{"chrA": "ttGCCTTTnnnNANcc"})
+    sum2, err := other.RangeDigest("chrA", 2, 14, crypto.SHA256)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    if !bytes.Equal(sum, sum2) {
+        t.Errorf("Digests of identical ranges differ: %x != %x", sum, sum2)
+    }
+
+    _, err = tb.RangeDigest("ex1", 0, 0, crypto.Hash(0))
+    if err == nil {
+        t.Errorf("Expected error for unavailable hash")
+    }
+}
+
+func TestDigests(t *testing.T) {
+    seqs := make(map[string]string)
+    for i := 0; i < 20; i++ {
+        seqs[fmt.Sprintf("chr%d", i)] = fmt.Sprintf("ACGTnnNN%dacgt", i)
+    }
+    tb := newTestReader(t, seqs)
+
+    for _, concurrency := range []int{0, 1, 4} {
+        digests, err := tb.Digests(context.Background(), concurrency)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        if len(digests) != len(seqs) {
+            t.Fatalf("Invalid number of digests: %d != %d", len(digests), len(seqs))
+        }
+        for name := range seqs {
+            good, _ := tb.Digest(name)
+            if digests[name] != good {
+                t.Errorf("Invalid digest for %s: %s != %s", name, digests[name], good)
+            }
+        }
+    }
+
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    _, err := tb.Digests(ctx, 4)
+    if err != context.Canceled {
+        t.Errorf("Expected context.Canceled, got: %v", err)
+    }
+}
@@ -0,0 +1,44 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+//go:build ignore
+
+// Writes the synthetic genome used by the profiling examples with
+// testfixtures.Assembly, the generator of the benchmarks. The output is
+// deterministic for a given seed, the checked in synthetic.2bit was made with
+// the defaults:
+//
+//   go run examples/make_synthetic.go -out examples/synthetic.2bit
+package main
+
+import (
+    "flag"
+    "log"
+    "github.com/aebruno/twobit"
+    "github.com/aebruno/twobit/testfixtures"
+)
+
+func main() {
+    out := flag.String("out", "synthetic.2bit", "output file")
+    chroms := flag.Int("chroms", 2, "number of chromosomes")
+    size := flag.Int("size", 2000000, "bases per chromosome")
+    scaffolds := flag.Int("scaffolds", 100, "number of 1-6kb scaffolds")
+    seed := flag.Int64("seed", 1, "random seed")
+    flag.Parse()
+
+    w, err := twobit.Create(*out)
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    err = testfixtures.Assembly(w, *seed, *chroms, *size, *scaffolds)
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    err = w.Close()
+    if err != nil {
+        log.Fatal(err)
+    }
+}
@@ -0,0 +1,90 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+//go:build ignore
+
+// Exercises the pack and write hot path for profiling. The sequences of a
+// 2bit file (by default the synthetic genome in this directory) are decoded
+// once and then packed and written to a discarded .2bit n times, with the CPU
+// and heap profiles written for pprof:
+//
+//   go run examples/profile_pack.go -in examples/synthetic.2bit -cpuprofile cpu.prof
+//   go tool pprof -top cpu.prof
+package main
+
+import (
+    "flag"
+    "fmt"
+    "io/ioutil"
+    "log"
+    "os"
+    "runtime"
+    "runtime/pprof"
+    "time"
+    "github.com/aebruno/twobit"
+)
+
+func main() {
+    in := flag.String("in", "examples/synthetic.2bit", "input file (.2bit)")
+    cpuProfile := flag.String("cpuprofile", "", "write a CPU profile to file")
+    memProfile := flag.String("memprofile", "", "write a heap profile to file")
+    n := flag.Int("n", 20, "passes over the genome")
+    flag.Parse()
+
+    tb, err := twobit.Open(*in)
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    names := tb.Names()
+    seqs := make([]string, len(names))
+    bases := 0
+    for i, name := range names {
+        seq, err := tb.Read(name)
+        if err != nil {
+            log.Fatal(err)
+        }
+        seqs[i] = string(seq)
+        bases += len(seq)
+    }
+    tb.Close()
+
+    if *cpuProfile != "" {
+        f, err := os.Create(*cpuProfile)
+        if err != nil {
+            log.Fatal(err)
+        }
+        defer f.Close()
+        pprof.StartCPUProfile(f)
+        defer pprof.StopCPUProfile()
+    }
+
+    begin := time.Now()
+    for i := 0; i < *n; i++ {
+        w := twobit.NewWriter()
+        for j, name := range names {
+            err := w.Add(name, seqs[j])
+            if err != nil {
+                log.Fatal(err)
+            }
+        }
+        err := w.WriteTo(ioutil.Discard)
+        if err != nil {
+            log.Fatal(err)
+        }
+    }
+    elapsed := time.Since(begin)
+
+    fmt.Printf("packed %d bases in %s (%.1f MB/s)\n", bases**n, elapsed, float64(bases**n)/elapsed.Seconds()/1e6)
+
+    if *memProfile != "" {
+        f, err := os.Create(*memProfile)
+        if err != nil {
+            log.Fatal(err)
+        }
+        defer f.Close()
+        runtime.GC()
+        pprof.WriteHeapProfile(f)
+    }
+}
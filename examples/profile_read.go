@@ -0,0 +1,107 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+//go:build ignore
+
+// Exercises the decode hot path for profiling. Whole sequences or short
+// random ranges are read from a 2bit file (by default the synthetic genome
+// in this directory) and the CPU and heap profiles are written for pprof:
+//
+//   go run examples/profile_read.go -in examples/synthetic.2bit -cpuprofile cpu.prof
+//   go tool pprof -top cpu.prof
+package main
+
+import (
+    "flag"
+    "fmt"
+    "log"
+    "math/rand"
+    "os"
+    "runtime"
+    "runtime/pprof"
+    "time"
+    "github.com/aebruno/twobit"
+)
+
+func main() {
+    in := flag.String("in", "examples/synthetic.2bit", "input file (.2bit)")
+    cpuProfile := flag.String("cpuprofile", "", "write a CPU profile to file")
+    memProfile := flag.String("memprofile", "", "write a heap profile to file")
+    mode := flag.String("mode", "whole", "read whole sequences (whole) or random ranges (random)")
+    n := flag.Int("n", 200, "passes over the genome (whole) or thousands of ranges (random)")
+    width := flag.Int("width", 100, "bases per random range")
+    flag.Parse()
+
+    tb, err := twobit.Open(*in)
+    if err != nil {
+        log.Fatal(err)
+    }
+    defer tb.Close()
+
+    lengths, err := tb.LengthAll()
+    if err != nil {
+        log.Fatal(err)
+    }
+    names := make([]string, 0, len(lengths))
+    for name, size := range lengths {
+        if size > *width {
+            names = append(names, name)
+        }
+    }
+    if len(names) == 0 {
+        log.Fatalf("No sequences longer than %d bases", *width)
+    }
+
+    if *cpuProfile != "" {
+        f, err := os.Create(*cpuProfile)
+        if err != nil {
+            log.Fatal(err)
+        }
+        defer f.Close()
+        pprof.StartCPUProfile(f)
+        defer pprof.StopCPUProfile()
+    }
+
+    begin := time.Now()
+    bases := 0
+    switch *mode {
+    case "whole":
+        for i := 0; i < *n; i++ {
+            for _, name := range names {
+                seq, err := tb.Read(name)
+                if err != nil {
+                    log.Fatal(err)
+                }
+                bases += len(seq)
+            }
+        }
+    case "random":
+        rng := rand.New(rand.NewSource(1))
+        dst := make([]byte, *width)
+        for i := 0; i < *n*1000; i++ {
+            name := names[rng.Intn(len(names))]
+            start := rng.Intn(lengths[name]-*width)
+            k, err := tb.ReadRangeInto(dst, name, start, start+*width)
+            if err != nil {
+                log.Fatal(err)
+            }
+            bases += k
+        }
+    default:
+        log.Fatalf("Invalid mode: %s", *mode)
+    }
+    elapsed := time.Since(begin)
+
+    fmt.Printf("read %d bases in %s (%.1f MB/s)\n", bases, elapsed, float64(bases)/elapsed.Seconds()/1e6)
+
+    if *memProfile != "" {
+        f, err := os.Create(*memProfile)
+        if err != nil {
+            log.Fatal(err)
+        }
+        defer f.Close()
+        runtime.GC()
+        pprof.WriteHeapProfile(f)
+    }
+}
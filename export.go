@@ -0,0 +1,293 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "bufio"
+    "compress/gzip"
+    "context"
+    "crypto/md5"
+    "encoding/json"
+    "fmt"
+    "io"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Extension appended to a FASTA path to name its export cursor
+const CURSOR_EXT = ".cursor.json"
+
+// Name of the checksum manifest written by ExportFastaDir
+const CHECKSUM_FILE = "md5sum.txt"
+
+// ExportCursor records the progress of ExportFasta. Sequences are exported in
+// file order so only the number of completed sequences and the name of the
+// last one, to check the cursor belongs to the file, are kept. Offset is the
+// size of the output after the last completed sequence.
+type ExportCursor struct {
+    Completed  int    `json:"completed"`
+    Last       string `json:"last"`
+    Offset     int64  `json:"offset"`
+}
+
+// ExportOption configures ExportFasta
+type ExportOption func(*exportConfig)
+
+type exportConfig struct {
+    resume  bool
+    policy  CasePolicy
+}
+
+// Resume continues an interrupted export from its cursor, skipping sequences
+// already written and truncating any partially written record. Without a
+// cursor the export starts from the beginning.
+func Resume() (ExportOption) {
+    return func(c *exportConfig) {
+        c.resume = true
+    }
+}
+
+// OutputCase writes the sequences in the case selected by p instead of as
+// stored. The policy applies to a resumed export too, so it must not change
+// between runs.
+func OutputCase(p CasePolicy) (ExportOption) {
+    return func(c *exportConfig) {
+        c.policy = p
+    }
+}
+
+// Read the export cursor for the FASTA file at path
+func readCursor(path string) (*ExportCursor, error) {
+    f, err := os.Open(path+CURSOR_EXT)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+
+    c := new(ExportCursor)
+    err = json.NewDecoder(f).Decode(c)
+    if err != nil {
+        return nil, fmt.Errorf("Failed to read export cursor: %s", err)
+    }
+
+    return c, nil
+}
+
+// Replace the export cursor for the FASTA file at path. The cursor is
+// written to a temporary file and renamed so it is never seen half written.
+func (c *ExportCursor) write(path string) (error) {
+    f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".cursor")
+    if err != nil {
+        return err
+    }
+
+    err = json.NewEncoder(f).Encode(c)
+    if err == nil {
+        err = f.Sync()
+    }
+    cerr := f.Close()
+    if err == nil {
+        err = cerr
+    }
+    if err != nil {
+        os.Remove(f.Name())
+        return err
+    }
+
+    return os.Rename(f.Name(), path+CURSOR_EXT)
+}
+
+// ExportFasta writes every sequence in r to the FASTA file at path in file
+// order. Progress is synced to disk and recorded in a cursor (path+CURSOR_EXT)
+// at most every CHECKPOINT_INTERVAL so a long export interrupted by a failure
+// can be restarted with the Resume option. The cursor is removed once the
+// export completes.
+func ExportFasta(r *Reader, path string, opts ...ExportOption) (error) {
+    cfg := new(exportConfig)
+    for _, opt := range opts {
+        opt(cfg)
+    }
+
+    names := r.namesByOffset()
+    cursor := new(ExportCursor)
+    flags := os.O_WRONLY|os.O_CREATE|os.O_TRUNC
+    if cfg.resume {
+        c, err := readCursor(path)
+        if err == nil {
+            if c.Completed < 0 || c.Completed > len(names) || (c.Completed > 0 && names[c.Completed-1] != c.Last) {
+                return fmt.Errorf("Export cursor %s does not match the sequences of the file", path+CURSOR_EXT)
+            }
+            cursor = c
+            flags = os.O_WRONLY
+        } else if !os.IsNotExist(err) {
+            return err
+        }
+    }
+
+    f, err := os.OpenFile(path, flags, 0666)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+
+    // drop anything written after the last completed sequence
+    err = f.Truncate(cursor.Offset)
+    if err != nil {
+        return err
+    }
+    _, err = f.Seek(cursor.Offset, io.SeekStart)
+    if err != nil {
+        return err
+    }
+
+    w := bufio.NewWriter(f)
+    synced := time.Now()
+    checkpoint := func(completed int) (error) {
+        err := w.Flush()
+        if err == nil {
+            err = f.Sync()
+        }
+        if err != nil {
+            return err
+        }
+        cursor.Offset, err = f.Seek(0, io.SeekCurrent)
+        if err != nil {
+            return err
+        }
+        cursor.Completed = completed
+        cursor.Last = names[completed-1]
+        synced = time.Now()
+        return cursor.write(path)
+    }
+
+    for i := cursor.Completed; i < len(names); i++ {
+        name := names[i]
+        seq, err := r.Read(name)
+        if err != nil {
+            return err
+        }
+        cfg.policy.Apply(seq)
+
+        err = writeFasta(w, name, seq)
+        if err != nil {
+            return fmt.Errorf("Failed to write sequence %s: %s", name, err)
+        }
+
+        if time.Since(synced) >= CHECKPOINT_INTERVAL {
+            err = checkpoint(i+1)
+            if err != nil {
+                return fmt.Errorf("Failed to write sequence %s: %s", name, err)
+            }
+        }
+    }
+
+    err = w.Flush()
+    if err != nil {
+        return err
+    }
+    err = f.Close()
+    if err != nil {
+        return err
+    }
+
+    err = os.Remove(path+CURSOR_EXT)
+    if err != nil && !os.IsNotExist(err) {
+        return err
+    }
+
+    return nil
+}
+
+// ExportFastaDir writes each sequence in r to its own gzip compressed FASTA
+// file (name.fa.gz) in dir, laid out like the UCSC bigZips chromosome
+// downloads, along with a manifest (CHECKSUM_FILE) of the md5 of every file
+// in the format of md5sum. Sequences are decoded and compressed by
+// concurrency goroutines, see Digests.
+func ExportFastaDir(ctx context.Context, r *Reader, dir string, concurrency int) (error) {
+    names := r.namesByOffset()
+    for _, name := range names {
+        if name == "." || name == ".." || strings.ContainsAny(name, "/\\") {
+            return fmt.Errorf("Sequence name %s cannot be used as a file name", name)
+        }
+    }
+
+    err := os.MkdirAll(dir, 0755)
+    if err != nil {
+        return err
+    }
+
+    sums := make(map[string]string, len(names))
+    var mu sync.Mutex
+
+    err = r.parallel(ctx, concurrency, func(worker *Reader, name string) (error) {
+        sum, err := worker.exportGzip(name, filepath.Join(dir, name+".fa.gz"))
+        if err != nil {
+            return err
+        }
+        mu.Lock()
+        sums[name] = sum
+        mu.Unlock()
+        return nil
+    })
+    if err != nil {
+        return err
+    }
+
+    f, err := os.Create(filepath.Join(dir, CHECKSUM_FILE))
+    if err != nil {
+        return err
+    }
+
+    w := bufio.NewWriter(f)
+    for _, name := range names {
+        fmt.Fprintf(w, "%s  %s.fa.gz\n", sums[name], name)
+    }
+    err = w.Flush()
+    cerr := f.Close()
+    if err == nil {
+        err = cerr
+    }
+
+    return err
+}
+
+// Write sequence name as gzip compressed FASTA to path and return the hex
+// encoded md5 of the compressed file
+func (r *Reader) exportGzip(name, path string) (string, error) {
+    seq, err := r.Read(name)
+    if err != nil {
+        return "", err
+    }
+
+    f, err := os.Create(path)
+    if err != nil {
+        return "", err
+    }
+    defer f.Close()
+
+    h := md5.New()
+    zw := gzip.NewWriter(io.MultiWriter(f, h))
+    w := bufio.NewWriter(zw)
+
+    err = writeFasta(w, name, seq)
+    if err == nil {
+        err = w.Flush()
+    }
+    if err == nil {
+        err = zw.Close()
+    }
+    if err == nil {
+        err = f.Close()
+    }
+    if err != nil {
+        return "", fmt.Errorf("Failed to write %s: %s", path, err)
+    }
+
+    return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "compress/gzip"
+    "context"
+    "crypto/md5"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+func TestExportFasta(t *testing.T) {
+    tb := newTestReader(t, map[string]string{"chr1": "ACGTNNNNacgt", "chr2": "GGGGCCCC", "chr3": "TTTTaaaaNN"})
+    dir := t.TempDir()
+
+    full := filepath.Join(dir, "full.fa")
+    err := ExportFasta(tb, full)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    want, _ := ioutil.ReadFile(full)
+    if !bytes.Contains(want, []byte(">chr2\nGGGGCCCC\n")) {
+        t.Errorf("Invalid export: %s", want)
+    }
+    if _, err := os.Stat(full+CURSOR_EXT); !os.IsNotExist(err) {
+        t.Errorf("Cursor not removed after export")
+    }
+
+    // simulate an export interrupted while writing the second sequence
+    names := tb.namesByOffset()
+    first := []byte(">"+names[0]+"\n")
+    seq, _ := tb.Read(names[0])
+    first = append(first, seq...)
+    first = append(first, '\n')
+
+    part := filepath.Join(dir, "part.fa")
+    ioutil.WriteFile(part, append(append([]byte{}, first...), ">"+names[1]+"\nGG"...), 0644)
+    cursor := &ExportCursor{Completed: 1, Last: names[0], Offset: int64(len(first))}
+    err = cursor.write(part)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    err = ExportFasta(tb, part, Resume())
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    got, _ := ioutil.ReadFile(part)
+    if !bytes.Equal(got, want) {
+        t.Errorf("Resumed export differs: %q != %q", got, want)
+    }
+
+    // a cursor from another file is refused
+    cursor = &ExportCursor{Completed: 1, Last: "chrX", Offset: int64(len(first))}
+    cursor.write(part)
+    err = ExportFasta(tb, part, Resume())
+    if err == nil {
+        t.Errorf("Expected error for a cursor of another file")
+    }
+    os.Remove(part+CURSOR_EXT)
+
+    // resume without a cursor starts over
+    err = ExportFasta(tb, part, Resume())
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    got, _ = ioutil.ReadFile(part)
+    if !bytes.Equal(got, want) {
+        t.Errorf("Export without cursor differs: %q != %q", got, want)
+    }
+
+    upper := filepath.Join(dir, "upper.fa")
+    err = ExportFasta(tb, upper, OutputCase(CASE_UPPER))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    got, _ = ioutil.ReadFile(upper)
+    if len(got) != len(want) || !bytes.Contains(got, []byte(">chr3\nTTTTAAAANN\n")) || !bytes.Contains(got, []byte(">chr1\nACGTNNNNACGT\n")) {
+        t.Errorf("Invalid upper case export: %q", got)
+    }
+}
+
+func TestExportFastaDir(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "test.2bit")
+    w, _ := Create(path)
+    w.Add("chr1", "ACGTNNNNacgt")
+    w.Add("chr2", "GGGGCCCC")
+    w.Add("chrM", "TTTTaaaaNN")
+    err := w.Close()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    tb, err := Open(path)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    defer tb.Close()
+
+    dir := filepath.Join(t.TempDir(), "chromFa")
+    err = ExportFastaDir(context.Background(), tb, dir, 2)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    manifest, err := ioutil.ReadFile(filepath.Join(dir, CHECKSUM_FILE))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    lines := strings.Split(strings.TrimSpace(string(manifest)), "\n")
+    if len(lines) != 3 || !strings.HasSuffix(lines[2], "  chrM.fa.gz") {
+        t.Errorf("Invalid manifest: %s", manifest)
+    }
+
+    data, _ := ioutil.ReadFile(filepath.Join(dir, "chr1.fa.gz"))
+    if lines[0] != fmt.Sprintf("%x  chr1.fa.gz", md5.Sum(data)) {
+        t.Errorf("Invalid checksum line: %s", lines[0])
+    }
+
+    zr, err := gzip.NewReader(bytes.NewReader(data))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    fa, _ := ioutil.ReadAll(zr)
+    if string(fa) != ">chr1\nACGTNNNNacgt\n" {
+        t.Errorf("Invalid FASTA: %q", fa)
+    }
+
+    bad := newTestReader(t, map[string]string{"un/placed": "ACGT"})
+    err = ExportFastaDir(context.Background(), bad, dir, 1)
+    if err == nil {
+        t.Errorf("Name with path separator accepted")
+    }
+}
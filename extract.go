@@ -0,0 +1,89 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+// Shift packed bases left so the base at phase (0-3) of the first byte
+// becomes the first base, keeping n bases and zeroing the padding bits
+func shiftPacked(packed []byte, phase, n int) ([]byte) {
+    out := make([]byte, packedSize(n))
+    shift := uint(2*phase)
+    for k := range out {
+        b := packed[k]<<shift
+        if shift > 0 && k+1 < len(packed) {
+            b |= packed[k+1]>>(8-shift)
+        }
+        out[k] = b
+    }
+
+    if tail := n%BASES_PER_BYTE; tail > 0 {
+        out[len(out)-1] &= 0xff<<uint(2*(BASES_PER_BYTE-tail))
+    }
+
+    return out
+}
+
+// Return the parts of bs within start to end, moved to start at 0
+func (bs Blocks) rebase(start, end int) (Blocks) {
+    out := make(Blocks, 0)
+    for _, b := range bs {
+        lo, hi := b.clip(start, end)
+        if lo < hi {
+            out = append(out, &Block{Start: lo-start, Length: hi-lo})
+        }
+    }
+
+    return out
+}
+
+// AddInterval adds the bases of iv in src to w as a new sequence name. The
+// packed data is copied without decoding and N and mask blocks are clipped
+// to the interval and rebased to start at 0.
+func (w *Writer) AddInterval(name string, src *Reader, iv Interval) (error) {
+    if w.closed {
+        return ErrClosed
+    }
+    err := w.checkName(name)
+    if err != nil {
+        return err
+    }
+
+    iv, err = src.checkRegion(iv)
+    if err != nil {
+        return err
+    }
+
+    rec, err := src.parseRecord(iv.Name, true)
+    if err != nil {
+        return err
+    }
+
+    packed, err := src.ReadPackedRange(iv.Name, iv.Start, iv.End)
+    if err != nil {
+        return err
+    }
+
+    return w.addRecord(name, &seqRecord{
+        dnaSize: uint32(iv.Len()),
+        nBlocks: rec.nBlocks.rebase(iv.Start, iv.End),
+        mBlocks: rec.mBlocks.rebase(iv.Start, iv.End),
+        sequence: shiftPacked(packed, iv.Start%BASES_PER_BYTE, iv.Len()),
+    })
+}
+
+// ExtractIntervals returns a new Writer holding each interval of src as its
+// own sequence, in the order given and named as printed by Interval.String
+// (for example chr1:1000000-2000000). This makes small test references from
+// real genomes with their masking and gaps intact.
+func ExtractIntervals(src *Reader, ivs []Interval, opts ...WriterOption) (*Writer, error) {
+    w := NewWriter(opts...)
+    for _, iv := range ivs {
+        err := w.AddInterval(iv.String(), src, iv)
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    return w, nil
+}
@@ -0,0 +1,53 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "math/rand"
+)
+
+func TestExtractIntervals(t *testing.T) {
+    rng := rand.New(rand.NewSource(1))
+    seq := fragmented(rng, 5000)
+    src := newTestReader(t, map[string]string{"chr1": seq, "chr2": "ACGTacgtNNNN"})
+
+    ivs := []Interval{{"chr2", 0, 12}, {"chr2", 5, 10}}
+    for i := 0; i < 50; i++ {
+        start := rng.Intn(len(seq)-1)
+        ivs = append(ivs, Interval{"chr1", start, start+1+rng.Intn(len(seq)-start-1)})
+    }
+
+    w, err := ExtractIntervals(src, ivs)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    tb := reopen(t, w)
+
+    for _, iv := range ivs {
+        got, err := tb.Read(iv.String())
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        good, _ := src.ReadInterval(iv)
+        if string(got) != string(good) {
+            t.Errorf("Invalid sequence %s: %s != %s", iv, got, good)
+        }
+    }
+
+    // padding bits of the last byte are zero as written by Add
+    direct := NewWriter()
+    direct.Add("chr2:5-10", "cgtNN")
+    a := w.records["chr2:5-10"]
+    b := direct.records["chr2:5-10"]
+    if string(a.sequence) != string(b.sequence) {
+        t.Errorf("Invalid packed data: %x != %x", a.sequence, b.sequence)
+    }
+
+    _, err = ExtractIntervals(src, []Interval{{"chr2", 4, 20}})
+    if err == nil {
+        t.Errorf("Interval past end accepted")
+    }
+}
@@ -0,0 +1,184 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "io"
+    "bufio"
+    "bytes"
+    "fmt"
+    "strconv"
+    "strings"
+)
+
+// FaiRecord is one line of a samtools faidx (.fai) index
+type FaiRecord struct {
+    Name       string
+    Length     int   // number of bases
+    Offset     int64 // byte offset of the first base in the FASTA file
+    LineBases  int   // bases per line
+    LineBytes  int   // bytes per line including the line ending
+}
+
+// Return the number of bytes from the first base to the last base of e
+func (e *FaiRecord) span() (int64) {
+    if e.Length == 0 {
+        return 0
+    }
+
+    lines := int64((e.Length-1)/e.LineBases)
+    return lines*int64(e.LineBytes)+int64(e.Length)-lines*int64(e.LineBases)
+}
+
+// ReadFai parses a samtools faidx index read from in. Columns after the
+// fifth, such as the quality offset of FASTQ indexes, are ignored.
+func ReadFai(in io.Reader) ([]*FaiRecord, error) {
+    records := make([]*FaiRecord, 0)
+    s := bufio.NewScanner(in)
+    line := 0
+    for s.Scan() {
+        line++
+        text := strings.TrimRight(s.Text(), "\r")
+        if len(strings.TrimSpace(text)) == 0 {
+            continue
+        }
+
+        fields := strings.Split(text, "\t")
+        if len(fields) < 5 {
+            return nil, fmt.Errorf("Invalid fai line %d: expected 5 columns, got %d", line, len(fields))
+        }
+
+        e := &FaiRecord{Name: fields[0]}
+        var vals [4]int64
+        for i := range vals {
+            v, err := strconv.ParseInt(fields[i+1], 10, 64)
+            if err != nil || v < 0 {
+                return nil, fmt.Errorf("Invalid fai line %d: bad number %q", line, fields[i+1])
+            }
+            vals[i] = v
+        }
+        e.Offset = vals[1]
+
+        var err error
+        e.Length, err = toInt(vals[0])
+        if err == nil {
+            e.LineBases, err = toInt(vals[2])
+        }
+        if err == nil {
+            e.LineBytes, err = toInt(vals[3])
+        }
+        if err != nil {
+            return nil, fmt.Errorf("Invalid fai line %d: %s", line, err)
+        }
+        if e.Length > 0 && (e.LineBases == 0 || e.LineBytes < e.LineBases) {
+            return nil, fmt.Errorf("Invalid fai line %d: %d bases in lines of %d bytes", line, e.LineBases, e.LineBytes)
+        }
+
+        records = append(records, e)
+    }
+
+    if err := s.Err(); err != nil {
+        return nil, fmt.Errorf("Failed to read fai: %s", err)
+    }
+
+    return records, nil
+}
+
+// Pack the bases of e from fasta into b, checking they match the index
+func packFai(fasta io.ReaderAt, e *FaiRecord, b *seqBuilder) (error) {
+    r := bufio.NewReaderSize(io.NewSectionReader(fasta, e.Offset, e.span()), 64*1024)
+    for b.size < e.Length {
+        data, err := r.ReadSlice('\n')
+        seq := bytes.TrimRight(data, "\r\n")
+        if len(seq) > 0 && seq[0] == '>' {
+            return fmt.Errorf("Sequence %s is shorter than its fai length %d", e.Name, e.Length)
+        }
+        b.append(string(seq))
+
+        if err == io.EOF {
+            break
+        }
+        if err != nil && err != bufio.ErrBufferFull {
+            return fmt.Errorf("Failed to read %s: %s", e.Name, err)
+        }
+    }
+
+    if b.size != e.Length {
+        return fmt.Errorf("Sequence %s has %d bases but its fai length is %d", e.Name, b.size, e.Length)
+    }
+
+    return nil
+}
+
+// ImportIndexedFasta converts the FASTA file fasta, indexed by fai, to a 2bit
+// file written to out by w. As the lengths are known from the index the
+// sequences are reserved and streamed with WriteIndex, so the output is
+// written in a single pass without holding the genome in memory, and the
+// offsets let up to workers sequences be read and packed in parallel. The
+// sequences are added to w in the order given by its Layout, so the OnPacked
+// and OnWritten hooks are called in file order. Sequences with more N and
+// mask blocks than Reserve allows for their length fail. out may be nil for
+// Writers returned by Create, which must still be closed.
+func ImportIndexedFasta(fasta io.ReaderAt, fai []*FaiRecord, w *Writer, out io.Writer, workers int) (error) {
+    if workers < 1 {
+        workers = 1
+    }
+
+    records := make(map[string]*FaiRecord)
+    for _, e := range fai {
+        err := w.Reserve(e.Name, e.Length)
+        if err != nil {
+            return err
+        }
+        records[e.Name] = e
+    }
+
+    err := w.WriteIndex(out)
+    if err != nil {
+        return err
+    }
+
+    // one buffered channel per sequence keeps the results in file order and
+    // the semaphore bounds the sequences packed but not yet written
+    slots := w.reserved.slots
+    results := make([]chan *seqBuilder, len(slots))
+    for i := range results {
+        results[i] = make(chan *seqBuilder, 1)
+    }
+    errs := make([]error, len(slots))
+    sem := make(chan struct{}, workers)
+    done := make(chan struct{})
+    defer close(done)
+
+    go func() {
+        for i, s := range slots {
+            select {
+            case sem <- struct{}{}:
+            case <-done:
+                return
+            }
+
+            go func(i int, e *FaiRecord) {
+                b := w.newBuilder(e.Name)
+                errs[i] = packFai(fasta, e, b)
+                results[i] <- b
+            }(i, records[s.name])
+        }
+    }()
+
+    for i := range slots {
+        b := <-results[i]
+        <-sem
+        if errs[i] != nil {
+            return errs[i]
+        }
+        err = w.endBuilder(b)
+        if err != nil {
+            return err
+        }
+    }
+
+    return w.Finish()
+}
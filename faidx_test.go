@@ -0,0 +1,123 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "fmt"
+    "strings"
+)
+
+// Format seqs as FASTA with lines of width bases ending in eol, returning the
+// FASTA and its fai index
+func indexedFasta(names []string, seqs map[string]string, width int, eol string) ([]byte, string) {
+    var fasta bytes.Buffer
+    var fai strings.Builder
+    for _, name := range names {
+        seq := seqs[name]
+        fmt.Fprintf(&fasta, ">%s description\n", name)
+        fmt.Fprintf(&fai, "%s\t%d\t%d\t%d\t%d\n", name, len(seq), fasta.Len(), width, width+len(eol))
+        for i := 0; i < len(seq); i += width {
+            end := i+width
+            if end > len(seq) {
+                end = len(seq)
+            }
+            fasta.WriteString(seq[i:end])
+            fasta.WriteString(eol)
+        }
+    }
+
+    return fasta.Bytes(), fai.String()
+}
+
+func TestReadFai(t *testing.T) {
+    fai, err := ReadFai(strings.NewReader("chr1\t100\t6\t60\t61\nchr2\t0\t114\t0\t0\n\nread1\t8\t7\t8\t9\t17\n"))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if len(fai) != 3 || *fai[0] != (FaiRecord{"chr1", 100, 6, 60, 61}) || fai[2].Name != "read1" {
+        t.Errorf("Invalid fai: %+v", fai)
+    }
+    if fai[0].span() != 101 {
+        t.Errorf("Invalid span: %d", fai[0].span())
+    }
+
+    // indexes written on Windows
+    fai, err = ReadFai(strings.NewReader("chr1\t100\t6\t60\t61\r\n"))
+    if err != nil || len(fai) != 1 || fai[0].LineBytes != 61 {
+        t.Errorf("Invalid fai with CRLF line endings: %v %v", fai, err)
+    }
+
+    for _, bad := range []string{"chr1\t100\t6\t60\n", "chr1\t100\t6\t60\t59\n", "chr1\t-1\t6\t60\t61\n", "chr1\tx\t6\t60\t61\n"} {
+        _, err := ReadFai(strings.NewReader(bad))
+        if err == nil {
+            t.Errorf("Invalid fai accepted: %q", bad)
+        }
+    }
+}
+
+func TestImportIndexedFasta(t *testing.T) {
+    names := []string{"chr1", "chr2", "chrEmpty", "chrM"}
+    seqs := map[string]string{
+        "chr1": strings.Repeat("ACGT", 100)+"NNNNNNNN"+strings.Repeat("acgt", 30)+strings.Repeat("GATTACA", 50),
+        "chr2": "GGGGNNNcc",
+        "chrEmpty": "",
+        "chrM": strings.Repeat("acgt", 17),
+    }
+
+    for _, eol := range []string{"\n", "\r\n"} {
+        for _, workers := range []int{1, 3} {
+            fasta, idx := indexedFasta(names, seqs, 7, eol)
+            fai, err := ReadFai(strings.NewReader(idx))
+            if err != nil {
+                t.Fatalf("%s", err)
+            }
+
+            var out bytes.Buffer
+            w := NewWriter(WithLayout(Layout{Order: LENGTH_ORDER}))
+            err = ImportIndexedFasta(bytes.NewReader(fasta), fai, w, &out, workers)
+            if err != nil {
+                t.Fatalf("%s", err)
+            }
+
+            tb, err := NewReader(bytes.NewReader(out.Bytes()), Strict())
+            if err != nil {
+                t.Fatalf("%s", err)
+            }
+            if got := tb.Names(); len(got) != len(names) {
+                t.Fatalf("Invalid names: %v", got)
+            }
+            if w.Report().Sequences[0].Name != "chr1" {
+                t.Errorf("Layout not applied: %+v", w.Report().Sequences)
+            }
+            for _, name := range names {
+                seq, err := tb.Read(name)
+                if err != nil {
+                    t.Fatalf("Failed to read %s: %s", name, err)
+                }
+                if string(seq) != seqs[name] {
+                    t.Errorf("Invalid sequence %s: %s != %s", name, seq, seqs[name])
+                }
+            }
+        }
+    }
+}
+
+func TestImportIndexedFastaMismatch(t *testing.T) {
+    names := []string{"chr1", "chr2"}
+    seqs := map[string]string{"chr1": "ACGTACGTAC", "chr2": "GGGG"}
+    fasta, _ := indexedFasta(names, seqs, 4, "\n")
+
+    // chr1 is longer than indexed and runs into the next header
+    fai := []*FaiRecord{
+        &FaiRecord{Name: "chr1", Length: 14, Offset: 18, LineBases: 4, LineBytes: 5},
+        &FaiRecord{Name: "chr2", Length: 4, Offset: 49, LineBases: 4, LineBytes: 5},
+    }
+    err := ImportIndexedFasta(bytes.NewReader(fasta), fai, NewWriter(), &bytes.Buffer{}, 2)
+    if err == nil {
+        t.Errorf("Index not matching the FASTA accepted")
+    }
+}
@@ -0,0 +1,302 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "io"
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "strings"
+)
+
+// UTF-8 byte order mark written at the start of files by some editors
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// Remove all whitespace, including line endings, from line in place
+func stripSpace(line []byte) ([]byte) {
+    out := line[:0]
+    for _, c := range line {
+        switch c {
+        case ' ', '\t', '\r', '\n', '\v', '\f':
+        default:
+            out = append(out, c)
+        }
+    }
+
+    return out
+}
+
+// Parse FASTA from in calling start for each header with the sequence name
+// (the first word of the header) and the whole header line without the >,
+// chunk for each piece of sequence and end after the last piece of each
+// sequence. Lines of any length are handled without holding them in memory.
+// Files as downloaded are accepted: CRLF line endings, whitespace within and
+// around sequence lines, blank lines, indented headers and a leading UTF-8
+// byte order mark.
+func readFasta(in io.Reader, start func(name, header string) (error), chunk func(seq []byte) (error), end func() (error)) (error) {
+    return scanFasta(in, 0,
+        func(name, header string, at int64) (error) { return start(name, header) },
+        chunk,
+        func(at int64) (error) { return end() })
+}
+
+// Parse FASTA as readFasta, with in positioned at byte offset of the file.
+// start is passed the offset of each header line and end the offset just
+// past each sequence, where the next header or the end of the file begins.
+func scanFasta(in io.Reader, offset int64, start func(name, header string, at int64) (error), chunk func(seq []byte) (error), end func(at int64) (error)) (error) {
+    r := bufio.NewReaderSize(in, 64*1024)
+    open := false
+    bol := true // at beginning of a line
+    line := 0
+    pos := offset
+    lineStart := offset
+
+    for {
+        data, err := r.ReadSlice('\n')
+        if bol {
+            lineStart = pos
+        }
+        pos += int64(len(data))
+        if line == 0 && offset == 0 {
+            data = bytes.TrimPrefix(data, utf8BOM)
+        }
+        if len(data) > 0 {
+            if bol {
+                line++
+            }
+
+            if trimmed := bytes.TrimLeft(data, " \t"); bol && len(trimmed) > 0 && trimmed[0] == '>' {
+                // a > later in the line is part of the description
+                header := string(trimmed[1:])
+                for err == bufio.ErrBufferFull {
+                    data, err = r.ReadSlice('\n')
+                    pos += int64(len(data))
+                    header += string(data)
+                }
+                header = strings.TrimSpace(header)
+
+                fields := strings.Fields(header)
+                if len(fields) == 0 {
+                    return fmt.Errorf("Missing sequence name on line %d", line)
+                }
+                name := fields[0]
+
+                if open {
+                    cerr := end(lineStart)
+                    if cerr != nil {
+                        return cerr
+                    }
+                }
+
+                cerr := start(name, header, lineStart)
+                if cerr != nil {
+                    return cerr
+                }
+                open = true
+                bol = true
+            } else {
+                bol = data[len(data)-1] == '\n'
+                seq := stripSpace(data)
+                if len(seq) > 0 {
+                    if !open {
+                        return fmt.Errorf("Sequence data before first header on line %d", line)
+                    }
+                    cerr := chunk(seq)
+                    if cerr != nil {
+                        return cerr
+                    }
+                }
+            }
+        }
+
+        if err == io.EOF {
+            break
+        }
+        if err != nil && err != bufio.ErrBufferFull {
+            return fmt.Errorf("Failed to read FASTA: %s", err)
+        }
+    }
+
+    if open {
+        return end(pos)
+    }
+
+    return nil
+}
+
+// ImportFasta adds every sequence in the FASTA read from in to w. Sequences
+// are packed as they are read so no sequence is held in memory as text.
+// Header lines with a description are recorded, see Descriptions.
+func ImportFasta(in io.Reader, w *Writer) (error) {
+    var name, header string
+    start := func(n, h string) (error) {
+        name, header = n, h
+        return w.StartSequence(name)
+    }
+    chunk := func(seq []byte) (error) {
+        return w.AppendChunk(string(seq))
+    }
+    end := func() (error) {
+        err := w.EndSequence()
+        if err != nil {
+            return err
+        }
+        w.describe(name, header)
+        return nil
+    }
+
+    return readFasta(in, start, chunk, end)
+}
+
+// Record the FASTA header line of sequence name if it has a description
+func (w *Writer) describe(name, header string) {
+    if header == name {
+        return
+    }
+    if w.descriptions == nil {
+        w.descriptions = make(map[string]string)
+    }
+    w.descriptions[name] = header
+}
+
+// Descriptions returns the FASTA header lines, without the >, of sequences
+// imported by ImportFasta and related functions keyed by name. Sequences
+// whose header is only their name are not included. 2bit files can't store
+// descriptions, Writers with WithProvenance record them in the provenance
+// sidecar.
+func (w *Writer) Descriptions() (map[string]string) {
+    descriptions := make(map[string]string, len(w.descriptions))
+    for name, header := range w.descriptions {
+        descriptions[name] = header
+    }
+
+    return descriptions
+}
+
+// SplitOptions control how ImportFastaSplit divides sequences across files
+type SplitOptions struct {
+    MaxSequences  int    // maximum sequences per file, 0 for no limit
+    MaxBases      int64  // maximum total bases per file, 0 for no limit
+    Pattern       string // output path with a %d verb for the file number, counting from 1
+}
+
+// SplitFile is one file written by ImportFastaSplit
+type SplitFile struct {
+    Path       string   `json:"path"`
+    Sequences  []string `json:"sequences"`
+    Bases      int64    `json:"bases"`
+}
+
+// SplitManifest lists the files written by ImportFastaSplit
+type SplitManifest struct {
+    Files  []*SplitFile `json:"files"`
+}
+
+// Write the manifest in JSON format to out
+func (m *SplitManifest) Write(out io.Writer) (error) {
+    enc := json.NewEncoder(out)
+    enc.SetIndent("", "  ")
+    return enc.Encode(m)
+}
+
+// ImportFastaSplit imports the FASTA read from in into multiple 2bit files
+// named by opts.Pattern, starting a new file when adding a sequence would
+// exceed MaxSequences or MaxBases. A single sequence larger than MaxBases is
+// written to a file of its own. MaxSequences of 1 gives one file per
+// sequence. Files are created with Create using wopts.
+func ImportFastaSplit(in io.Reader, opts SplitOptions, wopts ...WriterOption) (*SplitManifest, error) {
+    if opts.MaxSequences < 0 || opts.MaxBases < 0 {
+        return nil, fmt.Errorf("Invalid split limits: %+v", opts)
+    }
+    if len(opts.Pattern) == 0 {
+        return nil, fmt.Errorf("Missing output path pattern")
+    }
+
+    manifest := &SplitManifest{Files: make([]*SplitFile, 0)}
+    var w *Writer
+    var cur *SplitFile
+
+    rotate := func() (error) {
+        if w != nil {
+            err := w.Close()
+            if err != nil {
+                return err
+            }
+        }
+
+        cur = &SplitFile{Path: fmt.Sprintf(opts.Pattern, len(manifest.Files)+1)}
+        var err error
+        w, err = Create(cur.Path, wopts...)
+        if err != nil {
+            return err
+        }
+        manifest.Files = append(manifest.Files, cur)
+
+        return nil
+    }
+
+    var name, header string
+    start := func(n, h string) (error) {
+        name, header = n, h
+        if w == nil || (opts.MaxSequences > 0 && len(cur.Sequences) >= opts.MaxSequences) {
+            err := rotate()
+            if err != nil {
+                return err
+            }
+        }
+        return w.StartSequence(name)
+    }
+
+    chunk := func(seq []byte) (error) {
+        return w.AppendChunk(string(seq))
+    }
+
+    end := func() (error) {
+        err := w.EndSequence()
+        if err != nil {
+            return err
+        }
+
+        rec := w.records[name]
+        size := int64(rec.dnaSize)
+
+        // the sequence does not fit in the current file, move it to a new one
+        if opts.MaxBases > 0 && len(cur.Sequences) > 0 && cur.Bases+size > opts.MaxBases {
+            // the spill file of w is removed when it is closed
+            w.removeLast(name)
+            err = rec.unspill()
+            if err != nil {
+                return err
+            }
+
+            err = rotate()
+            if err != nil {
+                return err
+            }
+            w.setRecord(name, rec)
+        }
+        w.describe(name, header)
+
+        cur.Sequences = append(cur.Sequences, name)
+        cur.Bases += size
+
+        return nil
+    }
+
+    err := readFasta(in, start, chunk, end)
+    if w != nil {
+        cerr := w.Close()
+        if err == nil {
+            err = cerr
+        }
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    return manifest, nil
+}
@@ -0,0 +1,149 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "fmt"
+    "path/filepath"
+    "strings"
+)
+
+func TestImportFasta(t *testing.T) {
+    long := strings.Repeat("ACGTacgtNN", 20000)
+    fasta := ">chr1 first chromosome\r\nACGTN\r\nacgt\r\n\n>chr2\n" + long + "\n>empty\n>chr3\nGG"
+
+    w := NewWriter()
+    err := ImportFasta(strings.NewReader(fasta), w)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    var out bytes.Buffer
+    w.WriteTo(&out)
+    tb, err := NewReader(bytes.NewReader(out.Bytes()))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    if order := strings.Join(tb.namesByOffset(), ","); order != "chr1,chr2,empty,chr3" {
+        t.Errorf("Invalid order: %s", order)
+    }
+
+    for name, good := range map[string]string{"chr1": "ACGTNacgt", "chr2": long, "empty": "", "chr3": "GG"} {
+        seq, err := tb.Read(name)
+        if err != nil || string(seq) != good {
+            t.Errorf("Invalid sequence %s: %v", name, err)
+        }
+    }
+
+    err = ImportFasta(strings.NewReader("ACGT\n>chr1\nACGT\n"), NewWriter())
+    if err == nil {
+        t.Errorf("Expected error for sequence before header")
+    }
+
+    err = ImportFasta(strings.NewReader(">\nACGT\n"), NewWriter())
+    if err == nil {
+        t.Errorf("Expected error for missing name")
+    }
+}
+
+func TestImportFastaTolerant(t *testing.T) {
+    long := strings.Repeat("x", 100000)
+    fasta := "\xef\xbb\xbf>chr1 Homo sapiens chromosome 1, GRCh38 > primary  \r\n" +
+        "ACGT  \r\n" +
+        "\tac gt\t\r\n" +
+        "\r\n" +
+        "   \n" +
+        "  >chr2\tdesc "+long+"\n" +
+        "GG NN\n\n" +
+        ">chr3\n" +
+        "T\n"
+
+    for _, parallel := range []bool{false, true} {
+        w := NewWriter()
+        var err error
+        if parallel {
+            err = ImportFastaParallel(strings.NewReader(fasta), w, 2)
+        } else {
+            err = ImportFasta(strings.NewReader(fasta), w)
+        }
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+
+        var out bytes.Buffer
+        w.WriteTo(&out)
+        tb, err := NewReader(bytes.NewReader(out.Bytes()))
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        for name, good := range map[string]string{"chr1": "ACGTacgt", "chr2": "GGNN", "chr3": "T"} {
+            seq, err := tb.Read(name)
+            if err != nil || string(seq) != good {
+                t.Errorf("Invalid sequence %s: %s %v", name, seq, err)
+            }
+        }
+
+        descriptions := w.Descriptions()
+        if len(descriptions) != 2 || descriptions["chr1"] != "chr1 Homo sapiens chromosome 1, GRCh38 > primary" || descriptions["chr2"] != "chr2\tdesc "+long {
+            t.Errorf("Invalid descriptions: %.200v", descriptions)
+        }
+    }
+}
+
+func TestImportFastaSplit(t *testing.T) {
+    fasta := ">chr1\nAAAAAAAAAA\n>chr2\nCCCCC\n>chr3\nGGGGG\n>chr4\nTTTTTTTTTTTTTTTTTTTT\n>chr5\nA\n"
+
+    tests := []struct {
+        opts   SplitOptions
+        files  []string
+    }{
+        {SplitOptions{MaxSequences: 1}, []string{"chr1", "chr2", "chr3", "chr4", "chr5"}},
+        {SplitOptions{MaxSequences: 2}, []string{"chr1,chr2", "chr3,chr4", "chr5"}},
+        {SplitOptions{MaxBases: 15}, []string{"chr1,chr2", "chr3", "chr4", "chr5"}},
+        {SplitOptions{MaxBases: 20, MaxSequences: 2}, []string{"chr1,chr2", "chr3", "chr4", "chr5"}},
+        {SplitOptions{}, []string{"chr1,chr2,chr3,chr4,chr5"}},
+    }
+
+    for i, test := range tests {
+        test.opts.Pattern = filepath.Join(t.TempDir(), fmt.Sprintf("split%d.%%03d.2bit", i))
+        manifest, err := ImportFastaSplit(strings.NewReader(fasta), test.opts)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+
+        if len(manifest.Files) != len(test.files) {
+            t.Fatalf("Invalid number of files for %+v: %d != %d", test.opts, len(manifest.Files), len(test.files))
+        }
+
+        for j, f := range manifest.Files {
+            if strings.Join(f.Sequences, ",") != test.files[j] {
+                t.Errorf("Invalid sequences in %s: %v != %s", f.Path, f.Sequences, test.files[j])
+            }
+
+            tb, err := Open(f.Path)
+            if err != nil {
+                t.Fatalf("%s", err)
+            }
+            if strings.Join(tb.namesByOffset(), ",") != test.files[j] {
+                t.Errorf("Invalid file contents %s: %v", f.Path, tb.namesByOffset())
+            }
+            total, _ := tb.TotalLength()
+            if int64(total) != f.Bases {
+                t.Errorf("Invalid bases in %s: %d != %d", f.Path, total, f.Bases)
+            }
+            tb.Close()
+        }
+    }
+
+    var m bytes.Buffer
+    manifest, _ := ImportFastaSplit(strings.NewReader(fasta), SplitOptions{MaxSequences: 3, Pattern: filepath.Join(t.TempDir(), "x%d.2bit")})
+    err := manifest.Write(&m)
+    if err != nil || !strings.Contains(m.String(), `"sequences"`) {
+        t.Errorf("Invalid manifest: %s %v", m.String(), err)
+    }
+}
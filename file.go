@@ -0,0 +1,187 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "os"
+    "io/ioutil"
+    "path/filepath"
+    "sort"
+)
+
+// Open opens the named 2bit file for reading. The returned Reader owns the
+// file handle and must be closed with Close.
+func Open(path string, opts ...ReadOption) (*Reader, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+
+    tb, err := NewReader(f, opts...)
+    if err != nil {
+        f.Close()
+        return nil, err
+    }
+
+    tb.file = f
+    tb.path = path
+
+    return tb, nil
+}
+
+// Close closes the file opened by Open. It is a no-op for Readers created
+// with NewReader.
+func (r *Reader) Close() (error) {
+    if r.file == nil {
+        return nil
+    }
+
+    err := r.file.Close()
+    r.file = nil
+
+    return err
+}
+
+// Atomic makes Create write to a temporary file in the destination directory
+// which is renamed to the destination path only once Close succeeds. An
+// interrupted write never leaves a partial 2bit file at the destination.
+func Atomic() (WriterOption) {
+    return func(w *Writer) {
+        w.atomic = true
+    }
+}
+
+// Create creates the named 2bit file. Sequences added to the returned Writer
+// are written to the file when Close is called.
+func Create(path string, opts ...WriterOption) (*Writer, error) {
+    tb := NewWriter(opts...)
+    tb.path = path
+
+    var f *os.File
+    var err error
+    if tb.atomic {
+        f, err = ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+        if err == nil {
+            err = f.Chmod(0644)
+            if err != nil {
+                f.Close()
+                os.Remove(f.Name())
+            }
+        }
+    } else {
+        f, err = os.Create(path)
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    tb.file = f
+
+    return tb, nil
+}
+
+// Close finalizes the Writer. For Writers returned by Create the header, index
+// and all sequence records are written and the file is closed. If writing
+// fails the partial file is removed so it can't be mistaken for a complete
+// one. Once closed, Add and Close return ErrClosed.
+func (w *Writer) Close() (error) {
+    if w.closed {
+        return ErrClosed
+    }
+    w.closed = true
+
+    if w.spill != nil {
+        defer w.spill.close()
+    }
+
+    reserved := w.reserved != nil && w.reserved.out != nil
+    if w.file == nil {
+        if reserved {
+            return w.Finish()
+        }
+        return nil
+    }
+
+    f := w.file
+    w.file = nil
+
+    var err error
+    if reserved {
+        err = w.Finish()
+    } else {
+        err = w.WriteTo(f)
+    }
+    if err == nil {
+        err = f.Close()
+    } else {
+        f.Close()
+    }
+
+    if err == nil && w.atomic {
+        err = os.Rename(f.Name(), w.path)
+    }
+
+    if err != nil {
+        os.Remove(f.Name())
+        return err
+    }
+
+    if w.provenance != nil {
+        return w.writeProvenance(w.path)
+    }
+
+    return nil
+}
+
+// WriteFile writes seqs to the named 2bit file in one call, with sequences in
+// name order. It is meant for small files such as test fixtures, toy genomes
+// and spike-ins: every sequence is held in memory, so use Create and Add (or
+// StartSequence) for whole genomes.
+func WriteFile(path string, seqs map[string]string, opts ...WriterOption) (error) {
+    names := make([]string, 0, len(seqs))
+    for name := range seqs {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    w, err := Create(path, opts...)
+    if err != nil {
+        return err
+    }
+
+    for _, name := range names {
+        err = w.Add(name, seqs[name])
+        if err != nil {
+            // don't leave a partial file behind
+            w.file.Close()
+            os.Remove(w.file.Name())
+            return err
+        }
+    }
+
+    return w.Close()
+}
+
+// ReadAll reads every sequence in the named 2bit file into a map keyed by
+// name. Like WriteFile it is meant for small files only, as a whole genome
+// would be decoded into memory at once.
+func ReadAll(path string, opts ...ReadOption) (map[string]string, error) {
+    tb, err := Open(path, opts...)
+    if err != nil {
+        return nil, err
+    }
+    defer tb.Close()
+
+    seqs := make(map[string]string, tb.Count())
+    for _, name := range tb.Names() {
+        seq, err := tb.Read(name)
+        if err != nil {
+            return nil, err
+        }
+        seqs[name] = string(seq)
+    }
+
+    return seqs, nil
+}
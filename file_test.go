@@ -0,0 +1,128 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "errors"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "reflect"
+    "regexp"
+)
+
+func TestCreateOpen(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "test.2bit")
+
+    w, err := Create(path)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    err = w.Add("ex1", "ACTgcctttnnnNantnaCgc")
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    err = w.Close()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    if err := w.Close(); !errors.Is(err, ErrClosed) {
+        t.Errorf("Expected ErrClosed on second Close, got: %v", err)
+    }
+
+    if err := w.Add("ex2", "ACGT"); !errors.Is(err, ErrClosed) {
+        t.Errorf("Expected ErrClosed on Add after Close, got: %v", err)
+    }
+
+    r, err := Open(path)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    defer r.Close()
+
+    seq, err := r.Read("ex1")
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    if string(seq) != "ACTgcctttnnnNantnaCgc" {
+        t.Errorf("Invalid sequence: %s", seq)
+    }
+}
+
+func TestCreateAtomic(t *testing.T) {
+    dir := t.TempDir()
+    path := filepath.Join(dir, "test.2bit")
+
+    w, err := Create(path, Atomic())
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    err = w.Add("ex1", "ACGT")
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    if _, err := os.Stat(path); !os.IsNotExist(err) {
+        t.Errorf("Destination file exists before Close")
+    }
+
+    err = w.Close()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    files, err := ioutil.ReadDir(dir)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    if len(files) != 1 || files[0].Name() != "test.2bit" {
+        t.Errorf("Expected only the destination file after Close, got %d files", len(files))
+    }
+}
+
+func TestWriteFileReadAll(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "spikes.2bit")
+    seqs := map[string]string{"ERCC-00002": "ACGTNNacgt", "ERCC-00003": "GGGGCCCC", "empty": ""}
+
+    err := WriteFile(path, seqs)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    got, err := ReadAll(path)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if !reflect.DeepEqual(got, seqs) {
+        t.Errorf("Invalid sequences: %v != %v", got, seqs)
+    }
+
+    tb, _ := Open(path)
+    defer tb.Close()
+    if names := tb.namesByOffset(); names[0] != "ERCC-00002" || names[2] != "empty" {
+        t.Errorf("Sequences not written in name order: %v", names)
+    }
+
+    bad := filepath.Join(t.TempDir(), "bad.2bit")
+    err = WriteFile(bad, map[string]string{"chr1": "ACGT"}, ValidateNames(NamePattern(regexp.MustCompile(`^scaffold`))))
+    if err == nil {
+        t.Errorf("Invalid name accepted")
+    }
+    if _, err := os.Stat(bad); !os.IsNotExist(err) {
+        t.Errorf("Partial file left after failure")
+    }
+
+    _, err = ReadAll(bad)
+    if err == nil {
+        t.Errorf("Missing file read")
+    }
+}
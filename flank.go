@@ -0,0 +1,79 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "fmt"
+)
+
+// FlankRegion is the result of a flank extraction
+type FlankRegion struct {
+    Start    int    // start of the region after clamping (0-based)
+    End      int    // end of the region after clamping (exclusive)
+    Seq      []byte // sequence oriented on the requested strand
+    Gap      int    // number of bases in the region falling within N blocks
+}
+
+// Read the region flanking pos (0-based), for example a TSS. On the '+' strand
+// the region is pos-upstream to pos+downstream. On the '-' strand upstream is
+// toward higher coordinates, the region is pos-downstream+1 to pos+upstream+1
+// and the sequence is reverse complemented. The region is clamped to the ends
+// of the sequence and the number of bases falling in N blocks is reported so
+// callers can discard gapped flanks.
+func (r *Reader) Flank(name string, pos, upstream, downstream int, strand byte) (*FlankRegion, error) {
+    rec, err := r.parseRecord(name, true)
+    if err != nil {
+        return nil, err
+    }
+
+    if pos < 0 || pos >= int(rec.dnaSize) {
+        return nil, fmt.Errorf("Invalid position: %d", pos)
+    }
+
+    if upstream < 0 || downstream < 0 {
+        return nil, fmt.Errorf("Invalid flank sizes: %d %d", upstream, downstream)
+    }
+
+    var start, end int
+    switch strand {
+    case '+':
+        start, end = pos-upstream, pos+downstream
+    case '-':
+        start, end = pos-downstream+1, pos+upstream+1
+    default:
+        return nil, fmt.Errorf("Invalid strand: %q", strand)
+    }
+
+    if start < 0 {
+        start = 0
+    }
+    if end > int(rec.dnaSize) {
+        end = int(rec.dnaSize)
+    }
+
+    region := &FlankRegion{Start: start, End: end, Seq: make([]byte, 0)}
+    if start >= end {
+        return region, nil
+    }
+
+    region.Seq = make([]byte, end-start)
+    err = r.readInto(region.Seq, rec, start, end)
+    if err != nil {
+        return nil, err
+    }
+
+    if strand == '-' {
+        ReverseComplementInPlace(region.Seq)
+    }
+
+    for _, b := range rec.nBlocks {
+        lo, hi := b.clip(start, end)
+        if lo < hi {
+            region.Gap += hi-lo
+        }
+    }
+
+    return region, nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+)
+
+func TestFlank(t *testing.T) {
+    tb, err := openTestTwoBit()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    tests := []struct {
+        pos, up, down int
+        strand byte
+        start, end, gap int
+        seq string
+    }{
+        {5, 2, 3, '+', 3, 8, 0, "gcctt"},
+        {1, 5, 2, '+', 0, 3, 0, "ACT"},
+        {10, 2, 1, '-', 10, 13, 3, "Nnn"},
+        {2, 1, 1, '-', 2, 4, 0, "cA"},
+        {19, 5, 0, '-', 20, 21, 0, "g"},
+    }
+
+    for _, tt := range tests {
+        f, err := tb.Flank("ex1", tt.pos, tt.up, tt.down, tt.strand)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+
+        if f.Start != tt.start || f.End != tt.end || f.Gap != tt.gap || string(f.Seq) != tt.seq {
+            t.Errorf("Invalid flank for %d %c: %d-%d gap %d %s", tt.pos, tt.strand, f.Start, f.End, f.Gap, f.Seq)
+        }
+    }
+
+    _, err = tb.Flank("ex1", 0, 1, 1, '.')
+    if err == nil {
+        t.Errorf("Expected error for invalid strand")
+    }
+}
@@ -0,0 +1,115 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+    "os"
+    "fmt"
+    "github.com/aebruno/twobit"
+)
+
+// FastaSource reads sequences from a FASTA file using its .fai index
+type FastaSource struct {
+    file     *os.File
+    entries  map[string]*twobit.FaiRecord
+    names    []string
+}
+
+// OpenFasta opens the FASTA file at path using the index at path+".fai"
+func OpenFasta(path string) (*FastaSource, error) {
+    idx, err := os.Open(path+".fai")
+    if err != nil {
+        return nil, fmt.Errorf("Missing FASTA index (samtools faidx): %s", err)
+    }
+    defer idx.Close()
+
+    records, err := twobit.ReadFai(idx)
+    if err != nil {
+        return nil, err
+    }
+    entries := make(map[string]*twobit.FaiRecord, len(records))
+    names := make([]string, 0, len(records))
+    for _, e := range records {
+        if _, ok := entries[e.Name]; !ok {
+            names = append(names, e.Name)
+        }
+        entries[e.Name] = e
+    }
+
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+
+    return &FastaSource{file: f, entries: entries, names: names}, nil
+}
+
+func (f *FastaSource) Names() ([]string) {
+    names := make([]string, len(f.names))
+    copy(names, f.names)
+    return names
+}
+
+func (f *FastaSource) Length(name string) (int, error) {
+    e, ok := f.entries[name]
+    if !ok {
+        return 0, fmt.Errorf("Invalid sequence name: %s", name)
+    }
+
+    return e.Length, nil
+}
+
+// File offset of base pos of e
+func faiPos(e *twobit.FaiRecord, pos int) (int64) {
+    return e.Offset + int64(pos/e.LineBases)*int64(e.LineBytes) + int64(pos%e.LineBases)
+}
+
+func (f *FastaSource) ReadRange(name string, start, end int) ([]byte, error) {
+    e, ok := f.entries[name]
+    if !ok {
+        return nil, fmt.Errorf("Invalid sequence name: %s", name)
+    }
+
+    // the same half-open range checks as twobit.Reader.ReadRange
+    if e.Length == 0 {
+        return []byte{}, nil
+    }
+    if end == 0 {
+        end = e.Length
+    }
+    if start < 0 || end < 0 {
+        return nil, fmt.Errorf("Invalid range: %d-%d: negative coordinate", start, end)
+    }
+    if end > e.Length {
+        return nil, fmt.Errorf("Invalid range: %d-%d: end past sequence length %d", start, end, e.Length)
+    }
+    if end <= start {
+        return nil, fmt.Errorf("Invalid range: %d-%d", start, end)
+    }
+
+    first := faiPos(e, start)
+    buf := make([]byte, faiPos(e, end-1)+1-first)
+    _, err := f.file.ReadAt(buf, first)
+    if err != nil {
+        return nil, fmt.Errorf("Failed to read %s: %s", name, err)
+    }
+
+    seq := buf[:0]
+    for _, c := range buf {
+        if c != '\n' && c != '\r' {
+            seq = append(seq, c)
+        }
+    }
+
+    if len(seq) != end-start {
+        return nil, fmt.Errorf("FASTA index does not match %s", name)
+    }
+
+    return seq, nil
+}
+
+func (f *FastaSource) Close() (error) {
+    return f.file.Close()
+}
@@ -0,0 +1,81 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+// Package genome reads sequences from either a 2bit file or an indexed FASTA
+// file (with a samtools .fai index) through one interface, so applications
+// can accept both formats with identical downstream calls. The format is
+// detected from the file contents.
+package genome
+
+import (
+    "os"
+    "sort"
+    "github.com/aebruno/twobit"
+)
+
+// SequenceSource is random access to the sequences of a genome
+type SequenceSource interface {
+    // Names of the sequences in file order
+    Names() []string
+    // Length of sequence name
+    Length(name string) (int, error)
+    // Sequence from start to end (0-based, end exclusive). An end of 0 reads
+    // to the end of the sequence.
+    ReadRange(name string, start, end int) ([]byte, error)
+    // Close the underlying file
+    Close() error
+}
+
+// Open the 2bit or indexed FASTA file at path. FASTA files must have an
+// index at path+".fai".
+func Open(path string) (SequenceSource, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+
+    ok, _, _, err := twobit.Sniff(f)
+    f.Close()
+    if err != nil {
+        return nil, err
+    }
+
+    if ok {
+        return openTwoBit(path)
+    }
+
+    return OpenFasta(path)
+}
+
+// twoBitSource adapts a twobit.Reader
+type twoBitSource struct {
+    *twobit.Reader
+    names  []string
+}
+
+func openTwoBit(path string) (*twoBitSource, error) {
+    r, err := twobit.Open(path)
+    if err != nil {
+        return nil, err
+    }
+
+    offsets, err := r.Offsets()
+    if err != nil {
+        r.Close()
+        return nil, err
+    }
+
+    names := r.Names()
+    sort.Slice(names, func(i, j int) bool {
+        return offsets[names[i]].Offset < offsets[names[j]].Offset
+    })
+
+    return &twoBitSource{Reader: r, names: names}, nil
+}
+
+func (t *twoBitSource) Names() ([]string) {
+    names := make([]string, len(t.names))
+    copy(names, t.names)
+    return names
+}
@@ -0,0 +1,112 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package genome
+
+import (
+    "os"
+    "testing"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "github.com/aebruno/twobit"
+)
+
+func TestOpen(t *testing.T) {
+    dir := t.TempDir()
+    seqs := []struct {
+        name  string
+        seq   string
+    }{
+        {"chr2", "ACGTACGTNNnnacgtTTGA"},
+        {"chr1", "GGGCCCAAATTT"},
+        {"chrM", ""},
+    }
+
+    // FASTA with 8 bases per line and a .fai index
+    var fa, fai strings.Builder
+    for _, s := range seqs {
+        fa.WriteString(">" + s.name + " description\n")
+        offset := fa.Len()
+        for i := 0; i < len(s.seq); i += 8 {
+            j := i+8
+            if j > len(s.seq) {
+                j = len(s.seq)
+            }
+            fa.WriteString(s.seq[i:j] + "\n")
+        }
+        fai.WriteString(strings.Join([]string{s.name, strconv.Itoa(len(s.seq)), strconv.Itoa(offset), "8", "9"}, "\t") + "\n")
+    }
+    faPath := filepath.Join(dir, "genome.fa")
+    os.WriteFile(faPath, []byte(fa.String()), 0644)
+    // the index is read by twobit.ReadFai: empty records may have no line
+    // length and blank lines are skipped
+    fai.WriteString("chrEmpty\t0\t" + strconv.Itoa(fa.Len()) + "\t0\t0\n\n")
+    os.WriteFile(faPath+".fai", []byte(fai.String()), 0644)
+
+    tbPath := filepath.Join(dir, "genome.2bit")
+    w, _ := twobit.Create(tbPath)
+    for _, s := range seqs {
+        w.Add(s.name, s.seq)
+    }
+    w.Close()
+
+    for _, path := range []string{faPath, tbPath} {
+        src, err := Open(path)
+        if err != nil {
+            t.Fatalf("%s: %s", path, err)
+        }
+
+        want := "chr2,chr1,chrM"
+        if path == faPath {
+            want += ",chrEmpty"
+        }
+        if names := strings.Join(src.Names(), ","); names != want {
+            t.Errorf("%s: invalid names: %s", path, names)
+        }
+
+        for _, s := range seqs {
+            n, err := src.Length(s.name)
+            if err != nil || n != len(s.seq) {
+                t.Errorf("%s: invalid length %s: %d %v", path, s.name, n, err)
+            }
+        }
+
+        for _, rg := range [][2]int{{0, 0}, {3, 17}, {7, 9}, {8, 16}, {19, 20}} {
+            seq, err := src.ReadRange("chr2", rg[0], rg[1])
+            end := rg[1]
+            if end == 0 {
+                end = 20
+            }
+            if err != nil || string(seq) != seqs[0].seq[rg[0]:end] {
+                t.Errorf("%s: invalid range %v: %s %v", path, rg, seq, err)
+            }
+        }
+
+        for _, rg := range [][2]int{{-1, 5}, {0, -1}, {5, 21}, {25, 30}, {5, 5}, {9, 3}} {
+            _, err := src.ReadRange("chr2", rg[0], rg[1])
+            if err == nil {
+                t.Errorf("%s: expected error for range %v", path, rg)
+            }
+        }
+
+        seq, err := src.ReadRange("chrM", 0, 0)
+        if err != nil || len(seq) != 0 {
+            t.Errorf("%s: invalid empty sequence: %s %v", path, seq, err)
+        }
+
+        _, err = src.ReadRange("chrX", 0, 0)
+        if err == nil {
+            t.Errorf("%s: expected error for missing sequence", path)
+        }
+
+        src.Close()
+    }
+
+    os.Remove(faPath+".fai")
+    _, err := Open(faPath)
+    if err == nil {
+        t.Errorf("Expected error for FASTA without index")
+    }
+}
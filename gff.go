@@ -0,0 +1,154 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "io"
+    "bufio"
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// transcript collects the exons of a single transcript
+type transcript struct {
+    id       string
+    chrom    string
+    strand   byte
+    exons    []Range
+}
+
+// Return the transcript ids an exon belongs to from the attributes column of
+// a GFF3 (Parent=) or GTF (transcript_id "") line
+func exonParents(attrs string) ([]string) {
+    for _, attr := range strings.Split(attrs, ";") {
+        attr = strings.TrimSpace(attr)
+        if strings.HasPrefix(attr, "Parent=") {
+            return strings.Split(strings.TrimPrefix(attr, "Parent="), ",")
+        }
+        if strings.HasPrefix(attr, "transcript_id ") {
+            return []string{strings.Trim(strings.TrimPrefix(attr, "transcript_id "), "\"")}
+        }
+    }
+
+    return nil
+}
+
+// Read exon features from a GFF3 or GTF file and write the spliced sequence of
+// each transcript in FASTA format to out. Exons are joined in genomic order and
+// transcripts on the minus strand are reverse complemented. Transcripts are
+// written in the order they first appear in the annotation.
+func (r *Reader) ExtractTranscripts(in io.Reader, out io.Writer) (error) {
+    transcripts := make(map[string]*transcript)
+    order := make([]string, 0)
+
+    scanner := bufio.NewScanner(in)
+    scanner.Buffer(make([]byte, defaultBufSize), 64*1024*1024)
+
+    line := 0
+    for scanner.Scan() {
+        line++
+        text := scanner.Text()
+        if len(text) == 0 || text[0] == '#' {
+            continue
+        }
+
+        cols := strings.Split(text, "\t")
+        if len(cols) < 9 {
+            return fmt.Errorf("Invalid annotation record on line %d", line)
+        }
+
+        if cols[2] != "exon" {
+            continue
+        }
+
+        start, err := strconv.Atoi(cols[3])
+        if err != nil || start < 1 {
+            return fmt.Errorf("Invalid start on line %d: %s", line, cols[3])
+        }
+        end, err := strconv.Atoi(cols[4])
+        if err != nil || end < start {
+            return fmt.Errorf("Invalid end on line %d: %s", line, cols[4])
+        }
+        if len(cols[6]) != 1 {
+            return fmt.Errorf("Invalid strand on line %d: %q", line, cols[6])
+        }
+
+        parents := exonParents(cols[8])
+        if len(parents) == 0 {
+            return fmt.Errorf("Exon without transcript on line %d", line)
+        }
+
+        for _, id := range parents {
+            t, ok := transcripts[id]
+            if !ok {
+                t = &transcript{id: id, chrom: cols[0], strand: cols[6][0]}
+                transcripts[id] = t
+                order = append(order, id)
+            }
+            if t.chrom != cols[0] {
+                return fmt.Errorf("Transcript %s spans multiple sequences on line %d", id, line)
+            }
+            t.exons = append(t.exons, Range{Name: cols[0], Start: start-1, End: end})
+        }
+    }
+
+    if err := scanner.Err(); err != nil {
+        return fmt.Errorf("Failed to read annotation: %s", err)
+    }
+
+    w := bufio.NewWriter(out)
+    for _, id := range order {
+        t := transcripts[id]
+        sort.Slice(t.exons, func(i, j int) bool {
+            return t.exons[i].Start < t.exons[j].Start
+        })
+
+        exons, err := r.ReadRanges(t.exons)
+        if err != nil {
+            return fmt.Errorf("Failed to read transcript %s: %s", id, err)
+        }
+
+        seq := make([]byte, 0)
+        for _, e := range exons {
+            seq = append(seq, e...)
+        }
+
+        if t.strand == '-' {
+            ReverseComplementInPlace(seq)
+        }
+
+        err = writeFasta(w, id, seq)
+        if err != nil {
+            return err
+        }
+    }
+
+    return w.Flush()
+}
+
+// Write seq in FASTA format with 50 bases per line
+func writeFasta(w *bufio.Writer, name string, seq []byte) (error) {
+    w.WriteString(">")
+    w.WriteString(name)
+    w.WriteString("\n")
+
+    cols := 50
+    for i := 0; i < len(seq); i += cols {
+        end := i+cols
+        if end > len(seq) {
+            end = len(seq)
+        }
+
+        w.Write(seq[i:end])
+        _, err := w.Write([]byte("\n"))
+        if err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
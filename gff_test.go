@@ -0,0 +1,40 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "strings"
+)
+
+func TestExtractTranscripts(t *testing.T) {
+    tb, err := openTestTwoBit()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    gff := "##gff-version 3\n" +
+        "ex1\tt\tgene\t1\t21\t.\t+\t.\tID=g1\n" +
+        "ex1\tt\texon\t6\t8\t.\t+\t.\tID=e2;Parent=tx1\n" +
+        "ex1\tt\texon\t1\t3\t.\t+\t.\tID=e1;Parent=tx1,tx2\n" +
+        "ex1\tt\texon\t19\t21\t.\t-\t.\ttranscript_id \"tx3\"; gene_id \"g2\";\n"
+
+    var out bytes.Buffer
+    err = tb.ExtractTranscripts(strings.NewReader(gff), &out)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    good := ">tx1\nACTctt\n>tx2\nACT\n>tx3\ngcG\n"
+    if out.String() != good {
+        t.Errorf("Invalid transcripts: %q != %q", out.String(), good)
+    }
+
+    err = tb.ExtractTranscripts(strings.NewReader("ex1\tt\texon\t1\t3\t.\t\t.\tParent=tx1\n"), &out)
+    if err == nil {
+        t.Errorf("Expected error for empty strand")
+    }
+}
@@ -0,0 +1,63 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "fmt"
+    "io"
+)
+
+// HardMask copies src to dst with the regions (keyed by sequence name, e.g.
+// from ReadBED) replaced by upper case N, as when building an analysis set
+// with the chrY pseudoautosomal regions hard-masked. Regions are clipped to
+// the sequence and any soft-masking within them is removed. Sequences are
+// copied without decoding and keep their order.
+func HardMask(src *Reader, dst io.Writer, regions map[string]Blocks, opts ...WriterOption) (error) {
+    for name := range regions {
+        if _, ok, err := src.lookup(name); err != nil || !ok {
+            return fmt.Errorf("Invalid sequence name: %s", name)
+        }
+    }
+
+    w := NewWriter(opts...)
+    err := w.copyFrom(src)
+    if err != nil {
+        return err
+    }
+
+    for name, blocks := range regions {
+        rec := w.records[name]
+        w.memory -= rec.memory()
+        err := rec.unspill()
+        if err != nil {
+            return err
+        }
+        whole := Blocks{&Block{Start: 0, Length: int(rec.dnaSize)}}
+        mask := blocks.Intersect(whole)
+
+        rec.nBlocks = rec.nBlocks.Union(mask)
+        rec.mBlocks = rec.mBlocks.Subtract(mask)
+        for _, b := range mask {
+            clearPacked(rec.sequence, b.Start, b.End())
+        }
+        w.memory += rec.memory()
+    }
+
+    return w.WriteTo(dst)
+}
+
+// Set bases start to end of packed to 0 (T) as written for N
+func clearPacked(packed []byte, start, end int) {
+    for ; start < end && start%BASES_PER_BYTE != 0; start++ {
+        packed[start/BASES_PER_BYTE] &^= 0x3 << uint(6-2*(start%BASES_PER_BYTE))
+    }
+    for ; end > start && end%BASES_PER_BYTE != 0; end-- {
+        last := end-1
+        packed[last/BASES_PER_BYTE] &^= 0x3 << uint(6-2*(last%BASES_PER_BYTE))
+    }
+    if start < end {
+        fill(packed[start/BASES_PER_BYTE:end/BASES_PER_BYTE], 0)
+    }
+}
@@ -0,0 +1,61 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "strings"
+)
+
+func TestHardMask(t *testing.T) {
+    w := NewWriter()
+    w.Add("chrX", "ACGTACGTACGTACGT")
+    w.Add("chrY", "ACGTacgtACGTNNGTACGTA")
+    var orig bytes.Buffer
+    w.WriteTo(&orig)
+    src, _ := NewReader(bytes.NewReader(orig.Bytes()))
+
+    regions, err := ReadBED(strings.NewReader("chrY\t1\t6\nchrY\t11\t15\nchrY\t19\t40\n"))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    var out bytes.Buffer
+    err = HardMask(src, &out, regions)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    tb, err := NewReader(bytes.NewReader(out.Bytes()))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    good := "ANNNNNgtACGNNNNTACGNN"
+    seq, _ := tb.Read("chrY")
+    if string(seq) != good {
+        t.Errorf("Invalid hard-masked sequence: %s != %s", seq, good)
+    }
+    seq, _ = tb.Read("chrX")
+    if string(seq) != "ACGTACGTACGTACGT" {
+        t.Errorf("Unmasked sequence changed: %s", seq)
+    }
+
+    // identical to a file written from the hard-masked sequence
+    w = NewWriter()
+    w.Add("chrX", "ACGTACGTACGTACGT")
+    w.Add("chrY", good)
+    var direct bytes.Buffer
+    w.WriteTo(&direct)
+    if !bytes.Equal(out.Bytes(), direct.Bytes()) {
+        t.Errorf("Hard-masked file differs from file written from masked sequence")
+    }
+
+    err = HardMask(src, &bytes.Buffer{}, map[string]Blocks{"chrZ": {{Start: 0, Length: 1}}})
+    if err == nil {
+        t.Errorf("Expected error for unknown sequence")
+    }
+}
@@ -0,0 +1,105 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "crypto/md5"
+    "fmt"
+)
+
+// SequenceEvent describes a sequence passed to a Writer hook. The offsets of
+// the embedded SequenceReport are only set once the sequence is written.
+type SequenceEvent struct {
+    SequenceReport
+    Digest  string // hex encoded MD5 of the upper case sequence, as Reader.Digest
+}
+
+// SequenceHook is called by a Writer for each sequence. Returning an error
+// fails the Add or WriteTo that triggered the call.
+type SequenceHook func(e *SequenceEvent) (error)
+
+// OnPacked calls hook after each sequence is packed and added to the Writer,
+// for example to report progress while a large FASTA file is imported.
+func OnPacked(hook SequenceHook) (WriterOption) {
+    return func(w *Writer) {
+        w.onPacked = hook
+    }
+}
+
+// OnWritten calls hook after each sequence record is written by WriteTo (or
+// Close), in file order and with the offsets set, for example to populate a
+// database as the file is built.
+func OnWritten(hook SequenceHook) (WriterOption) {
+    return func(w *Writer) {
+        w.onWritten = hook
+    }
+}
+
+// Call the OnPacked hook and store a new record for sequence name, or write
+// it to its reserved slot during a two-phase write. The record is not stored
+// if the hook fails.
+func (w *Writer) addRecord(name string, rec *seqRecord) (error) {
+    if w.onPacked == nil {
+        return w.putRecord(name, rec)
+    }
+
+    e := &SequenceEvent{
+        SequenceReport: SequenceReport{Name: name, PackedSize: len(rec.sequence), DnaSize: int(rec.dnaSize)},
+        Digest: rec.digest(),
+    }
+    err := w.onPacked(e)
+    if err != nil {
+        return fmt.Errorf("Packed hook failed for %s: %w", name, err)
+    }
+
+    return w.putRecord(name, rec)
+}
+
+// Store rec or write it out if the index was written by WriteIndex
+func (w *Writer) putRecord(name string, rec *seqRecord) (error) {
+    if w.reserved != nil && w.reserved.out != nil {
+        return w.writeReserved(name, rec)
+    }
+    if w.spill != nil {
+        err := w.spill.store(rec)
+        if err != nil {
+            return err
+        }
+    }
+
+    w.setRecord(name, rec)
+    return w.checkMemory()
+}
+
+// Return the hex encoded MD5 of the upper case bases of rec. The packed
+// bases are decoded in chunks so the hook costs no more than a constant
+// amount of memory per sequence.
+func (rec *seqRecord) digest() (string) {
+    hash := md5.New()
+    size := int(rec.dnaSize)
+    n := scanChunkSize
+    if size < n {
+        n = size
+    }
+    chunk := make([]byte, n)
+    for pos := 0; pos < size; pos += n {
+        end := pos+n
+        if end > size {
+            end = size
+        }
+
+        out := chunk[:end-pos]
+        decode(out, rec.sequence[pos/BASES_PER_BYTE:], 0, len(out))
+        for _, b := range rec.nBlocks {
+            lo, hi := b.clip(pos, end)
+            if lo < hi {
+                fill(out[lo-pos:hi-pos], BASE_N)
+            }
+        }
+        hash.Write(out)
+    }
+
+    return fmt.Sprintf("%x", hash.Sum(nil))
+}
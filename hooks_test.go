@@ -0,0 +1,72 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "errors"
+)
+
+func TestWriterHooks(t *testing.T) {
+    packed := make([]*SequenceEvent, 0)
+    written := make([]*SequenceEvent, 0)
+    w := NewWriter(
+        OnPacked(func(e *SequenceEvent) (error) {
+            packed = append(packed, e)
+            return nil
+        }),
+        OnWritten(func(e *SequenceEvent) (error) {
+            written = append(written, e)
+            return nil
+        }),
+    )
+
+    w.Add("chr1", "ACGTNNNNacgt")
+    w.StartSequence("chr2")
+    w.AppendChunk("GGGG")
+    w.AppendGap(3)
+    w.AppendChunk("cc")
+    w.EndSequence()
+
+    if len(packed) != 2 || packed[0].Name != "chr1" || packed[1].DnaSize != 9 || packed[0].Offset != 0 {
+        t.Fatalf("Invalid packed events: %+v", packed)
+    }
+
+    var out bytes.Buffer
+    err := w.WriteTo(&out)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    tb, _ := NewReader(bytes.NewReader(out.Bytes()))
+    for i, e := range written {
+        good := w.Report().Sequences[i]
+        if e.SequenceReport != good {
+            t.Errorf("Invalid written event: %+v != %+v", e.SequenceReport, good)
+        }
+        digest, _ := tb.Digest(e.Name)
+        if e.Digest != digest || packed[i].Digest != digest {
+            t.Errorf("Invalid digest for %s: %s != %s", e.Name, e.Digest, digest)
+        }
+    }
+    if len(written) != 2 {
+        t.Errorf("Invalid written events: %+v", written)
+    }
+
+    fail := errors.New("database unavailable")
+    w = NewWriter(OnPacked(func(e *SequenceEvent) (error) { return fail }))
+    err = w.Add("chr1", "ACGT")
+    if !errors.Is(err, fail) || len(w.records) != 0 {
+        t.Errorf("Hook error not returned: %v", err)
+    }
+
+    w = NewWriter(OnWritten(func(e *SequenceEvent) (error) { return fail }))
+    w.Add("chr1", "ACGT")
+    err = w.WriteTo(&out)
+    if !errors.Is(err, fail) {
+        t.Errorf("Hook error not returned: %v", err)
+    }
+}
@@ -0,0 +1,145 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "sync"
+    "time"
+)
+
+// Granularity in bytes of the read histogram kept by IOStats
+const STATS_BUCKET_SIZE = 4096
+
+// limitedSource throttles reads from a Source to a fixed rate
+type limitedSource struct {
+    Source
+    rate   float64 // bytes per second
+    mu     sync.Mutex
+    next   time.Time // when the next read may start
+}
+
+func (l *limitedSource) ReadAt(p []byte, off int64) (int, error) {
+    l.mu.Lock()
+    now := time.Now()
+    if l.next.Before(now) {
+        l.next = now
+    }
+    wait := l.next.Sub(now)
+    l.next = l.next.Add(time.Duration(float64(len(p)) / l.rate * float64(time.Second)))
+    l.mu.Unlock()
+
+    if wait > 0 {
+        time.Sleep(wait)
+    }
+
+    return l.Source.ReadAt(p, off)
+}
+
+// RateLimit returns a Transform limiting reads from the underlying storage to
+// bytesPerSecond. Readers sharing the Source, such as those in a ReaderPool,
+// share the limit.
+func RateLimit(bytesPerSecond int64) (Transform) {
+    return func(src Source) (Source, error) {
+        if bytesPerSecond <= 0 {
+            return src, nil
+        }
+        return &limitedSource{Source: src, rate: float64(bytesPerSecond)}, nil
+    }
+}
+
+// IOStats records reads from the underlying storage. It is safe for
+// concurrent use.
+type IOStats struct {
+    mu       sync.Mutex
+    reads    int64
+    bytes    int64
+    buckets  map[int64]int64 // bytes read per STATS_BUCKET_SIZE block of the file
+}
+
+// countingSource records reads in an IOStats
+type countingSource struct {
+    Source
+    stats  *IOStats
+}
+
+func (c *countingSource) ReadAt(p []byte, off int64) (int, error) {
+    n, err := c.Source.ReadAt(p, off)
+    c.stats.record(off, n)
+    return n, err
+}
+
+func (s *IOStats) record(off int64, n int) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if s.buckets == nil {
+        s.buckets = make(map[int64]int64)
+    }
+
+    s.reads++
+    s.bytes += int64(n)
+    for end := off+int64(n); off < end; {
+        b := off/STATS_BUCKET_SIZE
+        next := (b+1)*STATS_BUCKET_SIZE
+        if next > end {
+            next = end
+        }
+        s.buckets[b] += next-off
+        off = next
+    }
+}
+
+// Instrument returns a Transform recording reads from the underlying
+// storage in stats
+func Instrument(stats *IOStats) (Transform) {
+    return func(src Source) (Source, error) {
+        return &countingSource{Source: src, stats: stats}, nil
+    }
+}
+
+// Returns the number of reads and bytes read from the underlying storage
+func (s *IOStats) Totals() (int64, int64) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    return s.reads, s.bytes
+}
+
+// Returns the bytes of packed DNA read per sequence of r, the Reader being
+// instrumented. Reads are attributed at STATS_BUCKET_SIZE granularity so
+// small neighbouring sequences may share counts.
+func (s *IOStats) Hotspots(r *Reader) (map[string]int64, error) {
+    offsets, err := r.Offsets()
+    if err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    hot := make(map[string]int64)
+    for name, region := range offsets {
+        start := region.Offset
+        end := start+int64(region.Length)
+        for b := start/STATS_BUCKET_SIZE; b*STATS_BUCKET_SIZE < end; b++ {
+            read, ok := s.buckets[b]
+            if !ok {
+                continue
+            }
+
+            // share of the bucket covered by the sequence
+            lo, hi := b*STATS_BUCKET_SIZE, (b+1)*STATS_BUCKET_SIZE
+            if lo < start {
+                lo = start
+            }
+            if hi > end {
+                hi = end
+            }
+            hot[name] += read*(hi-lo)/STATS_BUCKET_SIZE
+        }
+    }
+
+    return hot, nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "strings"
+    "time"
+)
+
+func TestInstrument(t *testing.T) {
+    w := NewWriter()
+    w.Add("small", "ACGT")
+    w.Add("hot", strings.Repeat("ACGT", 20000))
+    w.Add("cold", strings.Repeat("GGCC", 20000))
+    var out bytes.Buffer
+    w.WriteTo(&out)
+
+    stats := new(IOStats)
+    tb, err := NewReader(bytes.NewReader(out.Bytes()), WithTransform(Instrument(stats)), BufferSize(0))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    for i := 0; i < 3; i++ {
+        _, err = tb.Read("hot")
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+    }
+
+    reads, n := stats.Totals()
+    if reads == 0 || n < 3*20000 {
+        t.Errorf("Invalid totals: %d reads %d bytes", reads, n)
+    }
+
+    hot, err := stats.Hotspots(tb)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if hot["hot"] < 3*19000 || hot["cold"] > hot["hot"]/10 {
+        t.Errorf("Invalid hotspots: %v", hot)
+    }
+}
+
+func TestRateLimit(t *testing.T) {
+    w := NewWriter()
+    w.Add("chr1", strings.Repeat("ACGT", 4000))
+    var out bytes.Buffer
+    w.WriteTo(&out)
+
+    tb, err := NewReader(bytes.NewReader(out.Bytes()), WithTransform(RateLimit(20000)), BufferSize(0))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    start := time.Now()
+    for i := 0; i < 4; i++ {
+        _, err = tb.Read("chr1")
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+    }
+
+    // 4 reads of 1000 packed bytes at 20KB/s take at least 150ms after the first
+    if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+        t.Errorf("Reads were not rate limited: %s", elapsed)
+    }
+}
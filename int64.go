@@ -0,0 +1,73 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "fmt"
+)
+
+// Largest value of int on this platform
+const maxInt = int64(^uint(0) >> 1)
+
+// Convert v to int, failing if it does not fit on this platform
+func toInt(v int64) (int, error) {
+    if v > maxInt || v < -maxInt-1 {
+        return 0, fmt.Errorf("Coordinate %d overflows int on this platform", v)
+    }
+
+    return int(v), nil
+}
+
+// Returns the length for sequence with name as an int64. Unlike Length this
+// is safe for sequences longer than 2^31-1 bases on 32-bit platforms.
+func (r *Reader) Length64(name string) (int64, error) {
+    rec, err := r.parseRecord(name, false)
+    if err != nil {
+        return -1, err
+    }
+
+    return int64(rec.dnaSize), nil
+}
+
+// Returns the total length of all sequences in the 2bit file as an int64.
+// Whole genome totals routinely exceed 2^31-1 bases.
+func (r *Reader) TotalLength64() (int64, error) {
+    total := int64(0)
+    for _, name := range r.Names() {
+        n, err := r.Length64(name)
+        if err != nil {
+            return -1, err
+        }
+        total += n
+    }
+
+    return total, nil
+}
+
+// Read sequence from start to end using int64 coordinates. An error is
+// returned if the range can't be held in memory on this platform.
+func (r *Reader) ReadRange64(name string, start, end int64) ([]byte, error) {
+    s, err := toInt(start)
+    if err != nil {
+        return nil, err
+    }
+
+    e, err := toInt(end)
+    if err != nil {
+        return nil, err
+    }
+
+    return r.ReadRange(name, s, e)
+}
+
+// Read a single base at pos (0-based) using int64 coordinates
+func (r *Reader) Base64(name string, pos int64) (byte, error) {
+    p, err := toInt(pos)
+    if err != nil {
+        return 0, err
+    }
+
+    return r.Base(name, p)
+}
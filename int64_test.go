@@ -0,0 +1,97 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "encoding/binary"
+    "errors"
+    "math"
+)
+
+func TestInt64(t *testing.T) {
+    tb, err := openTestTwoBit()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    n, err := tb.Length64("ex1")
+    if err != nil || n != 21 {
+        t.Errorf("Invalid length: %d %v", n, err)
+    }
+
+    total, err := tb.TotalLength64()
+    if err != nil || total != 21 {
+        t.Errorf("Invalid total length: %d %v", total, err)
+    }
+
+    seq, err := tb.ReadRange64("ex1", 5, 11)
+    if err != nil || string(seq) != "ctttnn" {
+        t.Errorf("Invalid sequence: %s %v", seq, err)
+    }
+
+    if maxInt < 1<<62 {
+        _, err = tb.ReadRange64("ex1", 0, 1<<40)
+        if err == nil {
+            t.Errorf("Expected overflow error on 32-bit platform")
+        }
+    }
+}
+
+// Build a 2bit file holding a single record with the given dnaSize and one N
+// block but no packed data
+func maxSizeTwoBit(dnaSize, blockStart, blockSize uint32) ([]byte) {
+    buf := make([]byte, 0)
+    put := func(v uint32) {
+        b := make([]byte, 4)
+        binary.LittleEndian.PutUint32(b, v)
+        buf = append(buf, b...)
+    }
+
+    put(SIG)
+    put(0)
+    put(1)
+    put(0)
+    buf = append(buf, 1, 'x')
+    put(uint32(HEADER_SIZE+6))
+    put(dnaSize)
+    put(1)
+    put(blockStart)
+    put(blockSize)
+    put(0)
+    put(0)
+
+    return buf
+}
+
+func TestNearMaxDnaSize(t *testing.T) {
+    if n := packedSize64(math.MaxUint32); n != 1<<30 {
+        t.Errorf("Invalid packed size: %d != %d", n, 1<<30)
+    }
+
+    tb, err := NewReader(bytes.NewReader(maxSizeTwoBit(math.MaxUint32, 0, 10)))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    _, err = tb.ReadRange("x", 0, 10)
+    if maxInt > math.MaxUint32 && !errors.Is(err, ErrTruncated) {
+        t.Errorf("Expected ErrTruncated, got: %v", err)
+    }
+    if err == nil {
+        t.Errorf("Expected error reading near max dnaSize")
+    }
+
+    tb, err = NewReader(bytes.NewReader(maxSizeTwoBit(math.MaxUint32, math.MaxUint32-4, 10)))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    _, err = tb.NBlocks("x")
+    if err == nil {
+        t.Errorf("Expected error for overflowing block")
+    }
+}
@@ -0,0 +1,148 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "fmt"
+)
+
+// Position is a single base of a named sequence. Pos is 0-based: the first
+// base of a sequence is at Pos 0.
+type Position struct {
+    Name     string
+    Pos      int
+}
+
+// Validate returns an error if p does not name a base
+func (p Position) Validate() (error) {
+    if len(p.Name) == 0 {
+        return fmt.Errorf("Invalid position: missing sequence name")
+    }
+    if p.Pos < 0 {
+        return fmt.Errorf("Invalid position %s:%d: negative coordinate", p.Name, p.Pos)
+    }
+
+    return nil
+}
+
+// Interval is a region of a named sequence. Coordinates are 0-based and
+// half-open: the region covers bases Start to End-1, so End is the first base
+// past the region and End-Start is its length. An empty interval has Start
+// equal to End. This matches BED, so BED columns can be used unchanged, while
+// GFF/VCF style 1-based closed coordinates need Start-1.
+type Interval struct {
+    Name     string
+    Start    int
+    End      int
+}
+
+// Range is a region of a named sequence from Start to End (0-based, end
+// exclusive). It is the same type as Interval.
+type Range = Interval
+
+// Validate returns an error if iv is not a well formed interval
+func (iv Interval) Validate() (error) {
+    if len(iv.Name) == 0 {
+        return fmt.Errorf("Invalid interval: missing sequence name")
+    }
+    if iv.Start < 0 {
+        return fmt.Errorf("Invalid interval %s: negative start", iv)
+    }
+    if iv.End < iv.Start {
+        return fmt.Errorf("Invalid interval %s: end before start", iv)
+    }
+
+    return nil
+}
+
+// Len returns the number of bases in iv
+func (iv Interval) Len() (int) {
+    return iv.End-iv.Start
+}
+
+// Contains returns true if p is one of the bases of iv
+func (iv Interval) Contains(p Position) (bool) {
+    return p.Name == iv.Name && p.Pos >= iv.Start && p.Pos < iv.End
+}
+
+// Overlaps returns true if iv and o share at least one base
+func (iv Interval) Overlaps(o Interval) (bool) {
+    return iv.Name == o.Name && iv.Block().Overlaps(o.Block())
+}
+
+// Block returns the bases of iv as a Block
+func (iv Interval) Block() (*Block) {
+    return &Block{Start: iv.Start, Length: iv.End-iv.Start}
+}
+
+// String formats iv as name:start-end using the 0-based half-open coordinates
+func (iv Interval) String() (string) {
+    return fmt.Sprintf("%s:%d-%d", iv.Name, iv.Start, iv.End)
+}
+
+// Intervals returns blocks as intervals of sequence name
+func (bs Blocks) Intervals(name string) ([]Interval) {
+    out := make([]Interval, len(bs))
+    for i, b := range bs {
+        out[i] = Interval{Name: name, Start: b.Start, End: b.End()}
+    }
+
+    return out
+}
+
+// Group intervals into blocks keyed by sequence name, validating each
+func intervalBlocks(ivs []Interval) (map[string]Blocks, error) {
+    blocks := make(map[string]Blocks)
+    for _, iv := range ivs {
+        err := iv.Validate()
+        if err != nil {
+            return nil, err
+        }
+        blocks[iv.Name] = append(blocks[iv.Name], iv.Block())
+    }
+
+    return blocks, nil
+}
+
+// ReadInterval returns the bases of iv. Unlike ReadRange an End of 0 is not
+// taken to mean the end of the sequence and intervals extending past the end
+// of the sequence are an error rather than clipped.
+func (r *Reader) ReadInterval(iv Interval) ([]byte, error) {
+    err := iv.Validate()
+    if err != nil {
+        return nil, err
+    }
+
+    size, err := r.Length(iv.Name)
+    if err != nil {
+        return nil, err
+    }
+    if iv.End > size {
+        return nil, fmt.Errorf("Invalid interval %s: end past sequence length %d", iv, size)
+    }
+    if iv.Len() == 0 {
+        return []byte{}, nil
+    }
+
+    return r.ReadRange(iv.Name, iv.Start, iv.End)
+}
+
+// SetMaskIntervals replaces the masked (lower-case) blocks of each sequence
+// named in ivs with the given intervals. See SetMask.
+func (w *Writer) SetMaskIntervals(ivs []Interval) (error) {
+    blocks, err := intervalBlocks(ivs)
+    if err != nil {
+        return err
+    }
+
+    for name, mask := range blocks {
+        err = w.SetMask(name, mask)
+        if err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
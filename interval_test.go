@@ -0,0 +1,100 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "strings"
+)
+
+func TestInterval(t *testing.T) {
+    iv := Interval{Name: "chr1", Start: 2, End: 5}
+    if iv.Len() != 3 || iv.String() != "chr1:2-5" {
+        t.Errorf("Invalid interval: %s (%d)", iv, iv.Len())
+    }
+    if iv.Contains(Position{Name: "chr1", Pos: 1}) || !iv.Contains(Position{Name: "chr1", Pos: 2}) ||
+        !iv.Contains(Position{Name: "chr1", Pos: 4}) || iv.Contains(Position{Name: "chr1", Pos: 5}) {
+        t.Errorf("Interval should contain bases 2 to 4 only")
+    }
+    if iv.Overlaps(Interval{Name: "chr1", Start: 5, End: 8}) || !iv.Overlaps(Interval{Name: "chr1", Start: 4, End: 8}) {
+        t.Errorf("Adjacent intervals should not overlap")
+    }
+
+    bad := []Interval{{Start: 0, End: 1}, {Name: "chr1", Start: -1, End: 1}, {Name: "chr1", Start: 3, End: 2}}
+    for _, b := range bad {
+        if b.Validate() == nil {
+            t.Errorf("Invalid interval accepted: %+v", b)
+        }
+    }
+    if (Position{Name: "chr1", Pos: -1}).Validate() == nil {
+        t.Errorf("Negative position accepted")
+    }
+
+    tb := newTestReader(t, map[string]string{"chr1": "ACGTACGTAC"})
+
+    seq, err := tb.ReadInterval(iv)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if string(seq) != "GTA" {
+        t.Errorf("Invalid interval sequence: %s", seq)
+    }
+
+    seq, err = tb.ReadInterval(Interval{Name: "chr1", Start: 3, End: 3})
+    if err != nil || len(seq) != 0 {
+        t.Errorf("Empty interval should read no bases: %s %v", seq, err)
+    }
+
+    _, err = tb.ReadInterval(Interval{Name: "chr1", Start: 0, End: 11})
+    if err == nil {
+        t.Errorf("Interval past end of sequence accepted")
+    }
+
+    _, err = tb.ReadInterval(Interval{Name: "chr1", Start: 0, End: 0})
+    if err != nil {
+        t.Errorf("Empty interval at start rejected: %s", err)
+    }
+}
+
+func TestIntervalMask(t *testing.T) {
+    ivs, err := ReadBEDIntervals(strings.NewReader("track name=x\nchr1\t1\t3\nchr2\t0\t2\nchr1\t6\t8\n"))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if len(ivs) != 3 || ivs[2] != (Interval{Name: "chr1", Start: 6, End: 8}) {
+        t.Errorf("Invalid BED intervals: %+v", ivs)
+    }
+
+    w := NewWriter()
+    w.Add("chr1", "ACGTACGTAC")
+    w.Add("chr2", "GGGG")
+    err = w.SetMaskIntervals(ivs)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    var out bytes.Buffer
+    w.WriteTo(&out)
+    tb, err := NewReader(bytes.NewReader(out.Bytes()))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    seq, _ := tb.Read("chr1")
+    if string(seq) != "AcgTACgtAC" {
+        t.Errorf("Invalid masked sequence: %s", seq)
+    }
+
+    err = w.SetMaskIntervals([]Interval{{Name: "chr1", Start: 4, End: 2}})
+    if err == nil {
+        t.Errorf("Invalid mask interval accepted")
+    }
+
+    blocks := Blocks{{Start: 1, Length: 2}}
+    if got := blocks.Intervals("chr1"); len(got) != 1 || got[0] != ivs[0] {
+        t.Errorf("Invalid intervals from blocks: %+v", got)
+    }
+}
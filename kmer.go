@@ -0,0 +1,98 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "fmt"
+)
+
+// Largest k supported by CountKmers
+const MAX_KMER = 12
+
+// Count the k-mers in sequence with name. Counts are computed directly on the
+// packed representation and k-mers overlapping N blocks are skipped. Case
+// (masking) is ignored. The returned slice has 4^k entries indexed by the
+// 2-bit encoding of the k-mer (see ENCODE_* and KmerString).
+func (r *Reader) CountKmers(name string, k int) ([]int64, error) {
+    if k < 1 || k > MAX_KMER {
+        return nil, fmt.Errorf("Invalid k: %d. Must be between 1 and %d", k, MAX_KMER)
+    }
+
+    counts := make([]int64, 1<<uint(2*k))
+    err := r.countKmers(name, k, counts)
+    if err != nil {
+        return nil, err
+    }
+
+    return counts, nil
+}
+
+// Count the k-mers across all sequences in the 2bit file. See CountKmers.
+func (r *Reader) CountKmersAll(k int) ([]int64, error) {
+    if k < 1 || k > MAX_KMER {
+        return nil, fmt.Errorf("Invalid k: %d. Must be between 1 and %d", k, MAX_KMER)
+    }
+
+    counts := make([]int64, 1<<uint(2*k))
+    for _, name := range r.Names() {
+        err := r.countKmers(name, k, counts)
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    return counts, nil
+}
+
+// Add the k-mer counts of sequence with name to counts
+func (r *Reader) countKmers(name string, k int, counts []int64) (error) {
+    rec, err := r.parseRecord(name, true)
+    if err != nil {
+        return err
+    }
+
+    if rec.dnaSize == 0 {
+        return nil
+    }
+
+    packed, err := r.ReadPackedRange(name, 0, 0)
+    if err != nil {
+        return err
+    }
+
+    mask := uint32(len(counts)-1)
+    kmer := uint32(0)
+    run := 0
+    nb := 0
+    for pos := 0; pos < int(rec.dnaSize); pos++ {
+        for nb < len(rec.nBlocks) && rec.nBlocks[nb].Start+rec.nBlocks[nb].Length <= pos {
+            nb++
+        }
+        if nb < len(rec.nBlocks) && rec.nBlocks[nb].Start <= pos {
+            run = 0
+            continue
+        }
+
+        shift := uint(6-2*(pos%BASES_PER_BYTE))
+        kmer = (kmer<<2 | uint32(packed[pos/BASES_PER_BYTE]>>shift)&0x3) & mask
+        run++
+        if run >= k {
+            counts[kmer]++
+        }
+    }
+
+    return nil
+}
+
+// Return the k-mer string for the index i of a slice returned by CountKmers
+func KmerString(i, k int) (string) {
+    kmer := make([]byte, k)
+    for j := k-1; j >= 0; j-- {
+        kmer[j] = BYTES2NT[i & 0x3]
+        i >>= 2
+    }
+
+    return string(kmer)
+}
@@ -0,0 +1,38 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+)
+
+func TestCountKmers(t *testing.T) {
+    tb, err := openTestTwoBit()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    // ACTgcctttnnnNantnaCgc without N blocks: ACTGCCTTT A T ACGC
+    counts, err := tb.CountKmers("ex1", 2)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    good := map[string]int64{
+        "AC": 2, "CT": 2, "TG": 1, "GC": 2, "CC": 1, "TT": 2, "CG": 1,
+    }
+
+    for i, n := range counts {
+        kmer := KmerString(i, 2)
+        if n != good[kmer] {
+            t.Errorf("Invalid count for %s: %d != %d", kmer, n, good[kmer])
+        }
+    }
+
+    _, err = tb.CountKmers("ex1", MAX_KMER+1)
+    if err == nil {
+        t.Errorf("Expected error for k > MAX_KMER")
+    }
+}
@@ -0,0 +1,65 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "sort"
+)
+
+// Order of sequences in the index and records of a written file
+type Order int
+
+const (
+    INSERTION_ORDER Order = iota // order sequences were added, as faToTwoBit
+    NAME_ORDER                   // sorted by name
+    LENGTH_ORDER                 // longest first, ties sorted by name
+)
+
+// Layout controls how a Writer lays out the file. The zero value writes
+// sequences in insertion order with no padding, producing files byte
+// identical to UCSC faToTwoBit for the same input.
+type Layout struct {
+    Order         Order // INSERTION_ORDER, NAME_ORDER or LENGTH_ORDER
+    Alignment     int   // start each record on a multiple of Alignment bytes, 0 or 1 for none
+    IndexPadding  int   // zero bytes reserved between the index and the first record
+}
+
+// WithLayout sets the file layout used by the Writer
+func WithLayout(layout Layout) (WriterOption) {
+    return func(w *Writer) {
+        w.layout = layout
+    }
+}
+
+// Round offset up to the record alignment
+func (l Layout) align(offset int64) (int64) {
+    if l.Alignment <= 1 {
+        return offset
+    }
+
+    a := int64(l.Alignment)
+    return (offset+a-1)/a*a
+}
+
+// Returns the names of sequences in the order they will be written
+func (w *Writer) sequenceOrder() ([]string) {
+    names := make([]string, len(w.order))
+    copy(names, w.order)
+
+    switch w.layout.Order {
+    case NAME_ORDER:
+        sort.Strings(names)
+    case LENGTH_ORDER:
+        sort.SliceStable(names, func(i, j int) bool {
+            a, b := w.records[names[i]].dnaSize, w.records[names[j]].dnaSize
+            if a != b {
+                return a > b
+            }
+            return names[i] < names[j]
+        })
+    }
+
+    return names
+}
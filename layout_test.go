@@ -0,0 +1,94 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "io/ioutil"
+    "strings"
+)
+
+func TestLayoutFaToTwoBit(t *testing.T) {
+    good, err := ioutil.ReadFile("examples/simple.2bit")
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    w := NewWriter()
+    w.Add("ex1", "ACTgcctttnnnNantnaCgc")
+    var out bytes.Buffer
+    w.WriteTo(&out)
+
+    if !bytes.Equal(out.Bytes(), good) {
+        t.Errorf("Output is not byte identical to faToTwoBit")
+    }
+}
+
+func TestLayout(t *testing.T) {
+    seqs := []string{"chrB", "chrA", "chrC"}
+
+    tests := []struct {
+        layout  Layout
+        order   string
+    }{
+        {Layout{}, "chrB,chrA,chrC"},
+        {Layout{Order: NAME_ORDER}, "chrA,chrB,chrC"},
+        {Layout{Alignment: 64, IndexPadding: 100}, "chrB,chrA,chrC"},
+    }
+
+    for _, test := range tests {
+        w := NewWriter(WithLayout(test.layout))
+        for _, name := range seqs {
+            w.Add(name, "ACGTNNacg")
+        }
+        // replacing a sequence keeps its position
+        w.Add("chrB", "GGGGA")
+
+        var out bytes.Buffer
+        err := w.WriteTo(&out)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+
+        report := w.Report()
+        if report.Bytes != int64(out.Len()) {
+            t.Errorf("Invalid report size: %d != %d", report.Bytes, out.Len())
+        }
+
+        var order []string
+        for _, s := range report.Sequences {
+            order = append(order, s.Name)
+            if test.layout.Alignment > 0 && s.Offset%int64(test.layout.Alignment) != 0 {
+                t.Errorf("Record %s is not aligned: %d", s.Name, s.Offset)
+            }
+        }
+        if strings.Join(order, ",") != test.order {
+            t.Errorf("Invalid order: %v != %s", order, test.order)
+        }
+
+        tb, err := NewReader(bytes.NewReader(out.Bytes()))
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        if strings.Join(tb.namesByOffset(), ",") != test.order {
+            t.Errorf("Invalid file order: %v != %s", tb.namesByOffset(), test.order)
+        }
+
+        seq, err := tb.Read("chrB")
+        if err != nil || string(seq) != "GGGGA" {
+            t.Errorf("Invalid sequence: %s %v", seq, err)
+        }
+        seq, err = tb.Read("chrC")
+        if err != nil || string(seq) != "ACGTNNacg" {
+            t.Errorf("Invalid sequence: %s %v", seq, err)
+        }
+    }
+
+    err := NewWriter(WithLayout(Layout{Alignment: -1})).WriteTo(&bytes.Buffer{})
+    if err == nil {
+        t.Errorf("Expected error for invalid layout")
+    }
+}
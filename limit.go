@@ -0,0 +1,59 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "errors"
+    "fmt"
+)
+
+// ErrTooLarge is returned when a read would decode more bases than allowed
+var ErrTooLarge = errors.New("twobit: range too large")
+
+// MaxBases limits the number of bases a single ReadRange (and Read,
+// ReadRanges, ReadRange64) may decode. Larger ranges fail with ErrTooLarge
+// before any memory is allocated, protecting servers from requests such as
+// a whole chromosome. A limit of 0 (the default) disables the check.
+func MaxBases(n int) (ReadOption) {
+    return func(r *Reader) (error) {
+        if n < 0 {
+            return fmt.Errorf("Invalid maximum bases: %d", n)
+        }
+        r.maxBases = n
+        return nil
+    }
+}
+
+// Read sequence from start to end as ReadRange, failing with ErrTooLarge if
+// the range is longer than max bases. This overrides the Reader's MaxBases
+// for one call; a max of 0 disables the limit.
+func (r *Reader) ReadRangeMax(name string, start, end, max int) ([]byte, error) {
+    rec, start, end, err := r.rangeRecord(name, start, end)
+    if err != nil {
+        return nil, err
+    }
+
+    err = checkSize(name, start, end, max)
+    if err != nil {
+        return nil, err
+    }
+
+    seq := make([]byte, end-start)
+    err = r.readInto(seq, rec, start, end)
+    if err != nil {
+        return nil, err
+    }
+
+    return seq, nil
+}
+
+// Check the range start to end is at most max bases, 0 for no limit
+func checkSize(name string, start, end, max int) (error) {
+    if max > 0 && end-start > max {
+        return fmt.Errorf("%w: %s:%d-%d is %d bases, limit is %d", ErrTooLarge, name, start, end, end-start, max)
+    }
+
+    return nil
+}
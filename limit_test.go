@@ -0,0 +1,58 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "errors"
+)
+
+func TestMaxBases(t *testing.T) {
+    w := NewWriter()
+    w.Add("chr1", "ACGTACGTACGTACGTACGT")
+    var out bytes.Buffer
+    w.WriteTo(&out)
+
+    tb, err := NewReader(bytes.NewReader(out.Bytes()), MaxBases(10))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    seq, err := tb.ReadRange("chr1", 5, 15)
+    if err != nil || string(seq) != "CGTACGTACG" {
+        t.Errorf("Invalid read within limit: %s %v", seq, err)
+    }
+
+    _, err = tb.Read("chr1")
+    if !errors.Is(err, ErrTooLarge) {
+        t.Errorf("Expected ErrTooLarge, got: %v", err)
+    }
+
+    _, err = tb.ReadRanges([]Range{{"chr1", 0, 4}, {"chr1", 0, 11}})
+    if !errors.Is(err, ErrTooLarge) {
+        t.Errorf("Expected ErrTooLarge, got: %v", err)
+    }
+
+    _, err = tb.ReadRanges([]Range{{"chr1", 0, 0}})
+    if err == nil {
+        t.Errorf("Expected error for empty range")
+    }
+
+    seq, err = tb.ReadRangeMax("chr1", 0, 0, 20)
+    if err != nil || len(seq) != 20 {
+        t.Errorf("Invalid read with per call limit: %s %v", seq, err)
+    }
+
+    _, err = tb.ReadRangeMax("chr1", 0, 3, 2)
+    if !errors.Is(err, ErrTooLarge) {
+        t.Errorf("Expected ErrTooLarge, got: %v", err)
+    }
+
+    _, err = NewReader(bytes.NewReader(out.Bytes()), MaxBases(-1))
+    if err == nil {
+        t.Errorf("Expected error for negative limit")
+    }
+}
@@ -0,0 +1,155 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "fmt"
+)
+
+// Default DUST window length and score threshold
+const DUST_WINDOW = 64
+const DUST_THRESHOLD = 20
+
+// Find runs of a single repeated base (ignoring case) of at least minLen
+// bases in sequence with name. Runs of N are not reported, whatever the gap
+// character of r.
+func (r *Reader) Homopolymers(name string, minLen int) ([]Range, error) {
+    if minLen < 1 {
+        return nil, fmt.Errorf("Invalid minimum length: %d", minLen)
+    }
+
+    s, err := newWindowScanner(r, name, 0)
+    if err != nil {
+        return nil, err
+    }
+    rec, err := r.parseRecord(name, true)
+    if err != nil {
+        return nil, err
+    }
+    nBlocks := rec.sortedBlocks().nBlocks
+    ni := 0
+
+    runs := make([]Range, 0)
+    start := 0
+    var last byte
+    for i := 0; i <= s.length; i++ {
+        var b byte
+        if i < s.length {
+            b, err = s.at(i)
+            if err != nil {
+                return nil, err
+            }
+        }
+
+        if i > 0 && b == last {
+            continue
+        }
+
+        // N blocks decode as the gap character, so skip runs inside them
+        if i-start >= minLen && sweepBlocks(nBlocks, &ni, start, i) < i-start {
+            runs = append(runs, Range{Name: name, Start: start, End: i})
+        }
+        start = i
+        last = b
+    }
+
+    return runs, nil
+}
+
+// Find low-complexity intervals in sequence with name using a DUST-like
+// score. For each window of window bases the score is the sum over all
+// triplets of c*(c-1)/2 divided by the number of triplets in the window less
+// one, where c is the count of each distinct triplet. Windows scoring above
+// threshold are merged into intervals. Triplets containing N are ignored.
+func (r *Reader) LowComplexity(name string, window int, threshold float64) ([]Range, error) {
+    if window < 4 {
+        return nil, fmt.Errorf("Invalid window length: %d", window)
+    }
+
+    s, err := newWindowScanner(r, name, window)
+    if err != nil {
+        return nil, err
+    }
+
+    intervals := make([]Range, 0)
+    interval := Range{Name: name, Start: -1}
+
+    // triplet ending at each position of the window, -1 if it contains N
+    triplet := func(i int) (int, error) {
+        t := 0
+        for j := i-2; j <= i; j++ {
+            b, err := s.at(j)
+            if err != nil {
+                return -1, err
+            }
+            v, ok := dustCode(b)
+            if !ok {
+                return -1, nil
+            }
+            t = t<<2 | v
+        }
+        return t, nil
+    }
+
+    counts := make([]int, 64)
+    score := 0
+    for i := 2; i < s.length; i++ {
+        t, err := triplet(i)
+        if err != nil {
+            return nil, err
+        }
+        if t >= 0 {
+            score += counts[t]
+            counts[t]++
+        }
+
+        // drop the triplet that fell out of the window
+        if i-window+2 >= 2 {
+            t, err := triplet(i-window+2)
+            if err != nil {
+                return nil, err
+            }
+            if t >= 0 {
+                counts[t]--
+                score -= counts[t]
+            }
+        }
+
+        if i < window-1 {
+            continue
+        }
+
+        if float64(score) / float64(window-3) <= threshold {
+            continue
+        }
+
+        start := i-window+1
+        if interval.Start >= 0 && start <= interval.End {
+            interval.End = i+1
+            continue
+        }
+
+        if interval.Start >= 0 {
+            intervals = append(intervals, interval)
+        }
+        interval = Range{Name: name, Start: start, End: i+1}
+    }
+
+    if interval.Start >= 0 {
+        intervals = append(intervals, interval)
+    }
+
+    return intervals, nil
+}
+
+// Return the 2-bit code of an upper case base for triplet counting
+func dustCode(b byte) (int, bool) {
+    switch b {
+    case BASE_A, BASE_C, BASE_G, BASE_T:
+        return int(NT2BYTES[b]), true
+    }
+
+    return 0, false
+}
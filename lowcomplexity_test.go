@@ -0,0 +1,88 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "math/rand"
+    "reflect"
+    "strings"
+)
+
+func newTestReader(t *testing.T, seqs map[string]string) (*Reader) {
+    w := NewWriter()
+    for name, seq := range seqs {
+        err := w.Add(name, seq)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+    }
+
+    var out bytes.Buffer
+    err := w.WriteTo(&out)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    tb, err := NewReader(bytes.NewReader(out.Bytes()))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    return tb
+}
+
+func TestHomopolymers(t *testing.T) {
+    tb := newTestReader(t, map[string]string{"chr1": "ACaaaAGTTTNNNNNNCCCCC"})
+
+    runs, err := tb.Homopolymers("chr1", 4)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    good := []Range{{"chr1", 2, 6}, {"chr1", 16, 21}}
+    if !reflect.DeepEqual(runs, good) {
+        t.Errorf("Invalid homopolymers: %v != %v", runs, good)
+    }
+
+    // gaps are not homopolymers whatever character they decode as
+    w := NewWriter()
+    w.Add("chr1", "ACaaaAGTTTNNNNNNCCCCC")
+    var out bytes.Buffer
+    w.WriteTo(&out)
+    tb, err = NewReader(bytes.NewReader(out.Bytes()), GapChar('-'))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    runs, err = tb.Homopolymers("chr1", 4)
+    if err != nil || !reflect.DeepEqual(runs, good) {
+        t.Errorf("Invalid homopolymers with gap character: %v %v", runs, err)
+    }
+}
+
+func TestLowComplexity(t *testing.T) {
+    rnd := rand.New(rand.NewSource(1))
+    random := make([]byte, 200)
+    for i := range random {
+        random[i] = "ACGT"[rnd.Intn(4)]
+    }
+
+    seq := string(random) + strings.Repeat("A", 100) + string(random)
+    tb := newTestReader(t, map[string]string{"chr1": seq})
+
+    intervals, err := tb.LowComplexity("chr1", DUST_WINDOW, DUST_THRESHOLD)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    if len(intervals) != 1 {
+        t.Fatalf("Invalid interval count: %d != %d", len(intervals), 1)
+    }
+
+    if intervals[0].Start > 200 || intervals[0].End < 300 {
+        t.Errorf("Interval %d-%d does not cover the repeat", intervals[0].Start, intervals[0].End)
+    }
+}
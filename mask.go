@@ -0,0 +1,237 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "io"
+    "bufio"
+    "encoding/json"
+    "fmt"
+    "strconv"
+)
+
+// Read intervals from a BED file as blocks keyed by sequence name. Only the
+// first three columns are used, see ReadBEDIntervals.
+func ReadBED(in io.Reader) (map[string]Blocks, error) {
+    ivs, err := ReadBEDIntervals(in)
+    if err != nil {
+        return nil, err
+    }
+
+    blocks := make(map[string]Blocks)
+    for _, iv := range ivs {
+        blocks[iv.Name] = append(blocks[iv.Name], iv.Block())
+    }
+
+    return blocks, nil
+}
+
+// Read intervals from a BED file in file order. Only the first three columns
+// are used. Header, track and comment lines are skipped, see ScanBED.
+func ReadBEDIntervals(in io.Reader) ([]Interval, error) {
+    ivs := make([]Interval, 0)
+    err := ScanBED(in, func(iv Interval, cols []string, line int) (error) {
+        ivs = append(ivs, iv)
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    return ivs, nil
+}
+
+// Parse the chrom, start and end columns of a BED record on line. Empty
+// intervals are invalid.
+func parseBEDInterval(cols []string, line int) (Interval, error) {
+    if len(cols) < 3 {
+        return Interval{}, fmt.Errorf("Invalid BED record on line %d", line)
+    }
+
+    start, err := strconv.Atoi(cols[1])
+    if err != nil || start < 0 {
+        return Interval{}, fmt.Errorf("Invalid BED start on line %d: %s", line, cols[1])
+    }
+    end, err := strconv.Atoi(cols[2])
+    if err != nil || end <= start {
+        return Interval{}, fmt.Errorf("Invalid BED end on line %d: %s", line, cols[2])
+    }
+
+    return Interval{Name: cols[0], Start: start, End: end}, nil
+}
+
+// SetMask replaces the masked (lower-case) blocks of sequence name. The mask
+// is normalized and clipped to the length of the sequence. Combined with the
+// Blocks Union, Intersect and Subtract operations this allows masks from
+// different sources (e.g. RepeatMasker and TRF) to be combined at write time.
+func (w *Writer) SetMask(name string, mask Blocks) (error) {
+    if w.closed {
+        return ErrClosed
+    }
+
+    rec, ok := w.records[name]
+    if !ok {
+        return fmt.Errorf("Invalid sequence name: %s", name)
+    }
+
+    whole := Blocks{&Block{Start: 0, Length: int(rec.dnaSize)}}
+    w.memory -= rec.memory()
+    rec.mBlocks = mask.Intersect(whole)
+    w.memory += rec.memory()
+
+    return nil
+}
+
+// MaskCount is the masked bases of one sequence or of the whole genome
+type MaskCount struct {
+    Name     string  `json:"name"`
+    Length   int64   `json:"length"`
+    N        int64   `json:"n"`
+    Blocks   int     `json:"blocks"`  // number of mask blocks
+    Masked   int64   `json:"masked"`  // masked bases
+    Percent  float64 `json:"percent"` // masked bases as a percentage of length
+}
+
+// MaskSummary summarizes the masked bases of each sequence in file order and
+// of the whole genome, similar to the RepeatMasker summary table
+type MaskSummary struct {
+    Sequences  []*MaskCount `json:"sequences"`
+    Total      *MaskCount   `json:"total"`
+}
+
+// Compute the mask summary from the block tables without reading sequence
+func (r *Reader) MaskSummary() (*MaskSummary, error) {
+    summary := &MaskSummary{Sequences: make([]*MaskCount, 0), Total: &MaskCount{Name: "total"}}
+
+    for _, name := range r.namesByOffset() {
+        rec, err := r.parseRecord(name, true)
+        if err != nil {
+            return nil, err
+        }
+
+        c := &MaskCount{Name: name, Length: int64(rec.dnaSize), Blocks: len(rec.mBlocks)}
+        for _, b := range rec.nBlocks {
+            c.N += int64(b.Length)
+        }
+        for _, b := range rec.mBlocks {
+            c.Masked += int64(b.Length)
+        }
+        c.setPercent()
+        summary.Sequences = append(summary.Sequences, c)
+
+        summary.Total.Length += c.Length
+        summary.Total.N += c.N
+        summary.Total.Blocks += c.Blocks
+        summary.Total.Masked += c.Masked
+    }
+    summary.Total.setPercent()
+
+    return summary, nil
+}
+
+func (c *MaskCount) setPercent() {
+    if c.Length > 0 {
+        c.Percent = float64(c.Masked) * 100 / float64(c.Length)
+    }
+}
+
+// Write the summary as a tab-delimited table with a header line and a final
+// total row
+func (s *MaskSummary) WriteTSV(out io.Writer) (error) {
+    w := bufio.NewWriter(out)
+    fmt.Fprintf(w, "name\tlength\tn\tblocks\tmasked\tpercent\n")
+    for _, c := range append(s.Sequences, s.Total) {
+        _, err := fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%.2f\n", c.Name, c.Length, c.N, c.Blocks, c.Masked, c.Percent)
+        if err != nil {
+            return err
+        }
+    }
+
+    return w.Flush()
+}
+
+// Write the summary in JSON format
+func (s *MaskSummary) WriteJSON(out io.Writer) (error) {
+    return json.NewEncoder(out).Encode(s)
+}
+
+// SequenceMaskDiff is the change in the mask of one sequence between two
+// versions of a genome
+type SequenceMaskDiff struct {
+    Name    string     `json:"name"`
+    Gained  []Interval `json:"gained"` // masked in the updated version only
+    Lost    []Interval `json:"lost"`   // masked in the old version only
+}
+
+// MaskDiff is the change in the masks of every sequence shared by two
+// versions of a genome, in the file order of the old version
+type MaskDiff struct {
+    Sequences  []*SequenceMaskDiff `json:"sequences"`
+}
+
+// DiffMasks compares the masked (lower-case) blocks of the sequences in old
+// and updated, for example two RepeatMasker runs over the same assembly. Only the
+// block tables are read, sequence data is never decoded. Sequences missing
+// from updated are skipped and sequences whose lengths differ are an error.
+func DiffMasks(old, updated *Reader) (*MaskDiff, error) {
+    diff := &MaskDiff{Sequences: make([]*SequenceMaskDiff, 0)}
+
+    for _, name := range old.namesByOffset() {
+        _, ok, err := updated.lookup(name)
+        if err != nil {
+            return nil, err
+        }
+        if !ok {
+            continue
+        }
+
+        a, err := old.parseRecord(name, true)
+        if err != nil {
+            return nil, err
+        }
+        b, err := updated.parseRecord(name, true)
+        if err != nil {
+            return nil, err
+        }
+        if a.dnaSize != b.dnaSize {
+            return nil, fmt.Errorf("Sequence %s has length %d in old and %d in updated", name, a.dnaSize, b.dnaSize)
+        }
+
+        diff.Sequences = append(diff.Sequences, &SequenceMaskDiff{
+            Name:   name,
+            Gained: b.mBlocks.Subtract(a.mBlocks).Intervals(name),
+            Lost:   a.mBlocks.Subtract(b.mBlocks).Intervals(name),
+        })
+    }
+
+    return diff, nil
+}
+
+// Write the changed intervals in BED format with a fourth column of gained
+// or lost, sorted by start within each sequence
+func (d *MaskDiff) WriteBED(out io.Writer) (error) {
+    w := bufio.NewWriter(out)
+    for _, s := range d.Sequences {
+        i, j := 0, 0
+        for i < len(s.Gained) || j < len(s.Lost) {
+            var iv Interval
+            var change string
+            if j == len(s.Lost) || (i < len(s.Gained) && s.Gained[i].Start < s.Lost[j].Start) {
+                iv, change = s.Gained[i], "gained"
+                i++
+            } else {
+                iv, change = s.Lost[j], "lost"
+                j++
+            }
+
+            _, err := fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", iv.Name, iv.Start, iv.End, change)
+            if err != nil {
+                return err
+            }
+        }
+    }
+
+    return w.Flush()
+}
@@ -0,0 +1,146 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "reflect"
+    "strings"
+)
+
+func TestMaskAlgebra(t *testing.T) {
+    a := Blocks{{Start: 0, Length: 5}, {Start: 10, Length: 10}}
+    b := Blocks{{Start: 3, Length: 4}, {Start: 12, Length: 2}, {Start: 18, Length: 5}}
+
+    tests := []struct {
+        desc string
+        got  Blocks
+        good Blocks
+    }{
+        {"union", a.Union(b), Blocks{{Start: 0, Length: 7}, {Start: 10, Length: 13}}},
+        {"intersect", a.Intersect(b), Blocks{{Start: 3, Length: 2}, {Start: 12, Length: 2}, {Start: 18, Length: 2}}},
+        {"subtract", a.Subtract(b), Blocks{{Start: 0, Length: 3}, {Start: 10, Length: 2}, {Start: 14, Length: 4}}},
+    }
+
+    for _, tt := range tests {
+        if !reflect.DeepEqual(tt.got, tt.good) {
+            t.Errorf("Invalid %s: %v != %v", tt.desc, tt.got, tt.good)
+        }
+    }
+}
+
+func TestSetMask(t *testing.T) {
+    tb, err := openTestTwoBit()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    mask, err := tb.MBlocks("ex1")
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    bed, err := ReadBED(strings.NewReader("track name=trf\nex1\t0\t2\nex1\t15\t30\n"))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    w := NewWriter()
+    w.Add("ex1", "ACTGCCTTTNNNNANTNACGC")
+    err = w.SetMask("ex1", mask.Union(bed["ex1"]))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    var out bytes.Buffer
+    w.WriteTo(&out)
+
+    tb, err = NewReader(bytes.NewReader(out.Bytes()))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    seq, _ := tb.Read("ex1")
+    if string(seq) != "acTgcctttnnnNantnacgc" {
+        t.Errorf("Invalid masked sequence: %s", seq)
+    }
+}
+
+func TestMaskSummary(t *testing.T) {
+    w := NewWriter()
+    w.Add("chr1", "ACGTacgtNNnnAC")
+    w.Add("chr2", "acgtacgtAC")
+    w.Add("chr3", "")
+    var out bytes.Buffer
+    w.WriteTo(&out)
+    tb, _ := NewReader(bytes.NewReader(out.Bytes()))
+
+    s, err := tb.MaskSummary()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    if len(s.Sequences) != 3 || s.Sequences[0].Name != "chr1" {
+        t.Fatalf("Invalid summary sequences: %+v", s.Sequences)
+    }
+    c := s.Sequences[0]
+    if c.Length != 14 || c.N != 4 || c.Masked != 6 || c.Blocks != 2 {
+        t.Errorf("Invalid summary for chr1: %+v", c)
+    }
+    if s.Total.Masked != 14 || s.Total.Length != 24 || s.Total.Blocks != 3 {
+        t.Errorf("Invalid summary total: %+v", s.Total)
+    }
+
+    var tsv bytes.Buffer
+    s.WriteTSV(&tsv)
+    lines := strings.Split(strings.TrimSpace(tsv.String()), "\n")
+    if len(lines) != 5 || lines[2] != "chr2\t10\t0\t1\t8\t80.00" || lines[4] != "total\t24\t4\t3\t14\t58.33" {
+        t.Errorf("Invalid TSV: %q", lines)
+    }
+
+    var js bytes.Buffer
+    err = s.WriteJSON(&js)
+    if err != nil || !strings.Contains(js.String(), `"total":{"name":"total"`) {
+        t.Errorf("Invalid JSON: %s", js.String())
+    }
+}
+
+func TestDiffMasks(t *testing.T) {
+    old := newTestReader(t, map[string]string{"chr1": "acgtACGTacgtACGT", "chr2": "ACGTACGT", "chr3": "ACGT"})
+    updated := newTestReader(t, map[string]string{"chr1": "acGTACgtacgtacGT", "chr2": "ACGTACGT", "chr4": "ACGT"})
+
+    diff, err := DiffMasks(old, updated)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    if len(diff.Sequences) != 2 {
+        t.Fatalf("Invalid diff sequences: %+v", diff.Sequences)
+    }
+    var s *SequenceMaskDiff
+    for _, d := range diff.Sequences {
+        if d.Name == "chr1" {
+            s = d
+        }
+    }
+    gained := []Interval{{"chr1", 6, 8}, {"chr1", 12, 14}}
+    lost := []Interval{{"chr1", 2, 4}}
+    if s == nil || !reflect.DeepEqual(s.Gained, gained) || !reflect.DeepEqual(s.Lost, lost) {
+        t.Errorf("Invalid diff for chr1: %+v", s)
+    }
+
+    var bed bytes.Buffer
+    diff.WriteBED(&bed)
+    if !strings.Contains(bed.String(), "chr1\t2\t4\tlost\nchr1\t6\t8\tgained\nchr1\t12\t14\tgained\n") {
+        t.Errorf("Invalid BED: %q", bed.String())
+    }
+
+    short := newTestReader(t, map[string]string{"chr2": "ACGT"})
+    _, err = DiffMasks(old, short)
+    if err == nil {
+        t.Errorf("Length mismatch accepted")
+    }
+}
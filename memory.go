@@ -0,0 +1,96 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "crypto/sha256"
+    "fmt"
+)
+
+// Approximate heap bytes used by the bookkeeping of one sequence (record
+// struct, map entries and name) and by one N or mask block
+const (
+    recordMemory = 128
+    blockMemory  = 24
+)
+
+// Estimate the heap bytes held by rec, counting packed bases only if they
+// are in memory
+func (rec *seqRecord) memory() (int64) {
+    return recordMemory+int64(cap(rec.sequence))+int64(len(rec.nBlocks)+len(rec.mBlocks))*blockMemory
+}
+
+// EstimatedMemory returns an estimate of the heap bytes held by w: the
+// packed bases and blocks of the sequences added and not yet written, the
+// sequence being built with StartSequence and the digests kept to find
+// duplicates. Packed bases spilled to disk are not counted. Go runtime
+// overhead is not included, so leave headroom when budgeting.
+func (w *Writer) EstimatedMemory() (int64) {
+    total := w.memory
+    if w.building != nil {
+        total += w.building.rec.memory()
+    }
+    total += int64(len(w.digests))*(recordMemory+sha256.Size)
+    total += int64(len(w.descriptions))*recordMemory
+
+    return total
+}
+
+// MaxMemory limits the memory held by the Writer to about max bytes, as
+// measured by EstimatedMemory. Once adding a sequence takes the Writer past
+// the limit it switches to spilling packed bases to disk in ts, moving the
+// sequences already added too, as if created with SpillToDisk(ts), so
+// small inputs stay in memory and large ones don't get the process killed.
+// Sequences streamed with Reserve and WriteIndex are never held so only the
+// sequence being built counts against the limit. Close the Writer to remove
+// the temp file.
+func MaxMemory(max int64, ts TempStorage) (WriterOption) {
+    return func(w *Writer) {
+        w.maxMemory = max
+        w.memoryTemp = ts
+    }
+}
+
+// Switch to spilling to disk if w is over its MaxMemory limit. The estimate
+// is kept as a running total so the check costs the same for every record.
+func (w *Writer) checkMemory() (error) {
+    if w.maxMemory <= 0 || w.spill != nil {
+        return nil
+    }
+    if w.EstimatedMemory() <= w.maxMemory {
+        return nil
+    }
+
+    w.spill = &spillFile{opts: w.memoryTemp}
+    for _, name := range w.order {
+        rec := w.records[name]
+        if rec.spill != nil {
+            continue
+        }
+        w.memory -= rec.memory()
+        err := w.spill.store(rec)
+        w.memory += rec.memory()
+        if err != nil {
+            return fmt.Errorf("Failed to spill sequences over the memory limit of %d bytes: %w", w.maxMemory, err)
+        }
+    }
+
+    return nil
+}
+
+// CacheSize returns an estimate of the heap bytes held by the caches of r:
+// the file index, sequence lengths, block tables of the sequences read and
+// the read buffer. Packed bases are not cached.
+func (r *Reader) CacheSize() (int64) {
+    var total int64
+    for _, rec := range r.records {
+        total += rec.memory()
+    }
+    total += int64(len(r.tables))*recordMemory
+    total += int64(len(r.index)+len(r.lengths))*recordMemory
+    total += int64(cap(r.buf))
+
+    return total
+}
@@ -0,0 +1,127 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "strings"
+)
+
+func TestEstimatedMemory(t *testing.T) {
+    w := NewWriter()
+    empty := w.EstimatedMemory()
+
+    w.Add("chr1", strings.Repeat("ACGT", 1000)+"NNNN")
+    used := w.EstimatedMemory()
+    if used < empty+1000 {
+        t.Errorf("Estimate %d does not count packed bases", used)
+    }
+
+    w.StartSequence("chr2")
+    w.AppendChunk(strings.Repeat("ACGT", 1000))
+    if w.EstimatedMemory() < used+1000 {
+        t.Errorf("Estimate does not count the sequence being built")
+    }
+    w.EndSequence()
+
+    w = NewWriter(SpillToDisk(TempStorage{Dir: t.TempDir()}))
+    defer w.Close()
+    w.Add("chr1", strings.Repeat("ACGT", 1000))
+    if w.EstimatedMemory() >= used {
+        t.Errorf("Estimate counts spilled bases")
+    }
+}
+
+func TestMaxMemory(t *testing.T) {
+    dir := t.TempDir()
+    w := NewWriter(MaxMemory(1500, TempStorage{Dir: dir}))
+    good := NewWriter()
+
+    seqs := []string{strings.Repeat("ACGT", 500), strings.Repeat("acgt", 500)+"NNNN", strings.Repeat("GATTACA", 500)}
+    for i, s := range seqs {
+        name := string(rune('a'+i))
+        w.Add(name, s)
+        good.Add(name, s)
+        if i == 0 && (w.spill != nil || countFiles(t, dir) != 0) {
+            t.Errorf("Spilled under the memory limit")
+        }
+    }
+    if w.spill == nil || countFiles(t, dir) != 1 {
+        t.Fatalf("Not spilled over the memory limit")
+    }
+    if w.EstimatedMemory() > 1500 {
+        t.Errorf("Still over the memory limit: %d", w.EstimatedMemory())
+    }
+
+    var got, want bytes.Buffer
+    err := w.WriteTo(&got)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    good.WriteTo(&want)
+    if !bytes.Equal(got.Bytes(), want.Bytes()) {
+        t.Errorf("Output differs after spilling")
+    }
+
+    w.Close()
+    if countFiles(t, dir) != 0 {
+        t.Errorf("Temp file not removed on close")
+    }
+}
+
+// The running estimate must match summing every record
+func TestEstimatedMemoryTotal(t *testing.T) {
+    summed := func(w *Writer) (int64) {
+        var total int64
+        for _, rec := range w.records {
+            total += rec.memory()
+        }
+        return total
+    }
+
+    w := NewWriter(MaxMemory(3000, TempStorage{Dir: t.TempDir()}))
+    defer w.Close()
+    w.Add("chr1", strings.Repeat("ACGT", 200)+"NNNN")
+    w.Add("chr1", strings.Repeat("ACGTacgt", 100))
+    w.SetMask("chr1", Blocks{&Block{Start: 0, Length: 10}, &Block{Start: 20, Length: 10}})
+    if w.memory != summed(w) {
+        t.Errorf("Running estimate %d != %d", w.memory, summed(w))
+    }
+
+    for i := 0; i < 20; i++ {
+        w.Add(string(rune('a'+i)), strings.Repeat("GATTACA", 100))
+    }
+    if w.spill == nil {
+        t.Fatalf("Not spilled over the memory limit")
+    }
+    if w.memory != summed(w) {
+        t.Errorf("Running estimate %d != %d after spilling", w.memory, summed(w))
+    }
+}
+
+func TestCacheSize(t *testing.T) {
+    w := NewWriter()
+    w.Add("chr1", "ACGTNNNNacgt")
+    w.Add("chr2", strings.Repeat("GATTACA", 100))
+    var out bytes.Buffer
+    w.WriteTo(&out)
+
+    r, err := NewReader(bytes.NewReader(out.Bytes()))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    before := r.CacheSize()
+    for _, name := range r.Names() {
+        _, err = r.Read(name)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+    }
+    if r.CacheSize() <= before {
+        t.Errorf("Cache size %d did not grow from %d", r.CacheSize(), before)
+    }
+}
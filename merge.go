@@ -0,0 +1,117 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "io"
+    "encoding/json"
+    "fmt"
+)
+
+// MergeSource is a genome or a set of decoys to merge. Exactly one of TwoBit
+// or Fasta is set.
+type MergeSource struct {
+    Label   string    // name of the source in errors and the manifest
+    TwoBit  *Reader
+    Fasta   io.Reader
+}
+
+// MergeEntry describes one sequence of a merged file
+type MergeEntry struct {
+    Name    string `json:"name"`
+    Source  string `json:"source"`
+    Length  int    `json:"length"`
+}
+
+// MergeManifest lists the sequences of a merged file in file order with the
+// source each came from
+type MergeManifest struct {
+    Sequences  []*MergeEntry `json:"sequences"`
+}
+
+// Write the manifest in JSON format to out
+func (m *MergeManifest) Write(out io.Writer) (error) {
+    enc := json.NewEncoder(out)
+    enc.SetIndent("", "  ")
+    return enc.Encode(m)
+}
+
+// Merge writes the sequences of all sources, in order, to a single 2bit file,
+// for example a primary assembly followed by decoys (EBV, phiX, spike-ins).
+// 2bit sources are copied without decoding. A sequence name occurring in more
+// than one source is an error.
+func Merge(dst io.Writer, sources []MergeSource, opts ...WriterOption) (*MergeManifest, error) {
+    w := NewWriter(opts...)
+    manifest := &MergeManifest{Sequences: make([]*MergeEntry, 0)}
+    origin := make(map[string]string)
+
+    claim := func(name, label string) (error) {
+        if first, ok := origin[name]; ok {
+            return fmt.Errorf("Sequence %s from %s collides with %s", name, label, first)
+        }
+        origin[name] = label
+        return nil
+    }
+
+    for _, src := range sources {
+        if (src.TwoBit == nil) == (src.Fasta == nil) {
+            return nil, fmt.Errorf("Source %s must have exactly one of TwoBit or Fasta", src.Label)
+        }
+
+        if src.TwoBit != nil {
+            for _, name := range src.TwoBit.namesByOffset() {
+                err := claim(name, src.Label)
+                if err != nil {
+                    return nil, err
+                }
+                err = w.copySequence(src.TwoBit, name, name)
+                if err != nil {
+                    return nil, err
+                }
+            }
+            continue
+        }
+
+        var name, header string
+        start := func(n, h string) (error) {
+            name, header = n, h
+            err := claim(name, src.Label)
+            if err != nil {
+                return err
+            }
+            return w.StartSequence(name)
+        }
+        chunk := func(seq []byte) (error) {
+            return w.AppendChunk(string(seq))
+        }
+        end := func() (error) {
+            err := w.EndSequence()
+            if err != nil {
+                return err
+            }
+            w.describe(name, header)
+            return nil
+        }
+        err := readFasta(src.Fasta, start, chunk, end)
+        if err != nil {
+            return nil, fmt.Errorf("Failed to read %s: %w", src.Label, err)
+        }
+    }
+
+    for _, name := range w.order {
+        manifest.Sequences = append(manifest.Sequences, &MergeEntry{
+            Name: name,
+            Source: origin[name],
+            Length: int(w.records[name].dnaSize),
+        })
+    }
+
+    err := w.WriteTo(dst)
+    if err != nil {
+        return nil, err
+    }
+
+    return manifest, nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "strings"
+)
+
+func TestMerge(t *testing.T) {
+    w := NewWriter()
+    w.Add("chr1", "ACGTacgtNN")
+    w.Add("chr2", "GGGG")
+    var primary bytes.Buffer
+    w.WriteTo(&primary)
+    genome, _ := NewReader(bytes.NewReader(primary.Bytes()))
+
+    decoys := ">chrEBV Epstein-Barr\nACGTTGCA\n>phiX\nGAGTTTTATC\n"
+
+    var out bytes.Buffer
+    manifest, err := Merge(&out, []MergeSource{
+        {Label: "hg38", TwoBit: genome},
+        {Label: "decoys.fa", Fasta: strings.NewReader(decoys)},
+    })
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    tb, err := NewReader(bytes.NewReader(out.Bytes()))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if order := strings.Join(tb.namesByOffset(), ","); order != "chr1,chr2,chrEBV,phiX" {
+        t.Errorf("Invalid merged order: %s", order)
+    }
+
+    seq, _ := tb.Read("chr1")
+    if string(seq) != "ACGTacgtNN" {
+        t.Errorf("Invalid merged sequence: %s", seq)
+    }
+    seq, _ = tb.Read("phiX")
+    if string(seq) != "GAGTTTTATC" {
+        t.Errorf("Invalid merged decoy: %s", seq)
+    }
+
+    if len(manifest.Sequences) != 4 || manifest.Sequences[2].Source != "decoys.fa" || manifest.Sequences[2].Length != 8 {
+        t.Errorf("Invalid manifest: %+v", manifest.Sequences)
+    }
+
+    _, err = Merge(&bytes.Buffer{}, []MergeSource{
+        {Label: "hg38", TwoBit: genome},
+        {Label: "spikes.fa", Fasta: strings.NewReader(">chr2\nACGT\n")},
+    })
+    if err == nil || !strings.Contains(err.Error(), "collides with hg38") {
+        t.Errorf("Expected collision error, got: %v", err)
+    }
+}
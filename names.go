@@ -0,0 +1,139 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "errors"
+    "fmt"
+    "path"
+    "regexp"
+    "strings"
+)
+
+// NameFilter retains only index entries whose name satisfies keep when the
+// file is opened. Other sequences are skipped while parsing the index and
+// can not be read, reducing memory and open time for files with very many
+// sequences when only a few are needed.
+func NameFilter(keep func(name string) bool) (ReadOption) {
+    return func(r *Reader) (error) {
+        if keep == nil {
+            return fmt.Errorf("Invalid nil name filter")
+        }
+        r.filter = keep
+        return nil
+    }
+}
+
+// Only is a NameFilter retaining the given sequence names
+func Only(names ...string) (ReadOption) {
+    set := make(map[string]bool, len(names))
+    for _, name := range names {
+        set[name] = true
+    }
+
+    return NameFilter(func(name string) bool {
+        return set[name]
+    })
+}
+
+// LazyIndex defers reading the file index until a sequence is looked up.
+// Entries are then read in order only as far as the requested name and
+// cached, making open nearly free for quick single sequence extractions.
+// Methods which need every name, such as Names, read the rest of the index.
+func LazyIndex() (ReadOption) {
+    return func(r *Reader) (error) {
+        r.lazy = true
+        return nil
+    }
+}
+
+// Returns the names of sequences matching the shell pattern in file order.
+// The pattern syntax is that of path.Match, for example "chr*_alt" or
+// "chr[0-9]". A '*' does not match a '/' in a sequence name.
+func (r *Reader) Glob(pattern string) ([]string, error) {
+    _, err := path.Match(pattern, "")
+    if err != nil {
+        return nil, err
+    }
+
+    names := make([]string, 0)
+    for _, name := range r.namesByOffset() {
+        if ok, _ := path.Match(pattern, name); ok {
+            names = append(names, name)
+        }
+    }
+
+    return names, nil
+}
+
+// Returns the names of sequences starting with prefix in file order
+func (r *Reader) Prefix(prefix string) ([]string) {
+    names := make([]string, 0)
+    for _, name := range r.namesByOffset() {
+        if strings.HasPrefix(name, prefix) {
+            names = append(names, name)
+        }
+    }
+
+    return names
+}
+
+// ErrInvalidName is returned when a Writer refuses a sequence name
+var ErrInvalidName = errors.New("twobit: invalid sequence name")
+
+// NameValidator checks a sequence name added to a Writer, returning an error
+// describing why the name is not allowed
+type NameValidator func(name string) (error)
+
+// NamePattern returns a NameValidator accepting names matching re. Patterns
+// should normally be anchored with ^ and $.
+func NamePattern(re *regexp.Regexp) (NameValidator) {
+    return func(name string) (error) {
+        if !re.MatchString(name) {
+            return fmt.Errorf("%s does not match %s", name, re)
+        }
+        return nil
+    }
+}
+
+// Preset name validators
+var (
+    // UCSCNames accepts names safe for UCSC tools and track hubs: letters,
+    // digits, underscore, dot and dash, not starting with a dot or dash
+    UCSCNames = NamePattern(regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.\-]*$`))
+
+    // NCBIAccessions accepts versioned NCBI/INSDC accessions such as
+    // NC_000001.11 or CM000663.2
+    NCBIAccessions = NamePattern(regexp.MustCompile(`^[A-Z]{1,6}_?[0-9]+\.[0-9]+$`))
+
+    // PermissiveNames accepts any printable ASCII name without whitespace,
+    // anything that survives a round trip through a FASTA header
+    PermissiveNames = NamePattern(regexp.MustCompile(`^[!-~]+$`))
+)
+
+// ValidateNames sets a NameValidator run on the name of every sequence added
+// to the Writer. Refused names are reported as ErrInvalidName. The length
+// limit of the format is always enforced.
+func ValidateNames(v NameValidator) (WriterOption) {
+    return func(w *Writer) {
+        w.validName = v
+    }
+}
+
+// Check that name can be added to w
+func (w *Writer) checkName(name string) (error) {
+    if len(name) > MAX_NAME_LEN {
+        return fmt.Errorf("Name string cannot be longer than %d characters", MAX_NAME_LEN)
+    }
+
+    if w.validName != nil {
+        err := w.validName(name)
+        if err != nil {
+            return fmt.Errorf("%w: %s", ErrInvalidName, err)
+        }
+    }
+
+    return nil
+}
@@ -0,0 +1,191 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "errors"
+    "fmt"
+    "sort"
+    "strings"
+)
+
+func TestGlob(t *testing.T) {
+    tb := newTestReader(t, map[string]string{
+        "chr1": "ACGT",
+        "chr2": "ACGT",
+        "chr1_KI270706v1_random": "ACGT",
+        "chr1_KI270762v1_alt": "ACGT",
+        "chrUn_GL000195v1": "ACGT",
+    })
+
+    tests := []struct {
+        pattern  string
+        names    string
+    }{
+        {"chr*_alt", "chr1_KI270762v1_alt"},
+        {"chr?", "chr1,chr2"},
+        {"chr[2-9]", "chr2"},
+        {"chrUn*", "chrUn_GL000195v1"},
+        {"scaffold*", ""},
+    }
+
+    for _, test := range tests {
+        names, err := tb.Glob(test.pattern)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        sort.Strings(names)
+        if strings.Join(names, ",") != test.names {
+            t.Errorf("Invalid glob %s: %v != %s", test.pattern, names, test.names)
+        }
+    }
+
+    _, err := tb.Glob("chr[")
+    if err == nil {
+        t.Errorf("Expected error for bad pattern")
+    }
+
+    names := tb.Prefix("chr1")
+    if len(names) != 3 {
+        t.Errorf("Invalid prefix match: %v", names)
+    }
+}
+
+func TestNameFilter(t *testing.T) {
+    w := NewWriter()
+    w.Add("chr1", "ACGT")
+    w.Add("chr2", "GGCC")
+    w.Add("chr1_alt", "TTTT")
+    var out bytes.Buffer
+    w.WriteTo(&out)
+
+    tb, err := NewReader(bytes.NewReader(out.Bytes()), Only("chr2", "chrX"))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    names := tb.Names()
+    if len(names) != 1 || names[0] != "chr2" || tb.Count() != 3 {
+        t.Errorf("Invalid filtered names: %v count %d", names, tb.Count())
+    }
+
+    seq, err := tb.Read("chr2")
+    if err != nil || string(seq) != "GGCC" {
+        t.Errorf("Invalid filtered read: %s %v", seq, err)
+    }
+
+    _, err = tb.Read("chr1")
+    if err == nil {
+        t.Errorf("Expected error reading excluded sequence")
+    }
+
+    tb, err = NewReader(bytes.NewReader(out.Bytes()), NameFilter(func(name string) bool {
+        return !strings.HasSuffix(name, "_alt")
+    }))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if len(tb.Names()) != 2 {
+        t.Errorf("Invalid filtered names: %v", tb.Names())
+    }
+}
+
+func TestLazyIndex(t *testing.T) {
+    w := NewWriter()
+    for i := 0; i < 50; i++ {
+        w.Add(fmt.Sprintf("chr%d", i), "ACGTNNacgt")
+    }
+    var out bytes.Buffer
+    w.WriteTo(&out)
+
+    full, err := NewReader(bytes.NewReader(out.Bytes()))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    first := full.namesByOffset()[0]
+
+    c := &countingReadSeeker{ReadSeeker: bytes.NewReader(out.Bytes())}
+    tb, err := NewReader(c, LazyIndex(), BufferSize(0))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if c.reads != 1 {
+        t.Errorf("Lazy open read more than the header: %d reads", c.reads)
+    }
+    if len(tb.index) != 0 {
+        t.Errorf("Lazy open parsed index entries: %d", len(tb.index))
+    }
+
+    seq, err := tb.Read(first)
+    if err != nil || string(seq) != "ACGTNNacgt" {
+        t.Errorf("Invalid lazy read: %s %v", seq, err)
+    }
+    if len(tb.index) != 1 {
+        t.Errorf("Expected partial index after lookup: %d entries", len(tb.index))
+    }
+
+    _, err = tb.Read("chrX")
+    if err == nil {
+        t.Errorf("Expected error for missing sequence")
+    }
+
+    if len(tb.Names()) != 50 {
+        t.Errorf("Invalid number of names: %d", len(tb.Names()))
+    }
+
+    tb, err = NewReader(bytes.NewReader(out.Bytes()), LazyIndex(), Only("chr3"))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    _, err = tb.Read("chr2")
+    if err == nil {
+        t.Errorf("Expected error for filtered sequence")
+    }
+    if names := tb.Names(); len(names) != 1 || names[0] != "chr3" {
+        t.Errorf("Invalid filtered lazy names: %v", names)
+    }
+}
+
+func TestValidateNames(t *testing.T) {
+    tests := []struct {
+        v      NameValidator
+        good   []string
+        bad    []string
+    }{
+        {UCSCNames, []string{"chr1", "chrUn_KI270302v1", "HLA-A.1"}, []string{"HLA-A*01:01", "-chr1", ".hidden", "chr 1", ""}},
+        {NCBIAccessions, []string{"NC_000001.11", "CM000663.2", "AC012345.1"}, []string{"chr1", "NC_000001", "nc_000001.1"}},
+        {PermissiveNames, []string{"HLA-A*01:01:01:01", "chr1|x"}, []string{"chr 1", "chr\t1", ""}},
+    }
+
+    for _, tt := range tests {
+        for _, name := range tt.good {
+            w := NewWriter(ValidateNames(tt.v))
+            if err := w.Add(name, "ACGT"); err != nil {
+                t.Errorf("Valid name %q refused: %s", name, err)
+            }
+        }
+        for _, name := range tt.bad {
+            w := NewWriter(ValidateNames(tt.v))
+            if err := w.Add(name, "ACGT"); !errors.Is(err, ErrInvalidName) {
+                t.Errorf("Invalid name %q accepted: %v", name, err)
+            }
+        }
+    }
+
+    w := NewWriter(ValidateNames(func(name string) (error) {
+        if !strings.HasPrefix(name, "ORG_") {
+            return fmt.Errorf("missing ORG_ prefix")
+        }
+        return nil
+    }))
+    if err := w.StartSequence("chr1"); !errors.Is(err, ErrInvalidName) {
+        t.Errorf("Callback validator not applied to StartSequence: %v", err)
+    }
+    if err := w.StartSequence("ORG_chr1"); err != nil {
+        t.Errorf("Valid name refused: %s", err)
+    }
+}
@@ -0,0 +1,91 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "errors"
+    "fmt"
+)
+
+// ErrNotNucleotide is returned when a sequence added to a Writer looks like
+// protein rather than DNA
+var ErrNotNucleotide = errors.New("twobit: not a nucleotide sequence")
+
+// Sequences with a larger fraction of amino acid letters are refused with
+// ErrNotNucleotide. Protein sequences have about 35%.
+const MAX_AMINO_ACID_FRACTION = 0.1
+
+// Number of bases of a sequence built with StartSequence (or imported from
+// FASTA) which are checked before the rest is packed
+const NUCLEOTIDE_SAMPLE = 10000
+
+// aminoAcid marks the letters used for amino acids but not nucleotides. The
+// IUPAC ambiguity codes, U and X (used for hard masking) are not included.
+var aminoAcid [256]bool
+
+func init() {
+    for _, c := range []byte("EFIJLOPQZefijlopqz") {
+        aminoAcid[c] = true
+    }
+}
+
+// Composition counts the kinds of letters in a sequence
+type Composition struct {
+    Length     int
+    N          int
+    Other      int // not ACGTN or amino acid letters, such as IUPAC ambiguity codes
+    AminoAcid  int // letters only used for amino acids: E, F, I, J, L, O, P, Q and Z
+}
+
+// Returns the fraction of the sequence in letters of count
+func (c Composition) fraction(count int) (float64) {
+    if c.Length == 0 {
+        return 0
+    }
+
+    return float64(count)/float64(c.Length)
+}
+
+func (c Composition) String() (string) {
+    acgt := c.Length-c.N-c.Other-c.AminoAcid
+    return fmt.Sprintf("ACGT %.1f%%, N %.1f%%, other nucleotide codes %.1f%%, amino acids %.1f%%",
+        100*c.fraction(acgt), 100*c.fraction(c.N), 100*c.fraction(c.Other), 100*c.fraction(c.AminoAcid))
+}
+
+// AllowNonNucleotide turns off the check refusing sequences which look like
+// protein. Letters other than ACGTN are then stored as T.
+func AllowNonNucleotide() (WriterOption) {
+    return func(w *Writer) {
+        w.allowAmino = true
+    }
+}
+
+// Refuse sequence name with composition c if it looks like protein
+func (w *Writer) checkNucleotide(name string, c Composition) (error) {
+    if w.allowAmino || c.fraction(c.AminoAcid) <= MAX_AMINO_ACID_FRACTION {
+        return nil
+    }
+
+    return fmt.Errorf("%w: %s looks like protein, %d of %d letters are only used for amino acids (%s). "+
+        "2bit files store DNA: IUPAC ambiguity codes such as R and Y are accepted and stored as T, "+
+        "use AllowNonNucleotide to store other letters as T too",
+        ErrNotNucleotide, name, c.AminoAcid, c.Length, c)
+}
+
+// Returns the composition of the bases added to b
+func (b *seqBuilder) composition() (Composition) {
+    return Composition{Length: b.size, N: b.nCount, Other: b.other-b.aminoAcid, AminoAcid: b.aminoAcid}
+}
+
+// Check the first NUCLEOTIDE_SAMPLE bases of the sequence built by b, once
+// they have been added. With final set, shorter sequences are checked too.
+func (w *Writer) sampleNucleotide(b *seqBuilder, final bool) (error) {
+    if b.sampled || (!final && b.size < NUCLEOTIDE_SAMPLE) {
+        return nil
+    }
+    b.sampled = true
+
+    return w.checkNucleotide(b.name, b.composition())
+}
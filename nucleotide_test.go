@@ -0,0 +1,69 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "errors"
+    "strings"
+)
+
+// start of human insulin
+const protein = "MALWMRLLPLLALLALWGPDPAAAFVNQHLCGSHLVEALYLVCGERGFFYTPKTRREAEDLQVGQVELGGGPGAGSLQPLALEGSLQKRGIVEQCCTSICSLYQLENYCN"
+
+func TestNucleotideCheck(t *testing.T) {
+    w := NewWriter()
+    err := w.Add("insulin", protein)
+    if !errors.Is(err, ErrNotNucleotide) {
+        t.Fatalf("Protein accepted: %v", err)
+    }
+    if !strings.Contains(err.Error(), "amino acids") || !strings.Contains(err.Error(), "AllowNonNucleotide") {
+        t.Errorf("Unhelpful error: %s", err)
+    }
+
+    // IUPAC codes and hard masking are nucleotide
+    for _, seq := range []string{"ACGTRYKMSWBDHVNacgt", "ACGTXXXXXXXXXXACGT", "ACGUACGU", ""} {
+        err := w.Add("dna", seq)
+        if err != nil {
+            t.Errorf("Nucleotide sequence %s refused: %s", seq, err)
+        }
+    }
+
+    w = NewWriter(AllowNonNucleotide())
+    err = w.Add("insulin", protein)
+    if err != nil {
+        t.Errorf("Protein refused with AllowNonNucleotide: %s", err)
+    }
+}
+
+func TestNucleotideCheckEarly(t *testing.T) {
+    w := NewWriter()
+    w.StartSequence("proteins")
+    var err error
+    chunks := 0
+    for err == nil && chunks < 1000 {
+        err = w.AppendChunk(protein)
+        chunks++
+    }
+    if !errors.Is(err, ErrNotNucleotide) {
+        t.Fatalf("Protein accepted: %v", err)
+    }
+    if chunks*len(protein) > 2*NUCLEOTIDE_SAMPLE {
+        t.Errorf("Protein detected after %d bases", chunks*len(protein))
+    }
+    if err := w.EndSequence(); err == nil {
+        t.Errorf("Refused sequence still started")
+    }
+
+    err = ImportFasta(strings.NewReader(">dna\nACGT\n>insulin\n"+protein+"\n"), NewWriter())
+    if !errors.Is(err, ErrNotNucleotide) {
+        t.Errorf("Protein FASTA accepted: %v", err)
+    }
+
+    err = ImportFastaParallel(strings.NewReader(">dna\nACGT\n>insulin\n"+protein+"\n"), NewWriter(), 2)
+    if !errors.Is(err, ErrNotNucleotide) {
+        t.Errorf("Protein FASTA accepted by parallel import: %v", err)
+    }
+}
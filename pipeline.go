@@ -0,0 +1,196 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "crypto/sha256"
+    "fmt"
+    "hash"
+    "io"
+    "math"
+)
+
+// Number of bases in each piece of a sequence packed by one worker of
+// ImportFastaParallel. Must be a multiple of BASES_PER_BYTE.
+const IMPORT_PIECE_SIZE = 1<<22
+
+// pieceSize is IMPORT_PIECE_SIZE, smaller in tests to join many pieces
+var pieceSize = IMPORT_PIECE_SIZE
+
+// piece is part of a sequence passed from the reader to a packer and on to
+// the writer stage of ImportFastaParallel
+type piece struct {
+    name    string    // sequence name, set on the first piece
+    header  string    // FASTA header line, set on the first piece
+    first   bool
+    last    bool
+    seq     []byte    // bases read
+    b       *seqBuilder
+    digest  hash.Hash // digest of the whole sequence, set on the last piece
+    done    chan struct{} // closed once b is packed
+}
+
+// Pack the bases of p into a builder of its own
+func (p *piece) pack() {
+    p.b = &seqBuilder{rec: &seqRecord{nBlocks: make(Blocks, 0), mBlocks: make(Blocks, 0)}}
+    p.b.append(string(p.seq))
+    p.seq = nil
+    close(p.done)
+}
+
+// Append the sequence packed by p, which must start on a byte boundary
+func (b *seqBuilder) appendPiece(p *seqBuilder) {
+    for _, blk := range p.rec.nBlocks {
+        b.rec.nBlocks = extendBlocks(b.rec.nBlocks, b.size+blk.Start, blk.Length)
+    }
+    for _, blk := range p.rec.mBlocks {
+        b.rec.mBlocks = extendBlocks(b.rec.mBlocks, b.size+blk.Start, blk.Length)
+    }
+    b.rec.sequence = append(b.rec.sequence, p.rec.sequence...)
+    b.partial = p.partial
+    b.nPartial = p.nPartial
+    b.size += p.size
+    b.nCount += p.nCount
+    b.other += p.other
+    b.aminoAcid += p.aminoAcid
+}
+
+// ImportFastaParallel adds every sequence in the FASTA read from in to w as
+// ImportFasta, using workers goroutines to pack. A reader stage parses the
+// FASTA and splits sequences into pieces of IMPORT_PIECE_SIZE bases, packer
+// stages pack the pieces concurrently and a writer stage joins them and adds
+// the sequences to w in input order, so large genomes (and single large
+// chromosomes) are converted using multiple cores. At most about 2*workers
+// pieces are held in memory at once. w must not be used by other goroutines
+// until ImportFastaParallel returns.
+func ImportFastaParallel(in io.Reader, w *Writer, workers int) (error) {
+    if w.closed {
+        return ErrClosed
+    }
+    if w.building != nil {
+        return fmt.Errorf("Sequence %s was started but not ended", w.building.name)
+    }
+    if workers < 1 {
+        workers = 1
+    }
+
+    tasks := make(chan *piece, workers)
+    ordered := make(chan *piece, 2*workers)
+    stop := make(chan struct{})
+    var readErr error
+
+    // packers
+    for i := 0; i < workers; i++ {
+        go func() {
+            for p := range tasks {
+                p.pack()
+            }
+        }()
+    }
+
+    // reader
+    go func() {
+        defer close(tasks)
+        defer close(ordered)
+
+        var cur *piece
+        var digest hash.Hash
+        size := 0
+        send := func(last bool) (error) {
+            cur.last = last
+            if last {
+                cur.digest = digest
+            }
+            select {
+            case ordered <- cur:
+            case <-stop:
+                return fmt.Errorf("Import stopped")
+            }
+            tasks <- cur
+            cur = &piece{done: make(chan struct{})}
+            return nil
+        }
+
+        start := func(name, header string) (error) {
+            cur = &piece{name: name, header: header, first: true, done: make(chan struct{})}
+            digest = nil
+            if w.dedup != dedupOff {
+                digest = sha256.New()
+            }
+            size = 0
+            return nil
+        }
+
+        chunk := func(seq []byte) (error) {
+            if uint64(size)+uint64(len(seq)) > math.MaxUint32 {
+                return fmt.Errorf("Sequence %s is longer than %d bases", cur.name, uint32(math.MaxUint32))
+            }
+            size += len(seq)
+            if digest != nil {
+                digest.Write(seq)
+            }
+
+            for len(seq) > 0 {
+                n := pieceSize-len(cur.seq)
+                if n > len(seq) {
+                    n = len(seq)
+                }
+                cur.seq = append(cur.seq, seq[:n]...)
+                seq = seq[n:]
+                if len(cur.seq) == pieceSize {
+                    err := send(false)
+                    if err != nil {
+                        return err
+                    }
+                }
+            }
+            return nil
+        }
+
+        end := func() (error) {
+            return send(true)
+        }
+
+        readErr = readFasta(in, start, chunk, end)
+    }()
+
+    // writer
+    var b *seqBuilder
+    var header string
+    var err error
+    for p := range ordered {
+        if err != nil {
+            continue
+        }
+
+        <-p.done
+        if p.first {
+            header = p.header
+            b = &seqBuilder{name: p.name, rec: &seqRecord{nBlocks: make(Blocks, 0), mBlocks: make(Blocks, 0)}}
+        }
+        b.appendPiece(p.b)
+        err = w.sampleNucleotide(b, false)
+        if err == nil && p.last {
+            b.digest = p.digest
+            err = w.checkName(b.name)
+            if err == nil {
+                err = w.endBuilder(b)
+            }
+            if err == nil {
+                w.describe(b.name, header)
+            }
+        }
+        if err != nil {
+            // stop the reader and drain the pieces already queued
+            close(stop)
+        }
+    }
+
+    if err != nil {
+        return err
+    }
+
+    return readErr
+}
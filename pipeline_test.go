@@ -0,0 +1,75 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "errors"
+    "fmt"
+    "math/rand"
+    "strings"
+)
+
+func TestImportFastaParallel(t *testing.T) {
+    defer func(n int) { pieceSize = n }(pieceSize)
+    pieceSize = 8
+
+    // runs of N and masked bases cross the piece boundaries
+    rng := rand.New(rand.NewSource(1))
+    var long strings.Builder
+    for long.Len() < 1000 {
+        long.WriteString(strings.Repeat(string("ACGTNacgtn"[rng.Intn(10)]), 1+rng.Intn(12)))
+    }
+    fasta := ">chr1 first chromosome\r\nACGTN\r\nacgt\r\n\n>chr2\n"+long.String()+"\n>empty\n>chr3\nACGTACGT\n>chr4\nGG"
+
+    for _, workers := range []int{1, 4} {
+        var serial, parallel bytes.Buffer
+        w := NewWriter()
+        err := ImportFasta(strings.NewReader(fasta), w)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        w.WriteTo(&serial)
+
+        w = NewWriter()
+        err = ImportFastaParallel(strings.NewReader(fasta), w, workers)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        w.WriteTo(&parallel)
+
+        if !bytes.Equal(serial.Bytes(), parallel.Bytes()) {
+            t.Errorf("Parallel import with %d workers differs from ImportFasta", workers)
+        }
+    }
+}
+
+func TestImportFastaParallelErrors(t *testing.T) {
+    defer func(n int) { pieceSize = n }(pieceSize)
+    pieceSize = 4
+
+    err := ImportFastaParallel(strings.NewReader("ACGT\n>chr1\nACGT\n"), NewWriter(), 2)
+    if err == nil {
+        t.Errorf("Expected error for sequence before header")
+    }
+
+    // the writer stage fails on the first duplicate while the reader still
+    // has sequences to send
+    var fasta strings.Builder
+    for i := 0; i < 50; i++ {
+        fmt.Fprintf(&fasta, ">chr%d\nACGTACGTACGT\n", i)
+    }
+    err = ImportFastaParallel(strings.NewReader(fasta.String()), NewWriter(RefuseDuplicates()), 2)
+    if !errors.Is(err, ErrDuplicate) {
+        t.Errorf("Expected duplicate error: %v", err)
+    }
+
+    w := NewWriter(FlagDuplicates())
+    err = ImportFastaParallel(strings.NewReader(fasta.String()), w, 2)
+    if err != nil || len(w.Duplicates()) != 49 || w.Duplicates()["chr7"] != "chr0" {
+        t.Errorf("Invalid duplicates: %v %v", err, w.Duplicates())
+    }
+}
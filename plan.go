@@ -0,0 +1,120 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "io"
+    "math"
+)
+
+// SequenceSpec describes a sequence to be written: its name, length and the
+// number of N and mask blocks
+type SequenceSpec struct {
+    Name     string
+    Length   int64
+    NBlocks  int
+    MBlocks  int
+}
+
+// SequenceSize is the predicted size of one sequence record
+type SequenceSize struct {
+    Name         string
+    RecordSize   int64
+    Ratio        float64 // record size relative to one byte per base
+}
+
+// SizePlan is the predicted size of a 2bit file
+type SizePlan struct {
+    Version      int   // VERSION if all records fit 32-bit offsets, else VERSION_LONG
+    IndexSize    int64
+    RecordsSize  int64
+    Total        int64 // header, index and records
+    Sequences    []*SequenceSize
+}
+
+// Return the size of a record for spec
+func (spec *SequenceSpec) recordSize() (int64) {
+    size := int64(RECORD_DNA_SIZE_LEN + 2*RECORD_BLOCK_COUNT_LEN + RECORD_RESERVED_LEN)
+    size += int64(2 * RECORD_BLOCK_FIELD_LEN * spec.NBlocks)
+    size += int64(2 * RECORD_BLOCK_FIELD_LEN * spec.MBlocks)
+    size += packedSize64(spec.Length)
+
+    return size
+}
+
+// PlanSize predicts the size of the 2bit file holding seqs, written in the
+// given order without padding, before writing it. The smallest version able
+// to address every record is chosen.
+func PlanSize(seqs []SequenceSpec) (*SizePlan) {
+    plan := &SizePlan{Version: VERSION, Sequences: make([]*SequenceSize, 0, len(seqs))}
+
+    lastRecord := int64(0)
+    for i := range seqs {
+        spec := &seqs[i]
+        size := spec.recordSize()
+        s := &SequenceSize{Name: spec.Name, RecordSize: size}
+        if spec.Length > 0 {
+            s.Ratio = float64(size) / float64(spec.Length)
+        }
+        plan.Sequences = append(plan.Sequences, s)
+
+        plan.IndexSize += int64(INDEX_NAME_SIZE_LEN + len(spec.Name) + INDEX_OFFSET_LEN)
+        lastRecord = plan.RecordsSize
+        plan.RecordsSize += size
+    }
+
+    if HEADER_SIZE+plan.IndexSize+lastRecord > math.MaxUint32 {
+        plan.Version = VERSION_LONG
+        plan.IndexSize += int64(len(seqs) * (INDEX_OFFSET_LEN_LONG-INDEX_OFFSET_LEN))
+    }
+
+    plan.Total = HEADER_SIZE+plan.IndexSize+plan.RecordsSize
+
+    return plan
+}
+
+// ScanFasta returns the SequenceSpec of each sequence in the FASTA read from
+// in, counting blocks as Add would without packing the sequence
+func ScanFasta(in io.Reader) ([]SequenceSpec, error) {
+    specs := make([]SequenceSpec, 0)
+    var cur *SequenceSpec
+    inN, inMask := false, false
+
+    start := func(name, header string) (error) {
+        specs = append(specs, SequenceSpec{Name: name})
+        cur = &specs[len(specs)-1]
+        inN, inMask = false, false
+        return nil
+    }
+
+    chunk := func(seq []byte) (error) {
+        for _, c := range seq {
+            isN := c == 'N' || c == 'n'
+            if isN && !inN {
+                cur.NBlocks++
+            }
+            inN = isN
+
+            isMask := c >= 'a' && c <= 'z'
+            if isMask && !inMask {
+                cur.MBlocks++
+            }
+            inMask = isMask
+        }
+        cur.Length += int64(len(seq))
+        return nil
+    }
+
+    end := func() (error) {
+        return nil
+    }
+
+    err := readFasta(in, start, chunk, end)
+    if err != nil {
+        return nil, err
+    }
+
+    return specs, nil
+}
@@ -0,0 +1,46 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "strings"
+)
+
+func TestPlanSize(t *testing.T) {
+    fasta := ">chr1\nACGTNNNN\nnnacgtAC\n>chr2\nGGGG\n>empty\n>chr3\nacgtNNACGT\n"
+
+    specs, err := ScanFasta(strings.NewReader(fasta))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if len(specs) != 4 || specs[0].Length != 16 || specs[0].NBlocks != 1 || specs[0].MBlocks != 1 || specs[3].MBlocks != 1 {
+        t.Errorf("Invalid specs: %+v", specs)
+    }
+
+    plan := PlanSize(specs)
+
+    w := NewWriter()
+    ImportFasta(strings.NewReader(fasta), w)
+    var out bytes.Buffer
+    w.WriteTo(&out)
+
+    if plan.Total != int64(out.Len()) || plan.Version != VERSION {
+        t.Errorf("Invalid plan: %d != %d (version %d)", plan.Total, out.Len(), plan.Version)
+    }
+    if plan.Sequences[1].RecordSize != 17 || plan.Sequences[1].Ratio != 17.0/4 {
+        t.Errorf("Invalid sequence size: %+v", plan.Sequences[1])
+    }
+
+    specs = nil
+    for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+        specs = append(specs, SequenceSpec{Name: name, Length: 4000000000})
+    }
+    big := PlanSize(specs)
+    if big.Version != VERSION_LONG || big.IndexSize != 6*(1+1+8) {
+        t.Errorf("Invalid plan for large genome: %+v", big)
+    }
+}
@@ -0,0 +1,107 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "context"
+    "fmt"
+)
+
+// ReaderPool hands out Readers of one 2bit file for concurrent use, for
+// example by HTTP handlers. A Reader is not safe for concurrent use, so each
+// caller takes one with Get and returns it with Put. Pooled Readers share the
+// parsed index and records and read the file independently through
+// io.ReaderAt.
+type ReaderPool struct {
+    base     *Reader
+    idle     chan *Reader
+}
+
+// Create a pool of size Readers cloned from base. The underlying reader of
+// base must implement io.ReaderAt (as files opened with Open do). All records
+// are parsed up front so the pooled Readers never modify shared state. base
+// must not be used directly while the pool is in use.
+func NewReaderPool(base *Reader, size int) (*ReaderPool, error) {
+    if size < 1 {
+        return nil, fmt.Errorf("Invalid pool size: %d", size)
+    }
+
+    err := base.loadIndex()
+    if err != nil {
+        return nil, err
+    }
+    for name := range base.index {
+        _, err := base.parseRecord(name, true)
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    p := &ReaderPool{base: base, idle: make(chan *Reader, size)}
+    for i := 0; i < size; i++ {
+        r, err := base.fork()
+        if err != nil {
+            return nil, err
+        }
+        p.idle <- r
+    }
+
+    return p, nil
+}
+
+// Open the 2bit file at path and create a pool of size Readers for it. The
+// file is closed by Close.
+func OpenPool(path string, size int, opts ...ReadOption) (*ReaderPool, error) {
+    r, err := Open(path, opts...)
+    if err != nil {
+        return nil, err
+    }
+
+    p, err := NewReaderPool(r, size)
+    if err != nil {
+        r.Close()
+        return nil, err
+    }
+
+    return p, nil
+}
+
+// Get takes a Reader from the pool, waiting until one is returned if all are
+// in use or ctx is done
+func (p *ReaderPool) Get(ctx context.Context) (*Reader, error) {
+    select {
+    case r := <-p.idle:
+        return r, nil
+    case <-ctx.Done():
+        return nil, ctx.Err()
+    }
+}
+
+// Put returns a Reader taken with Get to the pool
+func (p *ReaderPool) Put(r *Reader) {
+    p.idle <- r
+}
+
+// Check verifies the underlying file can still be read and has a valid 2bit
+// signature, for use in health checks
+func (p *ReaderPool) Check() (error) {
+    buf := make([]byte, HEADER_SIZE)
+    _, err := p.base.src.ReadAt(buf, 0)
+    if err != nil {
+        return fmt.Errorf("Failed to read 2bit header: %s", err)
+    }
+
+    if p.base.hdr.byteOrder.Uint32(buf) != SIG {
+        return fmt.Errorf("Invalid 2bit signature")
+    }
+
+    return nil
+}
+
+// Close closes the file if the pool was created with OpenPool. Readers must
+// not be used after Close.
+func (p *ReaderPool) Close() (error) {
+    return p.base.Close()
+}
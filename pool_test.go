@@ -0,0 +1,86 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "context"
+    "fmt"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+)
+
+func TestReaderPool(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "pool.2bit")
+    w, _ := Create(path)
+    for i := 0; i < 10; i++ {
+        w.Add(fmt.Sprintf("chr%d", i), "ACGTNNacgt"+strings.Repeat("G", i))
+    }
+    w.Close()
+
+    p, err := OpenPool(path, 3)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    defer p.Close()
+
+    err = p.Check()
+    if err != nil {
+        t.Errorf("Health check failed: %s", err)
+    }
+
+    var wg sync.WaitGroup
+    errs := make(chan error, 20)
+    for i := 0; i < 20; i++ {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            r, err := p.Get(context.Background())
+            if err != nil {
+                errs <- err
+                return
+            }
+            defer p.Put(r)
+
+            name := fmt.Sprintf("chr%d", i%10)
+            seq, err := r.Read(name)
+            if err != nil {
+                errs <- err
+                return
+            }
+            if string(seq) != "ACGTNNacgt"+strings.Repeat("G", i%10) {
+                errs <- fmt.Errorf("Invalid sequence %s: %s", name, seq)
+            }
+        }(i)
+    }
+    wg.Wait()
+    close(errs)
+    for err := range errs {
+        t.Error(err)
+    }
+
+    // exhausted pool honours the context
+    held := make([]*Reader, 0)
+    for i := 0; i < 3; i++ {
+        r, _ := p.Get(context.Background())
+        held = append(held, r)
+    }
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+    defer cancel()
+    _, err = p.Get(ctx)
+    if err != context.DeadlineExceeded {
+        t.Errorf("Expected deadline exceeded, got: %v", err)
+    }
+    for _, r := range held {
+        p.Put(r)
+    }
+
+    _, err = NewReaderPool(p.base, 0)
+    if err == nil {
+        t.Errorf("Expected error for invalid pool size")
+    }
+}
@@ -0,0 +1,122 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "io"
+    "os"
+    "crypto/sha256"
+    "encoding/json"
+    "fmt"
+    "time"
+)
+
+// Extension appended to a 2bit file path to name its provenance sidecar
+const PROVENANCE_EXT = ".prov.json"
+
+// Provenance records how a 2bit file was produced
+type Provenance struct {
+    Source        string            `json:"source,omitempty"`        // source FASTA path
+    Options       map[string]string `json:"options,omitempty"`       // import options
+    Tool          string            `json:"tool,omitempty"`
+    ToolVersion   string            `json:"tool_version,omitempty"`
+    Created       time.Time         `json:"created"`
+    Inputs        map[string]string `json:"inputs,omitempty"`        // input path to sha256
+    Sequences     int               `json:"sequences"`
+    Bases         int64             `json:"bases"`
+    Descriptions  map[string]string `json:"descriptions,omitempty"`  // FASTA header lines by sequence name
+}
+
+// AddInput records the sha256 digest of the input file at path
+func (p *Provenance) AddInput(path string) (error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+
+    h := sha256.New()
+    _, err = io.Copy(h, f)
+    if err != nil {
+        return err
+    }
+
+    if p.Inputs == nil {
+        p.Inputs = make(map[string]string)
+    }
+    p.Inputs[path] = fmt.Sprintf("%x", h.Sum(nil))
+
+    return nil
+}
+
+// WithProvenance records p in a sidecar (path+PROVENANCE_EXT) when a Writer
+// returned by Create is closed. Created defaults to the time of writing and
+// the sequence and base counts and FASTA descriptions are filled in from the
+// file written.
+func WithProvenance(p *Provenance) (WriterOption) {
+    return func(w *Writer) {
+        w.provenance = p
+    }
+}
+
+// Write the provenance sidecar for the 2bit file at path
+func (w *Writer) writeProvenance(path string) (error) {
+    p := *w.provenance
+    if p.Created.IsZero() {
+        p.Created = time.Now().UTC()
+    }
+
+    if len(w.descriptions) > 0 {
+        p.Descriptions = w.Descriptions()
+    }
+
+    p.Sequences = len(w.records)
+    p.Bases = 0
+    for _, rec := range w.records {
+        p.Bases += int64(rec.dnaSize)
+    }
+
+    f, err := os.Create(path+PROVENANCE_EXT)
+    if err != nil {
+        return err
+    }
+
+    enc := json.NewEncoder(f)
+    enc.SetIndent("", "  ")
+    err = enc.Encode(&p)
+    if err != nil {
+        f.Close()
+        return err
+    }
+
+    return f.Close()
+}
+
+// Read provenance in JSON format from in
+func ReadProvenance(in io.Reader) (*Provenance, error) {
+    p := new(Provenance)
+    err := json.NewDecoder(in).Decode(p)
+    if err != nil {
+        return nil, fmt.Errorf("Failed to read provenance: %s", err)
+    }
+
+    return p, nil
+}
+
+// Returns the provenance recorded for the file when it was written. Only
+// available for Readers returned by Open.
+func (r *Reader) Provenance() (*Provenance, error) {
+    if len(r.path) == 0 {
+        return nil, fmt.Errorf("Provenance is only available for files opened by path")
+    }
+
+    f, err := os.Open(r.path+PROVENANCE_EXT)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+
+    return ReadProvenance(f)
+}
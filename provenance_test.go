@@ -0,0 +1,73 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "crypto/sha256"
+    "fmt"
+    "io/ioutil"
+    "path/filepath"
+    "strings"
+)
+
+func TestProvenance(t *testing.T) {
+    dir := t.TempDir()
+    fasta := filepath.Join(dir, "in.fa")
+    ioutil.WriteFile(fasta, []byte(">chr1\nACGT\n"), 0644)
+    path := filepath.Join(dir, "out.2bit")
+
+    p := &Provenance{Source: fasta, Tool: "twobit", ToolVersion: "0.0.1", Options: map[string]string{"mask": "true"}}
+    err := p.AddInput(fasta)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    w, err := Create(path, WithProvenance(p))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    err = ImportFasta(strings.NewReader(">chr1 first chromosome\nACGT\n"), w)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    w.Add("chr2", "NNNNNN")
+    err = w.Close()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    r, err := Open(path)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    defer r.Close()
+
+    got, err := r.Provenance()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    if got.Source != fasta || got.Tool != "twobit" || got.Options["mask"] != "true" {
+        t.Errorf("Invalid provenance: %+v", got)
+    }
+    if got.Sequences != 2 || got.Bases != 10 || got.Created.IsZero() {
+        t.Errorf("Invalid provenance counts: %+v", got)
+    }
+    if len(got.Descriptions) != 1 || got.Descriptions["chr1"] != "chr1 first chromosome" {
+        t.Errorf("Invalid descriptions: %v", got.Descriptions)
+    }
+    if got.Inputs[fasta] != fmt.Sprintf("%x", sha256.Sum256([]byte(">chr1\nACGT\n"))) {
+        t.Errorf("Invalid input digest: %s", got.Inputs[fasta])
+    }
+
+    data, _ := ioutil.ReadFile(path)
+    nr, _ := NewReader(bytes.NewReader(data))
+    _, err = nr.Provenance()
+    if err == nil {
+        t.Errorf("Expected error for reader without path")
+    }
+}
@@ -0,0 +1,118 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "errors"
+    "fmt"
+)
+
+// ErrQC is returned when a sequence fails an import check in strict mode
+var ErrQC = errors.New("twobit: quality check failed")
+
+// Names of import checks
+const (
+    QC_DUPLICATE_NAME = "duplicate_name"
+    QC_MIN_LENGTH     = "min_length"
+    QC_MAX_N          = "max_n_fraction"
+    QC_MAX_OTHER      = "max_other_fraction"
+)
+
+// QCOptions configure the checks run on each sequence added to a Writer.
+// Zero values disable the length and fraction checks.
+type QCOptions struct {
+    DuplicateNames    bool    // flag names which were already added
+    MinLength         int     // flag sequences shorter than MinLength
+    MaxNFraction      float64 // flag sequences with a larger fraction of N
+    MaxOtherFraction  float64 // flag sequences with a larger fraction of bases other than ACGTN
+    Strict            bool    // refuse flagged sequences with ErrQC
+}
+
+// QCWarning is a failed check for a sequence
+type QCWarning struct {
+    Name     string  `json:"name"`
+    Check    string  `json:"check"`
+    Value    float64 `json:"value"`
+    Limit    float64 `json:"limit"`
+}
+
+func (q *QCWarning) String() (string) {
+    return fmt.Sprintf("%s: %s %g exceeds limit %g", q.Name, q.Check, q.Value, q.Limit)
+}
+
+// acgtn marks the bases A, C, G, T and N in either case
+var acgtn [256]bool
+
+func init() {
+    for _, c := range []byte("ACGTNacgtn") {
+        acgtn[c] = true
+    }
+}
+
+// WithQC runs the import checks in opts on every sequence added to the
+// Writer. Failed checks are reported by QCWarnings. In strict mode a sequence
+// failing any check is not added and Add or EndSequence return ErrQC.
+func WithQC(opts QCOptions) (WriterOption) {
+    return func(w *Writer) {
+        w.qc = &opts
+    }
+}
+
+// Returns the warnings from import checks in the order found
+func (w *Writer) QCWarnings() ([]*QCWarning) {
+    warnings := make([]*QCWarning, len(w.qcWarnings))
+    copy(warnings, w.qcWarnings)
+
+    return warnings
+}
+
+// Count N, amino acid and other non-ACGTN bases in seq
+func composition(seq string) (Composition) {
+    comp := Composition{Length: len(seq)}
+    for i := 0; i < len(seq); i++ {
+        c := seq[i]
+        if c == 'N' || c == 'n' {
+            comp.N++
+        } else if aminoAcid[c] {
+            comp.AminoAcid++
+        } else if !acgtn[c] {
+            comp.Other++
+        }
+    }
+
+    return comp
+}
+
+// Run the import checks on sequence name of length bases of which n are N
+// and other are not ACGTN
+func (w *Writer) checkQC(name string, length, n, other int) (error) {
+    if w.qc == nil {
+        return nil
+    }
+
+    warnings := make([]*QCWarning, 0)
+    if _, ok := w.records[name]; ok && w.qc.DuplicateNames {
+        warnings = append(warnings, &QCWarning{Name: name, Check: QC_DUPLICATE_NAME, Value: 1})
+    }
+    if length < w.qc.MinLength {
+        warnings = append(warnings, &QCWarning{Name: name, Check: QC_MIN_LENGTH, Value: float64(length), Limit: float64(w.qc.MinLength)})
+    }
+    if length > 0 {
+        if f := float64(n)/float64(length); w.qc.MaxNFraction > 0 && f > w.qc.MaxNFraction {
+            warnings = append(warnings, &QCWarning{Name: name, Check: QC_MAX_N, Value: f, Limit: w.qc.MaxNFraction})
+        }
+        if f := float64(other)/float64(length); w.qc.MaxOtherFraction > 0 && f > w.qc.MaxOtherFraction {
+            warnings = append(warnings, &QCWarning{Name: name, Check: QC_MAX_OTHER, Value: f, Limit: w.qc.MaxOtherFraction})
+        }
+    }
+
+    w.qcWarnings = append(w.qcWarnings, warnings...)
+
+    if w.qc.Strict && len(warnings) > 0 {
+        return fmt.Errorf("%w: %s", ErrQC, warnings[0])
+    }
+
+    return nil
+}
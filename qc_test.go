@@ -0,0 +1,55 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "errors"
+    "strings"
+)
+
+func TestQC(t *testing.T) {
+    opts := QCOptions{DuplicateNames: true, MinLength: 5, MaxNFraction: 0.5, MaxOtherFraction: 0.1}
+
+    w := NewWriter(WithQC(opts))
+    w.Add("ok", "ACGTACGTNN")
+    w.Add("short", "ACG")
+    w.Add("gappy", "ACNNNNNNNN")
+    w.Add("iupac", "ACGTRYKMAC")
+    w.Add("ok", "ACGTACGTAA")
+    w.StartSequence("built")
+    w.AppendChunk("AC")
+    w.AppendGap(10)
+    w.EndSequence()
+
+    checks := make([]string, 0)
+    for _, q := range w.QCWarnings() {
+        checks = append(checks, q.Name+":"+q.Check)
+    }
+    good := "short:min_length,gappy:max_n_fraction,iupac:max_other_fraction,ok:duplicate_name,built:max_n_fraction"
+    if strings.Join(checks, ",") != good {
+        t.Errorf("Invalid warnings: %v != %s", checks, good)
+    }
+
+    if len(w.records) != 5 {
+        t.Errorf("Flagged sequences should still be added: %d", len(w.records))
+    }
+
+    opts.Strict = true
+    w = NewWriter(WithQC(opts))
+    err := w.Add("gappy", "NNNNNNNNAC")
+    if !errors.Is(err, ErrQC) {
+        t.Errorf("Expected ErrQC, got: %v", err)
+    }
+    w.StartSequence("short")
+    w.AppendChunk("AC")
+    err = w.EndSequence()
+    if !errors.Is(err, ErrQC) {
+        t.Errorf("Expected ErrQC, got: %v", err)
+    }
+    if len(w.records) != 0 {
+        t.Errorf("Strict mode added failing sequences")
+    }
+}
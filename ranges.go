@@ -0,0 +1,44 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "io"
+    "bufio"
+    "fmt"
+)
+
+// Read sequences for a batch of ranges. Results are in the same order as
+// ranges. Each range must be a non-empty interval within its sequence: unlike
+// ReadRange an End of 0 does not mean the end of the sequence.
+func (r *Reader) ReadRanges(ranges []Range) ([][]byte, error) {
+    seqs := make([][]byte, len(ranges))
+    for i, rg := range ranges {
+        iv, err := r.checkRegion(rg)
+        if err != nil {
+            return nil, err
+        }
+        seq, err := r.ReadRange(iv.Name, iv.Start, iv.End)
+        if err != nil {
+            return nil, err
+        }
+        seqs[i] = seq
+    }
+
+    return seqs, nil
+}
+
+// Write ranges in BED format to out
+func WriteBED(out io.Writer, ranges []Range) (error) {
+    w := bufio.NewWriter(out)
+    for _, rg := range ranges {
+        _, err := fmt.Fprintf(w, "%s\t%d\t%d\n", rg.Name, rg.Start, rg.End)
+        if err != nil {
+            return err
+        }
+    }
+
+    return w.Flush()
+}
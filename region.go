@@ -0,0 +1,119 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+)
+
+// Parse "start-end", returning false if s is not a range
+func parseSpan(s string) (int, int, bool) {
+    pos := strings.SplitN(s, "-", 2)
+    if len(pos) != 2 {
+        return 0, 0, false
+    }
+
+    start, err := strconv.Atoi(pos[0])
+    if err != nil {
+        return 0, 0, false
+    }
+    end, err := strconv.Atoi(pos[1])
+    if err != nil {
+        return 0, 0, false
+    }
+
+    return start, end, true
+}
+
+// ParseRegion parses a region of one of the sequences in r written as name or
+// name:start-end, with 0-based half-open coordinates as printed by
+// Interval.String. A name alone is the whole sequence.
+//
+// Names may contain colons (for example HLA alleles such as
+// HLA-A*01:01:01:01), so unbraced specs are matched against the names in r,
+// preferring the longest name followed by a valid range. A spec which reads
+// both as a sequence name and as a shorter name with a range is ambiguous.
+// Wrapping the name in braces, {name} or {name}:start-end, always takes it
+// literally.
+func (r *Reader) ParseRegion(spec string) (Interval, error) {
+    err := r.loadIndex()
+    if err != nil {
+        return Interval{}, err
+    }
+
+    // braces quote the name
+    if strings.HasPrefix(spec, "{") {
+        i := strings.Index(spec, "}")
+        if i < 0 {
+            return Interval{}, fmt.Errorf("Invalid region %s: missing closing brace", spec)
+        }
+        name, rest := spec[1:i], spec[i+1:]
+        if len(rest) == 0 {
+            return r.wholeRegion(name)
+        }
+        if rest[0] != ':' {
+            return Interval{}, fmt.Errorf("Invalid region %s: expected : after name", spec)
+        }
+        start, end, ok := parseSpan(rest[1:])
+        if !ok {
+            return Interval{}, fmt.Errorf("Invalid range in region %s", spec)
+        }
+        return r.checkRegion(Interval{Name: name, Start: start, End: end})
+    }
+
+    _, whole := r.index[spec]
+
+    // try the longest name first
+    for i := strings.LastIndex(spec, ":"); i >= 0; i = strings.LastIndex(spec[:i], ":") {
+        name := spec[:i]
+        if _, ok := r.index[name]; !ok {
+            continue
+        }
+        start, end, ok := parseSpan(spec[i+1:])
+        if !ok {
+            continue
+        }
+        if whole {
+            return Interval{}, fmt.Errorf("Ambiguous region %s: use {%s} or {%s}:%s", spec, spec, name, spec[i+1:])
+        }
+        return r.checkRegion(Interval{Name: name, Start: start, End: end})
+    }
+
+    if whole {
+        return r.wholeRegion(spec)
+    }
+
+    return Interval{}, fmt.Errorf("Invalid region %s: no matching sequence", spec)
+}
+
+// Return the interval covering all of sequence name
+func (r *Reader) wholeRegion(name string) (Interval, error) {
+    size, err := r.Length(name)
+    if err != nil {
+        return Interval{}, err
+    }
+
+    return Interval{Name: name, Start: 0, End: size}, nil
+}
+
+// Return iv if it is valid and within its sequence
+func (r *Reader) checkRegion(iv Interval) (Interval, error) {
+    err := iv.Validate()
+    if err != nil {
+        return Interval{}, err
+    }
+
+    size, err := r.Length(iv.Name)
+    if err != nil {
+        return Interval{}, err
+    }
+    if iv.End > size || iv.Len() == 0 {
+        return Interval{}, fmt.Errorf("Invalid region %s: sequence length is %d", iv, size)
+    }
+
+    return iv, nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+)
+
+func TestParseRegion(t *testing.T) {
+    tb := newTestReader(t, map[string]string{
+        "chr1": "ACGTACGTAC",
+        "HLA-A*01:01:01:01": "ACGTACGTACGTACGTACGT",
+        "HLA-A*01:01": "ACGT",
+        "odd:1-2": "ACGTACGT",
+        "odd": "ACGTACGT",
+    })
+
+    tests := []struct {
+        spec   string
+        want   Interval
+        valid  bool
+    }{
+        {"chr1", Interval{"chr1", 0, 10}, true},
+        {"chr1:2-5", Interval{"chr1", 2, 5}, true},
+        {"HLA-A*01:01:01:01", Interval{"HLA-A*01:01:01:01", 0, 20}, true},
+        {"HLA-A*01:01:01:01:5-15", Interval{"HLA-A*01:01:01:01", 5, 15}, true},
+        {"HLA-A*01:01", Interval{"HLA-A*01:01", 0, 4}, true},
+        {"HLA-A*01:01:1-3", Interval{"HLA-A*01:01", 1, 3}, true},
+        {"{HLA-A*01:01}:1-3", Interval{"HLA-A*01:01", 1, 3}, true},
+        {"{odd:1-2}", Interval{"odd:1-2", 0, 8}, true},
+        {"{odd}:1-2", Interval{"odd", 1, 2}, true},
+        {"odd:1-2", Interval{}, false}, // ambiguous
+        {"chr1:5-20", Interval{}, false},
+        {"chr1:5-5", Interval{}, false},
+        {"chr2", Interval{}, false},
+        {"chr2:1-2", Interval{}, false},
+        {"{chr1", Interval{}, false},
+        {"{chr1}x", Interval{}, false},
+        {"{chr1}:x-y", Interval{}, false},
+    }
+
+    for _, tt := range tests {
+        iv, err := tb.ParseRegion(tt.spec)
+        if !tt.valid {
+            if err == nil {
+                t.Errorf("Region %s should be invalid: %s", tt.spec, iv)
+            }
+            continue
+        }
+        if err != nil {
+            t.Errorf("Region %s: %s", tt.spec, err)
+            continue
+        }
+        if iv != tt.want {
+            t.Errorf("Region %s: %+v != %+v", tt.spec, iv, tt.want)
+        }
+    }
+}
@@ -0,0 +1,252 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package remote
+
+import (
+    "io"
+    "io/ioutil"
+    "os"
+    "crypto/sha256"
+    "fmt"
+    "path/filepath"
+    "sort"
+    "sync"
+    "time"
+    "github.com/aebruno/twobit"
+)
+
+// Size of the blocks stored by a DiskCache
+const CACHE_BLOCK_SIZE = 64 * 1024
+
+// Percentage of the maximum size a full DiskCache is pruned down to, so the
+// cache directory is not walked again on the next block stored
+const CACHE_LOW_WATER = 90
+
+// DiskCache keeps blocks fetched from remote files in a directory so they are
+// not fetched again by later processes, like the UCSC udc cache (-udcDir).
+// Blocks are stored in one subdirectory per remote file. It is safe for
+// concurrent use and several processes may share a directory.
+type DiskCache struct {
+    dir      string
+    maxSize  int64         // 0 for no limit
+    ttl      time.Duration // 0 to keep blocks until evicted
+    mu       sync.Mutex
+    size     int64         // approximate bytes held
+    prunes   int           // number of times the directory was walked
+}
+
+// NewDiskCache returns a cache in dir, creating it if needed. Once the cache
+// holds more than maxSize bytes the least recently written blocks are
+// removed until it is down to CACHE_LOW_WATER percent of maxSize. Blocks older than ttl are fetched again. The directory is pruned
+// to these limits when the cache is opened.
+func NewDiskCache(dir string, maxSize int64, ttl time.Duration) (*DiskCache, error) {
+    if maxSize < 0 || ttl < 0 {
+        return nil, fmt.Errorf("Invalid cache limits: size %d ttl %s", maxSize, ttl)
+    }
+
+    err := os.MkdirAll(dir, 0755)
+    if err != nil {
+        return nil, err
+    }
+
+    c := &DiskCache{dir: dir, maxSize: maxSize, ttl: ttl}
+    err = c.Prune()
+    if err != nil {
+        return nil, err
+    }
+
+    return c, nil
+}
+
+// Transform returns a Transform caching reads of the remote file identified
+// by key, typically its URL, in c. The file size is part of the cache key so
+// a remote file replaced by one of a different size is not served stale.
+func (c *DiskCache) Transform(key string) (twobit.Transform) {
+    return func(src twobit.Source) (twobit.Source, error) {
+        sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d", key, src.Size())))
+        dir := filepath.Join(c.dir, fmt.Sprintf("%x", sum[:16]))
+        return &cachedSource{Source: src, cache: c, dir: dir}, nil
+    }
+}
+
+// Return cached block i from dir, false if it is missing or expired
+func (c *DiskCache) get(dir string, i int64) ([]byte, bool) {
+    path := filepath.Join(dir, fmt.Sprintf("%d", i))
+    if c.ttl > 0 {
+        info, err := os.Stat(path)
+        if err != nil || time.Since(info.ModTime()) > c.ttl {
+            return nil, false
+        }
+    }
+
+    b, err := ioutil.ReadFile(path)
+    if err != nil {
+        return nil, false
+    }
+
+    return b, true
+}
+
+// Store block i in dir. The block is written to a temporary file and renamed
+// so other processes never see a partial block.
+func (c *DiskCache) put(dir string, i int64, b []byte) (error) {
+    err := os.MkdirAll(dir, 0755)
+    if err != nil {
+        return err
+    }
+
+    f, err := ioutil.TempFile(dir, ".tmp")
+    if err != nil {
+        return err
+    }
+    _, err = f.Write(b)
+    cerr := f.Close()
+    if err == nil {
+        err = cerr
+    }
+    if err == nil {
+        err = os.Rename(f.Name(), filepath.Join(dir, fmt.Sprintf("%d", i)))
+    }
+    if err != nil {
+        os.Remove(f.Name())
+        return err
+    }
+
+    c.mu.Lock()
+    c.size += int64(len(b))
+    over := c.maxSize > 0 && c.size > c.maxSize
+    c.mu.Unlock()
+
+    if over {
+        return c.Prune()
+    }
+
+    return nil
+}
+
+// cachedBlock is a block file found in the cache directory
+type cachedBlock struct {
+    path     string
+    size     int64
+    modTime  time.Time
+}
+
+// Return every block in the cache
+func (c *DiskCache) blocks() ([]cachedBlock, error) {
+    blocks := make([]cachedBlock, 0)
+    err := filepath.Walk(c.dir, func(path string, info os.FileInfo, err error) (error) {
+        if err != nil {
+            if os.IsNotExist(err) {
+                return nil
+            }
+            return err
+        }
+        if info.Mode().IsRegular() && info.Name()[0] != '.' {
+            blocks = append(blocks, cachedBlock{path: path, size: info.Size(), modTime: info.ModTime()})
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    return blocks, nil
+}
+
+// Prune removes expired blocks and, if the cache is larger than its maximum
+// size, the least recently written blocks until it is down to
+// CACHE_LOW_WATER percent of the maximum
+func (c *DiskCache) Prune() (error) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    c.prunes++
+    blocks, err := c.blocks()
+    if err != nil {
+        return err
+    }
+    sort.Slice(blocks, func(i, j int) bool {
+        return blocks[i].modTime.Before(blocks[j].modTime)
+    })
+
+    c.size = 0
+    for _, b := range blocks {
+        c.size += b.size
+    }
+
+    limit := c.maxSize
+    if c.maxSize > 0 && c.size > c.maxSize {
+        limit = c.maxSize*CACHE_LOW_WATER/100
+    }
+
+    for _, b := range blocks {
+        expired := c.ttl > 0 && time.Since(b.modTime) > c.ttl
+        if !expired && (c.maxSize == 0 || c.size <= limit) {
+            continue
+        }
+
+        err = os.Remove(b.path)
+        if err != nil && !os.IsNotExist(err) {
+            return err
+        }
+        c.size -= b.size
+    }
+
+    return nil
+}
+
+// cachedSource reads a Source through a DiskCache
+type cachedSource struct {
+    twobit.Source
+    cache  *DiskCache
+    dir    string
+}
+
+// Return block i, fetching it from the underlying Source if not cached
+func (s *cachedSource) block(i int64) ([]byte, error) {
+    start := i*CACHE_BLOCK_SIZE
+    n := int64(CACHE_BLOCK_SIZE)
+    if start+n > s.Size() {
+        n = s.Size()-start
+    }
+
+    // blocks of the wrong size were damaged and are fetched again
+    if b, ok := s.cache.get(s.dir, i); ok && int64(len(b)) == n {
+        return b, nil
+    }
+
+    b := make([]byte, n)
+    _, err := s.Source.ReadAt(b, start)
+    if err != nil && err != io.EOF {
+        return nil, err
+    }
+
+    // a full cache directory only costs refetching, so errors are ignored
+    s.cache.put(s.dir, i, b)
+
+    return b, nil
+}
+
+func (s *cachedSource) ReadAt(p []byte, off int64) (int, error) {
+    if off < 0 {
+        return 0, fmt.Errorf("Invalid negative offset: %d", off)
+    }
+
+    n := 0
+    for n < len(p) {
+        pos := off+int64(n)
+        if pos >= s.Size() {
+            return n, io.EOF
+        }
+
+        b, err := s.block(pos/CACHE_BLOCK_SIZE)
+        if err != nil {
+            return n, err
+        }
+        n += copy(p[n:], b[pos%CACHE_BLOCK_SIZE:])
+    }
+
+    return n, nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package remote
+
+import (
+    "testing"
+    "bytes"
+    "fmt"
+    "time"
+    "github.com/aebruno/twobit"
+)
+
+// countingSource counts reads from the underlying storage
+type countingSource struct {
+    *bytes.Reader
+    reads  int
+}
+
+func (c *countingSource) ReadAt(p []byte, off int64) (int, error) {
+    c.reads++
+    return c.Reader.ReadAt(p, off)
+}
+
+func TestDiskCache(t *testing.T) {
+    w := twobit.NewWriter()
+    w.Add("chr1", "ACTGNNNNacgtGATTACA")
+    w.Add("chr2", "GGGGCCCCAAAATTTT")
+    var file bytes.Buffer
+    w.WriteTo(&file)
+
+    dir := t.TempDir()
+    read := func(cache *DiskCache) (int) {
+        src := &countingSource{Reader: bytes.NewReader(file.Bytes())}
+        tb, err := twobit.NewReaderAt(src, src.Size(), twobit.WithTransform(cache.Transform("https://example.org/test.2bit")))
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        seq, err := tb.Read("chr1")
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        if string(seq) != "ACTGNNNNacgtGATTACA" {
+            t.Errorf("Invalid sequence: %s", seq)
+        }
+        return src.reads
+    }
+
+    cache, err := NewDiskCache(dir, 0, 0)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if n := read(cache); n == 0 {
+        t.Errorf("First read should fetch from the source")
+    }
+
+    // a new process finds the blocks on disk
+    cache, err = NewDiskCache(dir, 0, 0)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if cache.size != int64(file.Len()) {
+        t.Errorf("Invalid cache size: %d != %d", cache.size, file.Len())
+    }
+    if n := read(cache); n != 0 {
+        t.Errorf("Cached read fetched from the source %d times", n)
+    }
+
+    cache, _ = NewDiskCache(dir, 0, time.Nanosecond)
+    time.Sleep(time.Millisecond)
+    if n := read(cache); n == 0 {
+        t.Errorf("Expired blocks should be fetched again")
+    }
+
+    cache, _ = NewDiskCache(dir, 1, 0)
+    read(cache)
+    if cache.size > 1 {
+        t.Errorf("Cache exceeds limit: %d bytes", cache.size)
+    }
+
+    _, err = NewDiskCache(dir, -1, 0)
+    if err == nil {
+        t.Errorf("Invalid cache size accepted")
+    }
+}
+
+func TestDiskCacheLowWater(t *testing.T) {
+    cache, err := NewDiskCache(t.TempDir(), 1000, 0)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    // a full cache is pruned with room to spare, not on every block stored
+    block := make([]byte, 100)
+    for i := int64(0); i < 30; i++ {
+        err = cache.put(fmt.Sprintf("%s/file", cache.dir), i, block)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        if cache.size > 1000 {
+            t.Fatalf("Cache exceeds limit: %d bytes", cache.size)
+        }
+    }
+    if cache.prunes > 12 {
+        t.Errorf("Cache directory walked %d times for 30 blocks", cache.prunes)
+    }
+}
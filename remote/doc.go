@@ -0,0 +1,16 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+// Package remote opens 2bit files held in object stores using HTTP range
+// requests. It is kept out of the twobit package so programs embedding the
+// core Reader and Writer never link net/http or cloud specific code.
+//
+// Each store is built only when its tag is given:
+//
+//   go build -tags s3    # OpenS3
+//   go build -tags gcs   # OpenGCS
+//
+// A DiskCache keeps fetched blocks on local disk across processes and works
+// with any remote reader through twobit.WithTransform.
+package remote
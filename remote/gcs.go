@@ -0,0 +1,48 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+//go:build gcs
+
+package remote
+
+import (
+    "fmt"
+    "net/http"
+    "net/url"
+    "github.com/aebruno/twobit"
+)
+
+// GCS_ENDPOINT is the Google Cloud Storage XML API endpoint
+const GCS_ENDPOINT = "https://storage.googleapis.com"
+
+// OpenGCS opens the 2bit file stored in bucket as object. If token is not
+// empty it is sent as an OAuth2 bearer token, otherwise the object must be
+// publicly readable. A nil client uses http.DefaultClient.
+func OpenGCS(client *http.Client, bucket, object, token string, opts ...twobit.ReadOption) (*twobit.Reader, error) {
+    return openGCS(client, GCS_ENDPOINT, bucket, object, token, FetchOptions{}, opts...)
+}
+
+// OpenGCSFetch is OpenGCS with control over how data is fetched
+func OpenGCSFetch(client *http.Client, bucket, object, token string, fetch FetchOptions, opts ...twobit.ReadOption) (*twobit.Reader, error) {
+    return openGCS(client, GCS_ENDPOINT, bucket, object, token, fetch, opts...)
+}
+
+func openGCS(client *http.Client, endpoint, bucket, object, token string, fetch FetchOptions, opts ...twobit.ReadOption) (*twobit.Reader, error) {
+    u := fmt.Sprintf("%s/%s/%s", endpoint, bucket, (&url.URL{Path: object}).EscapedPath())
+
+    var sign func(*http.Request) (error)
+    if len(token) > 0 {
+        sign = func(req *http.Request) (error) {
+            req.Header.Set("Authorization", "Bearer "+token)
+            return nil
+        }
+    }
+
+    src, err := newObjectSource(client, u, sign, fetch)
+    if err != nil {
+        return nil, err
+    }
+
+    return twobit.NewReaderAt(src, src.Size(), opts...)
+}
@@ -0,0 +1,54 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+//go:build gcs
+
+package remote
+
+import (
+    "testing"
+    "bytes"
+    "time"
+    "net/http"
+    "net/http/httptest"
+    "github.com/aebruno/twobit"
+)
+
+func TestOpenGCS(t *testing.T) {
+    w := twobit.NewWriter()
+    w.Add("chr1", "ACTGNNNNacgtGATTACA")
+    var file bytes.Buffer
+    w.WriteTo(&file)
+
+    ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+        if req.Header.Get("Authorization") != "Bearer secret" {
+            http.Error(rw, "forbidden", http.StatusForbidden)
+            return
+        }
+        if req.URL.Path != "/genomes/hg38 copy.2bit" {
+            http.NotFound(rw, req)
+            return
+        }
+        http.ServeContent(rw, req, "hg38.2bit", time.Time{}, bytes.NewReader(file.Bytes()))
+    }))
+    defer ts.Close()
+
+    _, err := openGCS(nil, ts.URL, "genomes", "hg38 copy.2bit", "", FetchOptions{})
+    if err == nil {
+        t.Errorf("Expected error without token")
+    }
+
+    tb, err := openGCS(nil, ts.URL, "genomes", "hg38 copy.2bit", "secret", FetchOptions{})
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    seq, err := tb.ReadRange("chr1", 4, 12)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if string(seq) != "NNNNacgt" {
+        t.Errorf("Invalid sequence: %s", seq)
+    }
+}
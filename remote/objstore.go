@@ -0,0 +1,252 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+//go:build s3 || gcs
+
+package remote
+
+import (
+    "io"
+    "fmt"
+    "sync"
+    "time"
+    "net/http"
+)
+
+// Defaults for object store sources
+const (
+    OBJECT_BLOCK_SIZE   = 1 << 20
+    OBJECT_CACHE_BLOCKS = 64
+    OBJECT_MAX_FETCH    = 16 << 20
+    OBJECT_RETRIES      = 3
+)
+
+// FetchOptions control how data is fetched from an object store. Zero values
+// use the defaults.
+type FetchOptions struct {
+    BlockSize    int64 // minimum fetch granularity in bytes, OBJECT_BLOCK_SIZE by default
+    MaxFetch     int64 // largest single GET when coalescing adjacent blocks, OBJECT_MAX_FETCH by default
+    CacheBlocks  int   // blocks kept in memory, OBJECT_CACHE_BLOCKS by default
+}
+
+// objectSource is a Source over an object in a remote store. Data is fetched
+// with HTTP range GETs in fixed size blocks which are cached. Adjacent
+// uncached blocks needed by one read are fetched with a single GET. ReadAt
+// may be called concurrently, the cache is guarded by mu which is not held
+// during GETs.
+type objectSource struct {
+    client     *http.Client
+    url        string
+    sign       func(req *http.Request) (error)
+    size       int64
+    blockSize  int64
+    maxFetch   int64
+    mu         sync.Mutex
+    cache      map[int64][]byte
+    order      []int64 // cached blocks, oldest first
+    maxBlocks  int
+    retries    int
+    requests   int // range GETs issued
+}
+
+func newObjectSource(client *http.Client, url string, sign func(*http.Request) (error), fetch FetchOptions) (*objectSource, error) {
+    if client == nil {
+        client = http.DefaultClient
+    }
+    if fetch.BlockSize < 0 || fetch.MaxFetch < 0 || fetch.CacheBlocks < 0 {
+        return nil, fmt.Errorf("Invalid fetch options: %+v", fetch)
+    }
+
+    o := &objectSource{
+        client: client,
+        url: url,
+        sign: sign,
+        blockSize: OBJECT_BLOCK_SIZE,
+        maxFetch: OBJECT_MAX_FETCH,
+        cache: make(map[int64][]byte),
+        maxBlocks: OBJECT_CACHE_BLOCKS,
+        retries: OBJECT_RETRIES,
+    }
+    if fetch.BlockSize > 0 {
+        o.blockSize = fetch.BlockSize
+    }
+    if fetch.MaxFetch > 0 {
+        o.maxFetch = fetch.MaxFetch
+    }
+    if fetch.CacheBlocks > 0 {
+        o.maxBlocks = fetch.CacheBlocks
+    }
+
+    resp, err := o.do("HEAD", "")
+    if err != nil {
+        return nil, err
+    }
+    resp.Body.Close()
+
+    if resp.ContentLength < 0 {
+        return nil, fmt.Errorf("Unknown object size: %s", url)
+    }
+    o.size = resp.ContentLength
+
+    return o, nil
+}
+
+// Issue a request, retrying network errors and server errors with backoff
+func (o *objectSource) do(method, rng string) (*http.Response, error) {
+    var lastErr error
+    for attempt := 0; attempt <= o.retries; attempt++ {
+        if attempt > 0 {
+            time.Sleep(time.Duration(1<<uint(attempt-1)) * 100 * time.Millisecond)
+        }
+
+        req, err := http.NewRequest(method, o.url, nil)
+        if err != nil {
+            return nil, err
+        }
+        if len(rng) > 0 {
+            req.Header.Set("Range", rng)
+        }
+        if o.sign != nil {
+            err = o.sign(req)
+            if err != nil {
+                return nil, err
+            }
+        }
+
+        resp, err := o.client.Do(req)
+        if err != nil {
+            lastErr = err
+            continue
+        }
+
+        if resp.StatusCode >= 500 {
+            resp.Body.Close()
+            lastErr = fmt.Errorf("%s %s: %s", method, o.url, resp.Status)
+            continue
+        }
+        if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+            resp.Body.Close()
+            return nil, fmt.Errorf("%s %s: %s", method, o.url, resp.Status)
+        }
+
+        return resp, nil
+    }
+
+    return nil, lastErr
+}
+
+// Fetch blocks first to last with a single GET, cache them and return their
+// data
+func (o *objectSource) fetch(first, last int64) ([]byte, error) {
+    start := first*o.blockSize
+    end := (last+1)*o.blockSize
+    if end > o.size {
+        end = o.size
+    }
+
+    o.mu.Lock()
+    o.requests++
+    o.mu.Unlock()
+
+    resp, err := o.do("GET", fmt.Sprintf("bytes=%d-%d", start, end-1))
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode == http.StatusOK && start > 0 {
+        // server ignored the range, skip to the block
+        _, err = io.CopyN(io.Discard, resp.Body, start)
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    data := make([]byte, end-start)
+    _, err = io.ReadFull(resp.Body, data)
+    if err != nil {
+        return nil, fmt.Errorf("Failed to read %s bytes %d-%d: %s", o.url, start, end, err)
+    }
+
+    o.mu.Lock()
+    defer o.mu.Unlock()
+    for i := first; i <= last; i++ {
+        lo := (i-first)*o.blockSize
+        hi := lo+o.blockSize
+        if hi > int64(len(data)) {
+            hi = int64(len(data))
+        }
+        o.store(i, data[lo:hi])
+    }
+
+    return data, nil
+}
+
+// Add block i to the cache, evicting the oldest block when full. o.mu must
+// be held.
+func (o *objectSource) store(i int64, b []byte) {
+    if _, ok := o.cache[i]; ok {
+        return
+    }
+    if len(o.order) >= o.maxBlocks {
+        delete(o.cache, o.order[0])
+        o.order = o.order[1:]
+    }
+    o.cache[i] = b
+    o.order = append(o.order, i)
+}
+
+func (o *objectSource) ReadAt(p []byte, off int64) (int, error) {
+    if off < 0 {
+        return 0, fmt.Errorf("Invalid negative offset: %d", off)
+    }
+
+    n := 0
+    for n < len(p) {
+        pos := off+int64(n)
+        if pos >= o.size {
+            return n, io.EOF
+        }
+
+        i := pos/o.blockSize
+        o.mu.Lock()
+        if b, ok := o.cache[i]; ok {
+            n += copy(p[n:], b[pos%o.blockSize:])
+            o.mu.Unlock()
+            continue
+        }
+
+        // coalesce the run of uncached blocks this read needs
+        last := (off+int64(len(p))-1)/o.blockSize
+        if end := (o.size-1)/o.blockSize; last > end {
+            last = end
+        }
+        blocks := o.maxFetch/o.blockSize
+        if blocks < 1 {
+            blocks = 1
+        }
+        if last > i+blocks-1 {
+            last = i+blocks-1
+        }
+        for j := i+1; j <= last; j++ {
+            if _, ok := o.cache[j]; ok {
+                last = j-1
+                break
+            }
+        }
+        o.mu.Unlock()
+
+        data, err := o.fetch(i, last)
+        if err != nil {
+            return n, err
+        }
+        n += copy(p[n:], data[pos-i*o.blockSize:])
+    }
+
+    return n, nil
+}
+
+func (o *objectSource) Size() (int64) {
+    return o.size
+}
@@ -0,0 +1,166 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+//go:build s3 || gcs
+
+package remote
+
+import (
+    "testing"
+    "bytes"
+    "context"
+    "fmt"
+    "strings"
+    "sync"
+    "time"
+    "net/http"
+    "net/http/httptest"
+    "github.com/aebruno/twobit"
+)
+
+func TestObjectSource(t *testing.T) {
+    w := twobit.NewWriter()
+    w.Add("chr1", "ACTGNNNNacgtGATTACA")
+    w.Add("chr2", "GGGGCCCCAAAATTTT")
+    var file bytes.Buffer
+    w.WriteTo(&file)
+
+    failures := 1
+    ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+        if req.Method == "GET" && failures > 0 {
+            failures--
+            http.Error(rw, "unavailable", http.StatusServiceUnavailable)
+            return
+        }
+        http.ServeContent(rw, req, "test.2bit", time.Time{}, bytes.NewReader(file.Bytes()))
+    }))
+    defer ts.Close()
+
+    src, err := newObjectSource(nil, ts.URL+"/test.2bit", nil, FetchOptions{BlockSize: 8, CacheBlocks: 2})
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    if src.Size() != int64(file.Len()) {
+        t.Errorf("Invalid size: %d != %d", src.Size(), file.Len())
+    }
+
+    tb, err := twobit.NewReaderAt(src, src.Size())
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    for name, good := range map[string]string{"chr1": "ACTGNNNNacgtGATTACA", "chr2": "GGGGCCCCAAAATTTT"} {
+        seq, err := tb.Read(name)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        if string(seq) != good {
+            t.Errorf("Invalid sequence %s: %s != %s", name, seq, good)
+        }
+    }
+
+    if len(src.cache) > 2 {
+        t.Errorf("Cache exceeds limit: %d blocks", len(src.cache))
+    }
+
+    if failures != 0 {
+        t.Errorf("Failed request was not retried")
+    }
+}
+
+func TestCoalesce(t *testing.T) {
+    data := make([]byte, 1000)
+    for i := range data {
+        data[i] = byte(i)
+    }
+    ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+        http.ServeContent(rw, req, "data", time.Time{}, bytes.NewReader(data))
+    }))
+    defer ts.Close()
+
+    src, err := newObjectSource(nil, ts.URL+"/data", nil, FetchOptions{BlockSize: 10, MaxFetch: 200, CacheBlocks: 100})
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    // 15 blocks fetched with one GET
+    p := make([]byte, 145)
+    n, err := src.ReadAt(p, 5)
+    if err != nil || n != len(p) || !bytes.Equal(p, data[5:150]) {
+        t.Fatalf("Invalid read: %d %v", n, err)
+    }
+    if src.requests != 1 {
+        t.Errorf("Adjacent blocks not coalesced: %d requests", src.requests)
+    }
+
+    // cached blocks split the run, reads past MaxFetch are split
+    p = make([]byte, 500)
+    n, err = src.ReadAt(p, 100)
+    if err != nil || !bytes.Equal(p, data[100:600]) {
+        t.Fatalf("Invalid read: %d %v", n, err)
+    }
+    if src.requests != 4 {
+        t.Errorf("Invalid request count: %d", src.requests)
+    }
+
+    n, err = src.ReadAt(make([]byte, 50), 980)
+    if n != 20 || err == nil {
+        t.Errorf("Read past end should return EOF: %d %v", n, err)
+    }
+
+    _, err = newObjectSource(nil, ts.URL+"/data", nil, FetchOptions{BlockSize: -1})
+    if err == nil {
+        t.Errorf("Invalid fetch options accepted")
+    }
+}
+
+// Run with -race: forks of a Reader share the source
+func TestObjectSourceConcurrent(t *testing.T) {
+    w := twobit.NewWriter()
+    for i := 0; i < 16; i++ {
+        w.Add(fmt.Sprintf("chr%d", i), strings.Repeat("ACGTNNacgt", 20+i))
+    }
+    var file bytes.Buffer
+    w.WriteTo(&file)
+
+    ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+        http.ServeContent(rw, req, "test.2bit", time.Time{}, bytes.NewReader(file.Bytes()))
+    }))
+    defer ts.Close()
+
+    src, err := newObjectSource(nil, ts.URL+"/test.2bit", nil, FetchOptions{BlockSize: 64, CacheBlocks: 4})
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    tb, err := twobit.NewReaderAt(src, src.Size())
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    digests, err := tb.Digests(context.Background(), 8)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if len(digests) != 16 {
+        t.Errorf("Invalid digest count: %d", len(digests))
+    }
+
+    var wg sync.WaitGroup
+    for g := 0; g < 8; g++ {
+        wg.Add(1)
+        go func(g int) {
+            defer wg.Done()
+            p := make([]byte, 100)
+            for off := int64(g*10); off+100 < src.Size(); off += 37 {
+                n, err := src.ReadAt(p, off)
+                if err != nil || !bytes.Equal(p[:n], file.Bytes()[off:off+100]) {
+                    t.Errorf("Invalid read at %d: %v", off, err)
+                    return
+                }
+            }
+        }(g)
+    }
+    wg.Wait()
+}
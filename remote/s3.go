@@ -0,0 +1,138 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+//go:build s3
+
+package remote
+
+import (
+    "fmt"
+    "time"
+    "sort"
+    "strings"
+    "net/http"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "github.com/aebruno/twobit"
+)
+
+// sha256 of an empty payload
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Config holds the location and credentials used to access S3
+type S3Config struct {
+    Region        string
+    AccessKey     string
+    SecretKey     string
+    SessionToken  string
+    Endpoint      string       // optional, for S3 compatible stores. Uses path style URLs
+    Client        *http.Client // optional, defaults to http.DefaultClient
+    Fetch         FetchOptions // optional, controls range request sizes
+}
+
+// OpenS3 opens the 2bit file stored in bucket under key. Requests are signed
+// with AWS Signature Version 4 unless no AccessKey is given, in which case
+// the object must be publicly readable.
+func OpenS3(bucket, key string, cfg S3Config, opts ...twobit.ReadOption) (*twobit.Reader, error) {
+    url := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, cfg.Region, uriEncode(key, false))
+    if len(cfg.Endpoint) > 0 {
+        url = fmt.Sprintf("%s/%s/%s", strings.TrimRight(cfg.Endpoint, "/"), bucket, uriEncode(key, false))
+    }
+
+    var sign func(*http.Request) (error)
+    if len(cfg.AccessKey) > 0 {
+        sign = func(req *http.Request) (error) {
+            signS3(req, cfg, time.Now().UTC())
+            return nil
+        }
+    }
+
+    src, err := newObjectSource(cfg.Client, url, sign, cfg.Fetch)
+    if err != nil {
+        return nil, err
+    }
+
+    return twobit.NewReaderAt(src, src.Size(), opts...)
+}
+
+// Sign req with AWS Signature Version 4
+func signS3(req *http.Request, cfg S3Config, now time.Time) {
+    amzDate := now.Format("20060102T150405Z")
+    date := now.Format("20060102")
+
+    req.Header.Set("x-amz-date", amzDate)
+    req.Header.Set("x-amz-content-sha256", emptyPayloadHash)
+    if len(cfg.SessionToken) > 0 {
+        req.Header.Set("x-amz-security-token", cfg.SessionToken)
+    }
+
+    headers := map[string]string{"host": req.URL.Host}
+    for k, v := range req.Header {
+        headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+    }
+    names := make([]string, 0, len(headers))
+    for k := range headers {
+        names = append(names, k)
+    }
+    sort.Strings(names)
+
+    var canonical strings.Builder
+    for _, k := range names {
+        canonical.WriteString(k + ":" + headers[k] + "\n")
+    }
+    signed := strings.Join(names, ";")
+
+    path := req.URL.EscapedPath()
+    if len(path) == 0 {
+        path = "/"
+    }
+
+    request := strings.Join([]string{
+        req.Method,
+        path,
+        req.URL.RawQuery,
+        canonical.String(),
+        signed,
+        emptyPayloadHash,
+    }, "\n")
+
+    scope := date + "/" + cfg.Region + "/s3/aws4_request"
+    hash := sha256.Sum256([]byte(request))
+    toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+    key := hmacSHA256([]byte("AWS4"+cfg.SecretKey), date)
+    key = hmacSHA256(key, cfg.Region)
+    key = hmacSHA256(key, "s3")
+    key = hmacSHA256(key, "aws4_request")
+    signature := hex.EncodeToString(hmacSHA256(key, toSign))
+
+    req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+        cfg.AccessKey, scope, signed, signature))
+}
+
+func hmacSHA256(key []byte, data string) ([]byte) {
+    h := hmac.New(sha256.New, key)
+    h.Write([]byte(data))
+    return h.Sum(nil)
+}
+
+// URI encode s as required by AWS. Slashes are kept unless encodeSlash.
+func uriEncode(s string, encodeSlash bool) (string) {
+    var b strings.Builder
+    for i := 0; i < len(s); i++ {
+        c := s[i]
+        switch {
+        case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+            c == '-', c == '.', c == '_', c == '~':
+            b.WriteByte(c)
+        case c == '/' && !encodeSlash:
+            b.WriteByte(c)
+        default:
+            fmt.Fprintf(&b, "%%%02X", c)
+        }
+    }
+
+    return b.String()
+}
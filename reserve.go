@@ -0,0 +1,280 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "bufio"
+    "encoding/binary"
+    "fmt"
+    "io"
+    "math"
+    "sort"
+)
+
+// Reserve sets aside one N or mask block entry per this many bases, about
+// twice the density of repeats soft-masked in mammalian genomes
+const RESERVE_BASES_PER_BLOCK = 256
+
+// Block entries Reserve sets aside for every sequence regardless of length
+const RESERVE_MIN_BLOCKS = 64
+
+// slot is the space reserved in the file for one sequence
+type slot struct {
+    name    string
+    dnaSize int
+    blocks  int   // N and mask block entries
+    offset  int64 // byte offset of the record
+}
+
+// Return the bytes reserved for the record of s
+func (s *slot) size() (int64) {
+    return int64(16+2*RECORD_BLOCK_FIELD_LEN*s.blocks)+packedSize64(int64(s.dnaSize))
+}
+
+// reservation is the state of a two-phase write
+type reservation struct {
+    slots   []*slot
+    names   map[string]bool
+    out     *bufio.Writer
+    next    int   // index of the next slot to write
+    pos     int64 // bytes written
+    report  *WriteReport
+    done    bool
+}
+
+// Reserve declares a sequence of length bases to be written by a two-phase
+// write. Once all sequences are reserved WriteIndex writes the header and
+// index, and each sequence is then added as usual (Add, StartSequence and so
+// on) and written out immediately instead of being held in memory. This
+// streams a genome in a single pass to outputs which can't seek, such as a
+// pipe, when the lengths are known up front, for example from a .fai file.
+//
+// The record of each sequence has room for
+// length/RESERVE_BASES_PER_BLOCK+RESERVE_MIN_BLOCKS N and mask blocks, as
+// the offsets in the index can't depend on blocks not yet seen. Unused block
+// entries are left as zero padding after the packed DNA, which readers skip.
+// Use ReserveBlocks if the number of blocks is known.
+func (w *Writer) Reserve(name string, length int) (error) {
+    return w.ReserveBlocks(name, length, length/RESERVE_BASES_PER_BLOCK+RESERVE_MIN_BLOCKS)
+}
+
+// ReserveBlocks is Reserve with room for at most blocks N and mask blocks
+func (w *Writer) ReserveBlocks(name string, length, blocks int) (error) {
+    if w.closed {
+        return ErrClosed
+    }
+    if w.reserved == nil {
+        w.reserved = &reservation{names: make(map[string]bool)}
+    }
+    res := w.reserved
+    if res.out != nil {
+        return fmt.Errorf("Cannot reserve %s after the index was written", name)
+    }
+
+    err := w.checkName(name)
+    if err != nil {
+        return err
+    }
+    if res.names[name] {
+        return fmt.Errorf("Sequence %s is already reserved", name)
+    }
+    if length < 0 || uint64(length) > math.MaxUint32 {
+        return fmt.Errorf("Invalid length for %s: %d", name, length)
+    }
+    if blocks < 0 {
+        return fmt.Errorf("Invalid block count for %s: %d", name, blocks)
+    }
+
+    res.names[name] = true
+    res.slots = append(res.slots, &slot{name: name, dnaSize: length, blocks: blocks})
+
+    return nil
+}
+
+// WriteIndex writes the header and index of the sequences declared with
+// Reserve to out, in the order given by the Layout. The sequences must then
+// be added in that order and Finish (or Close) called once all are written.
+// Writers returned by Create write to their file when out is nil.
+func (w *Writer) WriteIndex(out io.Writer) (error) {
+    if w.closed {
+        return ErrClosed
+    }
+    res := w.reserved
+    if res == nil {
+        return fmt.Errorf("No sequences reserved")
+    }
+    if res.out != nil {
+        return fmt.Errorf("Index was already written")
+    }
+    if len(w.records) > 0 {
+        return fmt.Errorf("Sequences added before the index was written must be reserved")
+    }
+    if out == nil {
+        if w.file == nil {
+            return fmt.Errorf("No output to write to")
+        }
+        out = w.file
+    }
+
+    offsetLen := INDEX_OFFSET_LEN
+    switch w.version {
+    case VERSION:
+    case VERSION_LONG:
+        offsetLen = INDEX_OFFSET_LEN_LONG
+    default:
+        return fmt.Errorf("Unsupported version %d", w.version)
+    }
+    if w.layout.Alignment < 0 || w.layout.IndexPadding < 0 {
+        return fmt.Errorf("Invalid layout: %+v", w.layout)
+    }
+
+    switch w.layout.Order {
+    case NAME_ORDER:
+        sort.SliceStable(res.slots, func(i, j int) bool {
+            return res.slots[i].name < res.slots[j].name
+        })
+    case LENGTH_ORDER:
+        sort.SliceStable(res.slots, func(i, j int) bool {
+            a, b := res.slots[i], res.slots[j]
+            if a.dnaSize != b.dnaSize {
+                return a.dnaSize > b.dnaSize
+            }
+            return a.name < b.name
+        })
+    }
+
+    idxSize := 0
+    for _, s := range res.slots {
+        idxSize += INDEX_NAME_SIZE_LEN + len(s.name) + offsetLen
+    }
+
+    buf := make([]byte, HEADER_SIZE+idxSize+w.layout.IndexPadding)
+    binary.LittleEndian.PutUint32(buf[0:4], SIG)
+    binary.LittleEndian.PutUint32(buf[4:8], w.version)
+    binary.LittleEndian.PutUint32(buf[8:12], uint32(len(res.slots)))
+    binary.LittleEndian.PutUint32(buf[12:16], uint32(0))
+
+    offset := int64(len(buf))
+    idx := HEADER_SIZE
+    for _, s := range res.slots {
+        offset = w.layout.align(offset)
+        s.offset = offset
+        buf[idx] = uint8(len(s.name))
+        idx++
+        idx += copy(buf[idx:], s.name)
+        if offsetLen == INDEX_OFFSET_LEN_LONG {
+            binary.LittleEndian.PutUint64(buf[idx:idx+8], uint64(offset))
+        } else if offset > math.MaxUint32 {
+            return fmt.Errorf("Sequence %s starts past the 32-bit offset limit of version %d files", s.name, VERSION)
+        } else {
+            binary.LittleEndian.PutUint32(buf[idx:idx+4], uint32(offset))
+        }
+        idx += offsetLen
+        offset += s.size()
+    }
+
+    res.out = bufio.NewWriter(out)
+    res.report = &WriteReport{IndexSize: idxSize}
+    _, err := res.out.Write(buf)
+    if err != nil {
+        return err
+    }
+    res.pos = int64(len(buf))
+
+    return nil
+}
+
+// Write rec into the next reserved slot, which must be for sequence name
+func (w *Writer) writeReserved(name string, rec *seqRecord) (error) {
+    res := w.reserved
+    if res.next == len(res.slots) {
+        return fmt.Errorf("Sequence %s was not reserved", name)
+    }
+    s := res.slots[res.next]
+    if name != s.name {
+        if !res.names[name] {
+            return fmt.Errorf("Sequence %s was not reserved", name)
+        }
+        return fmt.Errorf("Sequence %s added out of order, expected %s", name, s.name)
+    }
+    if int(rec.dnaSize) != s.dnaSize {
+        return fmt.Errorf("Sequence %s has %d bases but %d were reserved", name, rec.dnaSize, s.dnaSize)
+    }
+    blocks := len(rec.nBlocks)+len(rec.mBlocks)
+    if blocks > s.blocks {
+        return fmt.Errorf("Sequence %s has %d N and mask blocks but %d were reserved", name, blocks, s.blocks)
+    }
+
+    if pad := s.offset-res.pos; pad > 0 {
+        _, err := res.out.Write(make([]byte, pad))
+        if err != nil {
+            return err
+        }
+    }
+
+    buf := rec.encode()
+    _, err := res.out.Write(buf)
+    if err != nil {
+        return err
+    }
+    _, err = res.out.Write(make([]byte, 2*RECORD_BLOCK_FIELD_LEN*(s.blocks-blocks)))
+    if err != nil {
+        return err
+    }
+    res.pos = s.offset+s.size()
+    res.next++
+
+    report := SequenceReport{
+        Name: name,
+        Offset: s.offset,
+        PackedOffset: s.offset+int64(len(buf)-len(rec.sequence)),
+        PackedSize: len(rec.sequence),
+        DnaSize: int(rec.dnaSize),
+    }
+    res.report.Sequences = append(res.report.Sequences, report)
+
+    if w.onWritten != nil {
+        err = res.out.Flush()
+        if err != nil {
+            return err
+        }
+        err = w.onWritten(&SequenceEvent{SequenceReport: report, Digest: rec.digest()})
+        if err != nil {
+            return fmt.Errorf("Written hook failed for %s: %w", name, err)
+        }
+    }
+
+    return nil
+}
+
+// Finish completes a two-phase write started with WriteIndex, failing if any
+// reserved sequence was not added. Close calls Finish if needed, and must
+// still be called for Writers returned by Create.
+func (w *Writer) Finish() (error) {
+    res := w.reserved
+    if res == nil || res.out == nil {
+        return fmt.Errorf("Index was not written")
+    }
+    if res.done {
+        return nil
+    }
+    if w.building != nil {
+        return fmt.Errorf("Sequence %s was started but not ended", w.building.name)
+    }
+    if res.next < len(res.slots) {
+        return fmt.Errorf("Sequence %s was reserved but not added", res.slots[res.next].name)
+    }
+
+    err := res.out.Flush()
+    if err != nil {
+        return err
+    }
+
+    res.report.Bytes = res.pos
+    w.report = res.report
+    res.done = true
+
+    return nil
+}
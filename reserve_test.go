@@ -0,0 +1,176 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "io"
+    "path/filepath"
+)
+
+// onlyWriter hides any other methods of the buffer, like a pipe
+type onlyWriter struct {
+    w io.Writer
+}
+
+func (o onlyWriter) Write(p []byte) (int, error) {
+    return o.w.Write(p)
+}
+
+func TestReserve(t *testing.T) {
+    seqs := map[string]string{
+        "chr1": "ACGTNNNNacgtACGTNNnnTTTT",
+        "chr2": "GGGGNNNcc",
+        "chrM": "acgt",
+    }
+
+    tests := []struct {
+        layout Layout
+        order  []string
+    }{
+        {Layout{}, []string{"chrM", "chr2", "chr1"}},
+        {Layout{Order: NAME_ORDER, Alignment: 64, IndexPadding: 100}, []string{"chr1", "chr2", "chrM"}},
+        {Layout{Order: LENGTH_ORDER}, []string{"chr1", "chr2", "chrM"}},
+    }
+
+    for _, test := range tests {
+        layout := test.layout
+        var out bytes.Buffer
+        w := NewWriter(WithLayout(layout))
+        w.Reserve("chrM", 4)
+        w.Reserve("chr2", 9)
+        w.ReserveBlocks("chr1", 24, 4)
+
+        err := w.WriteIndex(onlyWriter{&out})
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+
+        for _, name := range test.order {
+            if name == "chr2" {
+                w.StartSequence(name)
+                w.AppendChunk("GGGG")
+                w.AppendGap(3)
+                w.AppendChunk("cc")
+                err = w.EndSequence()
+            } else {
+                err = w.Add(name, seqs[name])
+            }
+            if err != nil {
+                t.Fatalf("Failed to add %s: %s", name, err)
+            }
+        }
+
+        err = w.Close()
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        if w.Report().Bytes != int64(out.Len()) {
+            t.Errorf("Invalid report size: %d != %d", w.Report().Bytes, out.Len())
+        }
+
+        tb, err := NewReader(bytes.NewReader(out.Bytes()), Strict())
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        for i, s := range w.Report().Sequences {
+            seq, err := tb.Read(s.Name)
+            if err != nil {
+                t.Fatalf("Failed to read %s: %s", s.Name, err)
+            }
+            if string(seq) != seqs[s.Name] {
+                t.Errorf("Invalid sequence %s: %s != %s", s.Name, seq, seqs[s.Name])
+            }
+            if layout.Alignment > 0 && s.Offset%int64(layout.Alignment) != 0 {
+                t.Errorf("Sequence %d %s not aligned: %d", i, s.Name, s.Offset)
+            }
+        }
+    }
+}
+
+func TestReserveErrors(t *testing.T) {
+    var out bytes.Buffer
+
+    w := NewWriter()
+    err := w.WriteIndex(&out)
+    if err == nil {
+        t.Errorf("Index written without reservations")
+    }
+
+    w.Reserve("chr1", 8)
+    w.ReserveBlocks("chr2", 8, 1)
+    if err := w.Reserve("chr1", 8); err == nil {
+        t.Errorf("Duplicate reservation accepted")
+    }
+    if err := w.Reserve("chr3", -1); err == nil {
+        t.Errorf("Negative length accepted")
+    }
+    if err := w.WriteTo(&out); err == nil {
+        t.Errorf("WriteTo accepted reserved sequences")
+    }
+
+    err = w.WriteIndex(&out)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if err := w.Reserve("chr3", 8); err == nil {
+        t.Errorf("Reservation accepted after the index was written")
+    }
+
+    tests := []struct {
+        name string
+        seq  string
+    }{
+        {"chr2", "ACGTACGT"},  // out of order
+        {"chrX", "ACGTACGT"},  // not reserved
+        {"chr1", "ACGT"},      // wrong length
+    }
+    for _, test := range tests {
+        if err := w.Add(test.name, test.seq); err == nil {
+            t.Errorf("Invalid sequence %s accepted", test.name)
+        }
+    }
+
+    err = w.Add("chr1", "ACGTACGT")
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if err := w.Add("chr2", "NNacgtNN"); err == nil {
+        t.Errorf("Sequence with too many blocks accepted")
+    }
+    if err := w.Finish(); err == nil {
+        t.Errorf("Finished without all reserved sequences")
+    }
+}
+
+func TestReserveCreate(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "reserved.2bit")
+    w, err := Create(path)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    w.Reserve("chr1", 8)
+    err = w.WriteIndex(nil)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    err = w.Add("chr1", "ACGTacgt")
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    err = w.Close()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    seqs, err := ReadAll(path)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if seqs["chr1"] != "ACGTacgt" {
+        t.Errorf("Invalid sequence: %s", seqs["chr1"])
+    }
+}
@@ -0,0 +1,133 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "fmt"
+    "io"
+    "io/ioutil"
+)
+
+// streamReader tracks the position of a non-seekable reader. Seeking is only
+// supported to the current position.
+type streamReader struct {
+    reader    io.Reader
+    pos       int64
+}
+
+func (s *streamReader) Read(p []byte) (int, error) {
+    n, err := s.reader.Read(p)
+    s.pos += int64(n)
+    return n, err
+}
+
+func (s *streamReader) Seek(offset int64, whence int) (int64, error) {
+    if (whence == 0 && offset == s.pos) || (whence == 1 && offset == 0) {
+        return s.pos, nil
+    }
+
+    return s.pos, fmt.Errorf("Input is not seekable")
+}
+
+// Scanner reads every sequence of a 2bit file front-to-back exactly once
+// without seeking. This allows whole-file operations on non-seekable inputs
+// such as pipes. The file index is buffered in memory.
+type Scanner struct {
+    tb        *Reader
+    stream    *streamReader
+    names     []string
+    next      int
+    name      string
+    seq       []byte
+    err       error
+}
+
+// NewScanner returns a new Scanner which reads from r. The header and file
+// index are read immediately.
+func NewScanner(r io.Reader) (*Scanner, error) {
+    stream := &streamReader{reader: r}
+
+    tb := new(Reader)
+    tb.reader = stream
+    tb.size = -1
+    tb.gap = BASE_N
+
+    err := tb.parseHeader()
+    if err != nil {
+        return nil, err
+    }
+
+    err = tb.parseIndex()
+    if err != nil {
+        return nil, err
+    }
+
+    return &Scanner{tb: tb, stream: stream, names: tb.namesByOffset()}, nil
+}
+
+// Scan advances to the next sequence in file order. It returns false when
+// there are no more sequences or an error occurred.
+func (s *Scanner) Scan() (bool) {
+    if s.err != nil || s.next >= len(s.names) {
+        return false
+    }
+
+    s.name = s.names[s.next]
+    s.next++
+
+    offset := s.tb.index[s.name]
+    if offset < s.stream.pos {
+        s.err = fmt.Errorf("Sequence %s at offset %d overlaps previous record", s.name, offset)
+        return false
+    }
+
+    _, err := io.CopyN(ioutil.Discard, s.stream, offset-s.stream.pos)
+    if err != nil {
+        s.err = fmt.Errorf("Failed to skip to sequence %s: %s", s.name, err)
+        return false
+    }
+
+    rec, err := s.tb.parseRecord(s.name, true)
+    if err != nil {
+        s.err = err
+        return false
+    }
+
+    // read without trusting dnaSize for the allocation, so a corrupt size
+    // costs no more memory than the data actually present
+    bases := int(rec.dnaSize)
+    size := int64(packedSize(bases))
+    packed, err := ioutil.ReadAll(io.LimitReader(s.stream, size))
+    if err != nil {
+        s.err = fmt.Errorf("Failed to read dna bytes: %s", err)
+        return false
+    }
+    if int64(len(packed)) < size {
+        s.err = fmt.Errorf("%w: %s expected %d packed bytes, found %d", ErrTruncated, s.name, size, len(packed))
+        return false
+    }
+
+    s.seq = make([]byte, bases)
+    decode(s.seq, packed, 0, bases)
+    rec.applyBlocks(s.seq, 0, bases, s.tb.gap)
+
+    return true
+}
+
+// Name returns the name of the current sequence
+func (s *Scanner) Name() (string) {
+    return s.name
+}
+
+// Bytes returns the current sequence. The slice is not reused by later calls
+// to Scan.
+func (s *Scanner) Bytes() ([]byte) {
+    return s.seq
+}
+
+// Err returns the first error encountered by the Scanner
+func (s *Scanner) Err() (error) {
+    return s.err
+}
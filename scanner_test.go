@@ -0,0 +1,56 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "errors"
+    "io/ioutil"
+)
+
+func TestScanner(t *testing.T) {
+    data, err := ioutil.ReadFile("examples/simple.2bit")
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    // hide Seek from the scanner
+    s, err := NewScanner(struct{ *bytes.Buffer }{bytes.NewBuffer(data)})
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    count := 0
+    for s.Scan() {
+        count++
+        if s.Name() != "ex1" {
+            t.Errorf("Invalid sequence name: %s != %s", s.Name(), "ex1")
+        }
+        if string(s.Bytes()) != "ACTgcctttnnnNantnaCgc" {
+            t.Errorf("Invalid sequence: %s", s.Bytes())
+        }
+    }
+
+    if s.Err() != nil {
+        t.Errorf("%s", s.Err())
+    }
+
+    if count != 1 {
+        t.Errorf("Invalid sequence count: %d != %d", count, 1)
+    }
+
+    s, err = NewScanner(bytes.NewBuffer(data[:len(data)-1]))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    for s.Scan() {
+    }
+
+    if !errors.Is(s.Err(), ErrTruncated) {
+        t.Errorf("Expected ErrTruncated, got: %v", s.Err())
+    }
+}
@@ -0,0 +1,286 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "io"
+    "os"
+    "crypto/sha256"
+    "encoding/json"
+    "fmt"
+    "hash/crc32"
+    "hash/fnv"
+)
+
+// Extension appended to a 2bit file path to name its sidecar
+const SIDECAR_EXT = ".idx"
+
+// Bloom filter sizing: bits per name and number of hash functions
+const bloomBitsPerName = 10
+const bloomHashes = 7
+
+// SidecarEntry describes one sequence in a Sidecar
+type SidecarEntry struct {
+    Name     string `json:"name"`
+    Length   int    `json:"length"`
+    Digest   string `json:"md5"`
+    CRC32C   string `json:"crc32c,omitempty"` // of the packed bases, absent in older sidecars
+}
+
+// Sidecar is a small index stored next to a 2bit file (genome.2bit.idx)
+// listing its sequence names, lengths and digests along with a bloom filter of
+// the names. Tools searching many 2bit files can load the sidecars instead of
+// opening and parsing each file. The CRC32C of the packed bases of each
+// sequence lets Reader.VerifyQuick spot-check the file for bit rot.
+type Sidecar struct {
+    Sequences   []SidecarEntry `json:"sequences"`
+    Bloom       []byte         `json:"bloom"`
+    Checksum    string         `json:"checksum"`
+}
+
+// Build a Sidecar for all sequences in r, listed in file order so the same
+// file always gives the same sidecar
+func BuildSidecar(r *Reader) (*Sidecar, error) {
+    s := new(Sidecar)
+
+    lengths, err := r.LengthAll()
+    if err != nil {
+        return nil, err
+    }
+
+    for _, name := range r.namesByOffset() {
+        digest, err := r.Digest(name)
+        if err != nil {
+            return nil, err
+        }
+        crc, err := r.packedCRC(name)
+        if err != nil {
+            return nil, err
+        }
+        s.Sequences = append(s.Sequences, SidecarEntry{Name: name, Length: lengths[name], Digest: digest, CRC32C: crc})
+    }
+
+    s.Bloom = make([]byte, (len(s.Sequences)*bloomBitsPerName+7)/8+1)
+    for _, e := range s.Sequences {
+        for _, bit := range s.bloomBits(e.Name) {
+            s.Bloom[bit/8] |= 1 << uint(bit%8)
+        }
+    }
+
+    s.Checksum, err = s.checksum()
+    if err != nil {
+        return nil, err
+    }
+
+    return s, nil
+}
+
+// Return the hex encoded CRC32C (Castagnoli) of the packed bases of sequence
+// name, read straight from the file without decoding
+func (r *Reader) packedCRC(name string) (string, error) {
+    rec, err := r.parseRecord(name, true)
+    if err != nil {
+        return "", err
+    }
+
+    _, err = r.reader.Seek(rec.offset, 0)
+    if err != nil {
+        return "", err
+    }
+
+    h := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+    _, err = io.CopyN(h, r.reader, packedSize64(int64(rec.dnaSize)))
+    if err != nil {
+        return "", fmt.Errorf("Failed to read packed dna of %s: %s", name, err)
+    }
+
+    return fmt.Sprintf("%08x", h.Sum32()), nil
+}
+
+// Return the bloom filter bit positions for name using double hashing
+func (s *Sidecar) bloomBits(name string) ([]uint64) {
+    h := fnv.New64a()
+    h.Write([]byte(name))
+    h1 := h.Sum64()
+    h2 := h1>>33 | h1<<31 | 1
+
+    m := uint64(len(s.Bloom)*8)
+    bits := make([]uint64, bloomHashes)
+    for i := range bits {
+        bits[i] = (h1 + uint64(i)*h2) % m
+    }
+
+    return bits
+}
+
+// Compute the checksum over the sidecar contents excluding the checksum
+func (s *Sidecar) checksum() (string, error) {
+    c := *s
+    c.Checksum = ""
+    data, err := json.Marshal(&c)
+    if err != nil {
+        return "", err
+    }
+
+    return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
+// MayContain reports whether the sequence name may be in the 2bit file using
+// only the bloom filter. False positives are possible, false negatives are not.
+func (s *Sidecar) MayContain(name string) (bool) {
+    if len(s.Bloom) == 0 {
+        return false
+    }
+
+    for _, bit := range s.bloomBits(name) {
+        if s.Bloom[bit/8] & (1 << uint(bit%8)) == 0 {
+            return false
+        }
+    }
+
+    return true
+}
+
+// Lookup returns the entry for sequence name
+func (s *Sidecar) Lookup(name string) (*SidecarEntry, bool) {
+    if !s.MayContain(name) {
+        return nil, false
+    }
+
+    for i := range s.Sequences {
+        if s.Sequences[i].Name == name {
+            return &s.Sequences[i], true
+        }
+    }
+
+    return nil, false
+}
+
+// Write the sidecar in JSON format to out
+func (s *Sidecar) Write(out io.Writer) (error) {
+    return json.NewEncoder(out).Encode(s)
+}
+
+// Read a sidecar from in and verify its checksum
+func ReadSidecar(in io.Reader) (*Sidecar, error) {
+    s := new(Sidecar)
+    err := json.NewDecoder(in).Decode(s)
+    if err != nil {
+        return nil, fmt.Errorf("Failed to read sidecar: %s", err)
+    }
+
+    sum, err := s.checksum()
+    if err != nil {
+        return nil, err
+    }
+
+    if sum != s.Checksum {
+        return nil, fmt.Errorf("Invalid sidecar checksum: %s != %s", s.Checksum, sum)
+    }
+
+    return s, nil
+}
+
+// Build the sidecar for the 2bit file at path and save it to path+SIDECAR_EXT
+func BuildSidecarFile(path string) (*Sidecar, error) {
+    r, err := Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer r.Close()
+
+    s, err := BuildSidecar(r)
+    if err != nil {
+        return nil, err
+    }
+
+    f, err := os.Create(path+SIDECAR_EXT)
+    if err != nil {
+        return nil, err
+    }
+
+    err = s.Write(f)
+    if err != nil {
+        f.Close()
+        return nil, err
+    }
+
+    return s, f.Close()
+}
+
+// Load the sidecar of the 2bit file at path
+func LoadSidecar(path string) (*Sidecar, error) {
+    f, err := os.Open(path+SIDECAR_EXT)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+
+    return ReadSidecar(f)
+}
+
+// Return the 2bit files in paths containing sequence name, consulting only
+// their sidecars
+func LocateSequence(paths []string, name string) ([]string, error) {
+    found := make([]string, 0)
+    for _, path := range paths {
+        s, err := LoadSidecar(path)
+        if err != nil {
+            return nil, err
+        }
+
+        if _, ok := s.Lookup(name); ok {
+            found = append(found, path)
+        }
+    }
+
+    return found, nil
+}
+
+// WithSidecar gives the Reader the sidecar of its file for VerifyQuick.
+// Readers returned by Open load path+SIDECAR_EXT when first needed instead.
+func WithSidecar(s *Sidecar) (ReadOption) {
+    return func(r *Reader) (error) {
+        r.sidecar = s
+        return nil
+    }
+}
+
+// VerifyQuick checks the packed bases of sequence name against the CRC32C
+// recorded in the sidecar of the file, reporting a mismatch with ErrCorrupt.
+// Only the checksum of the packed bytes is computed, nothing is decoded, so
+// whole genome archives can be spot-checked for bit rot cheaply. N and mask
+// blocks are not covered, use Digest for a full check. Sidecars built
+// before CRC32C were recorded must be rebuilt with BuildSidecarFile.
+func (r *Reader) VerifyQuick(name string) (error) {
+    if r.sidecar == nil {
+        if len(r.path) == 0 {
+            return fmt.Errorf("No sidecar: use WithSidecar or open the file by path")
+        }
+        s, err := LoadSidecar(r.path)
+        if err != nil {
+            return err
+        }
+        r.sidecar = s
+    }
+
+    e, ok := r.sidecar.Lookup(name)
+    if !ok {
+        return fmt.Errorf("Sequence %s is not in the sidecar", name)
+    }
+    if len(e.CRC32C) == 0 {
+        return fmt.Errorf("Sidecar has no CRC32C for %s, rebuild it", name)
+    }
+
+    crc, err := r.packedCRC(name)
+    if err != nil {
+        return err
+    }
+    if crc != e.CRC32C {
+        return fmt.Errorf("%w: packed bases of %s have CRC32C %s, sidecar has %s", ErrCorrupt, name, crc, e.CRC32C)
+    }
+
+    return nil
+}
@@ -0,0 +1,175 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "errors"
+    "io/ioutil"
+    "path/filepath"
+    "reflect"
+)
+
+func TestSidecar(t *testing.T) {
+    dir := t.TempDir()
+    paths := []string{filepath.Join(dir, "a.2bit"), filepath.Join(dir, "b.2bit")}
+    seqs := []map[string]string{
+        {"chr1": "ACGT", "chr2": "acgtNN"},
+        {"chrM": "GATTACA"},
+    }
+
+    for i, path := range paths {
+        w, err := Create(path)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        for name, seq := range seqs[i] {
+            w.Add(name, seq)
+        }
+        err = w.Close()
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+
+        _, err = BuildSidecarFile(path)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+    }
+
+    found, err := LocateSequence(paths, "chrM")
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    if !reflect.DeepEqual(found, paths[1:]) {
+        t.Errorf("Invalid files for chrM: %v", found)
+    }
+
+    s, err := LoadSidecar(paths[0])
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    e, ok := s.Lookup("chr1")
+    if !ok || e.Length != 4 || e.Digest != "f1f8f4bf413b16ad135722aa4591043e" {
+        t.Errorf("Invalid sidecar entry: %#v", e)
+    }
+
+    var buf bytes.Buffer
+    s.Checksum = "bad"
+    s.Write(&buf)
+    _, err = ReadSidecar(&buf)
+    if err == nil {
+        t.Errorf("Expected checksum error")
+    }
+}
+
+func TestVerifyQuick(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "a.2bit")
+    w, err := Create(path)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    w.Add("chr1", "ACGTACGTNNNN")
+    w.Add("chr2", "gattaca")
+    err = w.Close()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    r, err := Open(path)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    err = r.VerifyQuick("chr1")
+    if err == nil {
+        t.Errorf("Expected error without a sidecar")
+    }
+    r.Close()
+
+    s, err := BuildSidecarFile(path)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    e, _ := s.Lookup("chr2")
+    if len(e.CRC32C) != 8 {
+        t.Errorf("Invalid sidecar CRC32C: %q", e.CRC32C)
+    }
+
+    r, err = Open(path)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    offsets, err := r.Offsets()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    for _, name := range []string{"chr1", "chr2"} {
+        err = r.VerifyQuick(name)
+        if err != nil {
+            t.Errorf("%s: %s", name, err)
+        }
+    }
+    r.Close()
+
+    // flip a bit in the packed bases of chr1
+    data, err := ioutil.ReadFile(path)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    data[offsets["chr1"].Offset] ^= 0x10
+
+    r, err = NewReader(bytes.NewReader(data), WithSidecar(s))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    err = r.VerifyQuick("chr1")
+    if !errors.Is(err, ErrCorrupt) {
+        t.Errorf("Expected ErrCorrupt: %v", err)
+    }
+    err = r.VerifyQuick("chr2")
+    if err != nil {
+        t.Errorf("%s", err)
+    }
+
+    e.CRC32C = ""
+    err = r.VerifyQuick("chr2")
+    if err == nil {
+        t.Errorf("Expected error for sidecar without CRC32C")
+    }
+}
+
+func TestSidecarReproducible(t *testing.T) {
+    w := NewWriter()
+    for _, name := range []string{"chr3", "chr1", "chrM", "chr2", "chrX", "chr10"} {
+        w.Add(name, "ACGTacgt")
+    }
+    var file bytes.Buffer
+    w.WriteTo(&file)
+    tb, err := NewReader(bytes.NewReader(file.Bytes()))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    var first bytes.Buffer
+    for i := 0; i < 10; i++ {
+        s, err := BuildSidecar(tb)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        var out bytes.Buffer
+        s.Write(&out)
+        if i == 0 {
+            first = out
+            if s.Sequences[0].Name != "chr3" || s.Sequences[5].Name != "chr10" {
+                t.Errorf("Sidecar not in file order: %v", s.Sequences)
+            }
+        } else if !bytes.Equal(out.Bytes(), first.Bytes()) {
+            t.Fatalf("Sidecar differs between builds")
+        }
+    }
+}
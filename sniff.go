@@ -0,0 +1,38 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "io"
+    "encoding/binary"
+)
+
+// Sniff reports whether r holds a 2bit file by checking only the signature
+// in the first HEADER_SIZE bytes, along with the byte order and version. The
+// version may be one this package does not support (see VERSION and
+// VERSION_LONG). Input shorter than a header is not a 2bit file; only other
+// read errors are returned.
+func Sniff(r io.ReaderAt) (bool, binary.ByteOrder, int, error) {
+    b := make([]byte, HEADER_SIZE)
+    n, err := r.ReadAt(b, 0)
+    if n < HEADER_SIZE {
+        if err == io.EOF || err == io.ErrUnexpectedEOF {
+            err = nil
+        }
+        return false, nil, 0, err
+    }
+
+    var order binary.ByteOrder
+    switch uint32(SIG) {
+    case binary.BigEndian.Uint32(b[0:4]):
+        order = binary.BigEndian
+    case binary.LittleEndian.Uint32(b[0:4]):
+        order = binary.LittleEndian
+    default:
+        return false, nil, 0, nil
+    }
+
+    return true, order, int(order.Uint32(b[4:8])), nil
+}
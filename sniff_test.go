@@ -0,0 +1,38 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "encoding/binary"
+    "github.com/aebruno/twobit/testfixtures"
+)
+
+func TestSniff(t *testing.T) {
+    w := NewWriter(FileVersion(VERSION_LONG))
+    w.Add("chr1", "ACGT")
+    var out bytes.Buffer
+    w.WriteTo(&out)
+
+    ok, order, version, err := Sniff(bytes.NewReader(out.Bytes()))
+    if err != nil || !ok || order != binary.LittleEndian || version != VERSION_LONG {
+        t.Errorf("Invalid sniff: %v %v %d %v", ok, order, version, err)
+    }
+
+    b := &testfixtures.Builder{ByteOrder: binary.BigEndian}
+    b.Add("chr1", "ACGT")
+    ok, order, version, err = Sniff(bytes.NewReader(b.Bytes()))
+    if err != nil || !ok || order != binary.BigEndian || version != VERSION {
+        t.Errorf("Invalid big endian sniff: %v %v %d %v", ok, order, version, err)
+    }
+
+    for _, data := range []string{">chr1\nACGT\nACGTACGTACGTACGT\n", "", "short"} {
+        ok, _, _, err = Sniff(bytes.NewReader([]byte(data)))
+        if err != nil || ok {
+            t.Errorf("Invalid sniff of %q: %v %v", data, ok, err)
+        }
+    }
+}
@@ -0,0 +1,136 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "os"
+    "errors"
+    "fmt"
+    "io/ioutil"
+)
+
+// ErrTempFull is returned when spilling a sequence to disk would exceed the
+// TempStorage limit
+var ErrTempFull = errors.New("twobit: temp storage limit reached")
+
+// TempCleanup selects when the temp files of a Writer are removed
+type TempCleanup int
+
+const (
+    TEMP_REMOVE_ON_CLOSE TempCleanup = iota // remove temp files when the Writer is closed
+    TEMP_UNLINK                             // unlink temp files once opened so a crash can't leave them behind (not on Windows)
+    TEMP_KEEP                               // never remove temp files, for debugging
+)
+
+// TempStorage configures where and how much a Writer spills to disk
+type TempStorage struct {
+    Dir       string      // directory for temp files, default os.TempDir which honors TMPDIR
+    MaxBytes  int64       // limit on the total size of temp files, 0 for none
+    Cleanup   TempCleanup
+}
+
+// SpillToDisk makes the Writer keep the packed bases of each sequence in a
+// temp file instead of in memory until they are written, so a genome can be
+// converted with little more memory than its largest sequence. Point
+// ts.Dir at a large partition as default temp partitions are often too small
+// for whole genomes. Adding a sequence which would take the temp file past
+// ts.MaxBytes fails with ErrTempFull. Close the Writer, even one returned by
+// NewWriter, to remove the temp file.
+func SpillToDisk(ts TempStorage) (WriterOption) {
+    return func(w *Writer) {
+        w.spill = &spillFile{opts: ts}
+    }
+}
+
+// spillFile holds the packed bases of spilled records
+type spillFile struct {
+    opts  TempStorage
+    file  *os.File
+    size  int64
+}
+
+// Move the packed bases of rec to the spill file
+func (s *spillFile) store(rec *seqRecord) (error) {
+    n := int64(len(rec.sequence))
+    if n == 0 {
+        return nil
+    }
+    if s.opts.MaxBytes > 0 && s.size+n > s.opts.MaxBytes {
+        return fmt.Errorf("%w: %d bytes used of %d", ErrTempFull, s.size, s.opts.MaxBytes)
+    }
+
+    if s.file == nil {
+        f, err := ioutil.TempFile(s.opts.Dir, "twobit-spill-")
+        if err != nil {
+            return err
+        }
+        if s.opts.Cleanup == TEMP_UNLINK {
+            err = os.Remove(f.Name())
+            if err != nil {
+                f.Close()
+                return err
+            }
+        }
+        s.file = f
+    }
+
+    _, err := s.file.WriteAt(rec.sequence, s.size)
+    if err != nil {
+        return fmt.Errorf("Failed to spill sequence to %s: %s", s.file.Name(), err)
+    }
+    rec.spill = s
+    rec.spillAt = s.size
+    rec.sequence = nil
+    s.size += n
+
+    return nil
+}
+
+// Close the spill file, removing it unless it is kept
+func (s *spillFile) close() (error) {
+    if s.file == nil {
+        return nil
+    }
+
+    err := s.file.Close()
+    if s.opts.Cleanup == TEMP_REMOVE_ON_CLOSE {
+        rerr := os.Remove(s.file.Name())
+        if err == nil {
+            err = rerr
+        }
+    }
+    s.file = nil
+    s.size = 0
+
+    return err
+}
+
+// Return rec with its packed bases in memory, as a copy if they were spilled
+func (rec *seqRecord) loaded() (*seqRecord, error) {
+    if rec.spill == nil {
+        return rec, nil
+    }
+
+    c := *rec
+    c.spill = nil
+    c.sequence = make([]byte, packedSize(int(rec.dnaSize)))
+    _, err := rec.spill.file.ReadAt(c.sequence, rec.spillAt)
+    if err != nil {
+        return nil, fmt.Errorf("Failed to read spilled sequence: %s", err)
+    }
+
+    return &c, nil
+}
+
+// Read the packed bases of rec back into memory if they were spilled
+func (rec *seqRecord) unspill() (error) {
+    c, err := rec.loaded()
+    if err != nil {
+        return err
+    }
+    *rec = *c
+
+    return nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "errors"
+    "io/ioutil"
+    "path/filepath"
+    "strings"
+)
+
+// Add the same sequences to w and return what it writes
+func spillOutput(t *testing.T, w *Writer) ([]byte) {
+    w.Add("chr1", "ACGTNNNNacgtA")
+    w.Add("empty", "")
+    w.StartSequence("chr2")
+    w.AppendChunk(strings.Repeat("GATTACA", 100))
+    w.AppendGap(10)
+    err := w.EndSequence()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    var out bytes.Buffer
+    err = w.WriteTo(&out)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    return out.Bytes()
+}
+
+// Returns the number of files in dir
+func countFiles(t *testing.T, dir string) (int) {
+    files, err := ioutil.ReadDir(dir)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    return len(files)
+}
+
+func TestSpillToDisk(t *testing.T) {
+    good := spillOutput(t, NewWriter())
+
+    dir := t.TempDir()
+    w := NewWriter(SpillToDisk(TempStorage{Dir: dir}))
+    got := spillOutput(t, w)
+    if !bytes.Equal(got, good) {
+        t.Errorf("Spilled output differs")
+    }
+    if w.records["chr1"].sequence != nil || countFiles(t, dir) != 1 {
+        t.Errorf("Sequences not spilled to %s", dir)
+    }
+    err := w.Close()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if countFiles(t, dir) != 0 {
+        t.Errorf("Temp file not removed on close")
+    }
+
+    w = NewWriter(SpillToDisk(TempStorage{Dir: dir, Cleanup: TEMP_UNLINK}))
+    got = spillOutput(t, w)
+    if !bytes.Equal(got, good) || countFiles(t, dir) != 0 {
+        t.Errorf("Unlinked spill failed")
+    }
+    w.Close()
+
+    w = NewWriter(SpillToDisk(TempStorage{Dir: dir, Cleanup: TEMP_KEEP}))
+    spillOutput(t, w)
+    w.Close()
+    if countFiles(t, dir) != 1 {
+        t.Errorf("Temp file not kept")
+    }
+
+    tmp := t.TempDir()
+    t.Setenv("TMPDIR", tmp)
+    w = NewWriter(SpillToDisk(TempStorage{}))
+    w.Add("chr1", "ACGT")
+    if countFiles(t, tmp) != 1 {
+        t.Errorf("TMPDIR not honored")
+    }
+    w.Close()
+}
+
+func TestSpillLimit(t *testing.T) {
+    w := NewWriter(SpillToDisk(TempStorage{Dir: t.TempDir(), MaxBytes: 4}))
+    defer w.Close()
+
+    err := w.Add("chr1", "ACGTACGTACGTACGT")
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    err = w.Add("chr2", "A")
+    if !errors.Is(err, ErrTempFull) {
+        t.Errorf("Expected ErrTempFull: %v", err)
+    }
+}
+
+func TestSpillSplit(t *testing.T) {
+    dir := t.TempDir()
+    fasta := ">chr1\nAAAAAAAAAA\n>chr2\nCCCCC\n>chr3\nGGGGGGGGGG\n"
+    opts := SplitOptions{MaxBases: 15, Pattern: filepath.Join(dir, "part%d.2bit")}
+
+    _, err := ImportFastaSplit(strings.NewReader(fasta), opts, SpillToDisk(TempStorage{Dir: t.TempDir()}))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    seqs, err := ReadAll(filepath.Join(dir, "part2.2bit"))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if seqs["chr3"] != "GGGGGGGGGG" {
+        t.Errorf("Invalid moved sequence: %v", seqs)
+    }
+}
@@ -0,0 +1,160 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "fmt"
+    "io"
+)
+
+// RangeStats are base composition statistics for a range of a sequence
+type RangeStats struct {
+    A        int
+    C        int
+    G        int
+    T        int
+    N        int     // bases within N blocks
+    Masked   int     // bases within mask blocks, including masked Ns
+    GC       float64 // (G+C)/(A+C+G+T), 0 if there are no non-N bases
+}
+
+// Compute base composition statistics for sequence name from start to end
+// (see ReadRange for range semantics). Counts are taken directly from the
+// packed bytes and block tables in a single pass without building the
+// sequence.
+func (r *Reader) RangeStats(name string, start, end int) (*RangeStats, error) {
+    rec, err := r.parseRecord(name, true)
+    if err != nil {
+        return nil, err
+    }
+
+    start, end, err = clampRange(start, end, int(rec.dnaSize))
+    if err != nil {
+        return nil, err
+    }
+
+    return r.rangeStats(rec.sortedBlocks(), start, end, new(blockCursor))
+}
+
+// Return rec with its N and mask blocks sorted by start, copying them only if
+// they are not already
+func (rec *seqRecord) sortedBlocks() (*seqRecord) {
+    sorted := func(bs Blocks) (bool) {
+        for i := 1; i < len(bs); i++ {
+            if bs[i].Start < bs[i-1].Start {
+                return false
+            }
+        }
+        return true
+    }
+    if sorted(rec.nBlocks) && sorted(rec.mBlocks) {
+        return rec
+    }
+
+    c := *rec
+    c.nBlocks = rec.nBlocks.copy()
+    c.nBlocks.Sort()
+    c.mBlocks = rec.mBlocks.copy()
+    c.mBlocks.Sort()
+    return &c
+}
+
+// blockCursor is the first N and mask block of a record which may overlap
+// the next range. Computing stats for increasing ranges with one cursor
+// visits each block about once instead of once per range.
+type blockCursor struct {
+    n  int
+    m  int
+}
+
+// Return the bases of the sorted blocks bs within start to end, advancing
+// *i past blocks ending before start
+func sweepBlocks(bs Blocks, i *int, start, end int) (int) {
+    for *i < len(bs) && bs[*i].End() <= start {
+        *i++
+    }
+
+    count := 0
+    for _, b := range bs[*i:] {
+        if b.Start >= end {
+            break
+        }
+        lo, hi := b.clip(start, end)
+        if lo < hi {
+            count += hi-lo
+        }
+    }
+
+    return count
+}
+
+// Compute stats for start to end of rec, whose blocks must be sorted, using
+// cursor c. Ranges computed with the same cursor must not start before the
+// previous one.
+func (r *Reader) rangeStats(rec *seqRecord, start, end int, c *blockCursor) (*RangeStats, error) {
+    stats := new(RangeStats)
+    stats.N = sweepBlocks(rec.nBlocks, &c.n, start, end)
+    stats.Masked = sweepBlocks(rec.mBlocks, &c.m, start, end)
+
+    if start >= end || stats.N == end-start {
+        return stats, nil
+    }
+
+    first := start/BASES_PER_BYTE
+    size := packedSize(end)-first
+
+    _, err := r.reader.Seek(rec.offset+int64(first), 0)
+    if err != nil {
+        return nil, err
+    }
+
+    if r.buf == nil {
+        r.buf = make([]byte, defaultBufSize)
+    }
+
+    var counts [4]int
+    nb := c.n
+    pos := first*BASES_PER_BYTE
+    for size > 0 {
+        sz := len(r.buf)
+        if size < sz {
+            sz = size
+        }
+
+        n, err := io.ReadFull(r.reader, r.buf[0:sz])
+        if err != nil {
+            return nil, fmt.Errorf("Failed to read %d dna bytes, got %d: %s", sz, n, err)
+        }
+
+        for _, base := range r.buf[0:sz] {
+            for j := 0; j < BASES_PER_BYTE; j++ {
+                p := pos+j
+                if p < start || p >= end {
+                    continue
+                }
+                for nb < len(rec.nBlocks) && rec.nBlocks[nb].End() <= p {
+                    nb++
+                }
+                if nb < len(rec.nBlocks) && rec.nBlocks[nb].Start <= p {
+                    continue
+                }
+                counts[(base >> uint(6-2*j)) & 0x3]++
+            }
+            pos += BASES_PER_BYTE
+        }
+        size -= sz
+    }
+
+    stats.T = counts[ENCODE_T]
+    stats.C = counts[ENCODE_C]
+    stats.A = counts[ENCODE_A]
+    stats.G = counts[ENCODE_G]
+
+    if total := stats.A+stats.C+stats.G+stats.T; total > 0 {
+        stats.GC = float64(stats.G+stats.C) / float64(total)
+    }
+
+    return stats, nil
+}
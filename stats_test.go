@@ -0,0 +1,37 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+)
+
+func TestRangeStats(t *testing.T) {
+    tb, err := openTestTwoBit()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    // ACTgcctttnnnNantnaCgc
+    stats, err := tb.RangeStats("ex1", 0, 0)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    good := RangeStats{A: 3, C: 5, G: 2, T: 5, N: 6, Masked: 16, GC: 7.0/15.0}
+    if *stats != good {
+        t.Errorf("Invalid stats: %+v != %+v", *stats, good)
+    }
+
+    stats, err = tb.RangeStats("ex1", 10, 15)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    good = RangeStats{A: 1, N: 4, Masked: 4}
+    if *stats != good {
+        t.Errorf("Invalid stats: %+v != %+v", *stats, good)
+    }
+}
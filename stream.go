@@ -0,0 +1,200 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "io"
+    "bufio"
+    "bytes"
+    "encoding/binary"
+    "fmt"
+)
+
+// Magic bytes starting a framed sequence stream
+const STREAM_MAGIC = "2BITSTRM"
+
+// Frame types in a sequence stream
+const (
+    STREAM_END = 0
+    STREAM_SEQ = 1
+)
+
+// ExportStream writes every sequence of r to out as a framed binary stream.
+// The stream starts with STREAM_MAGIC followed by one frame per sequence and
+// an end frame. All integers are little endian uint32.
+//
+//   type (1 byte, STREAM_SEQ)
+//   name length (1 byte), name
+//   dnaSize
+//   nBlockCount, nBlockStarts, nBlockSizes
+//   mBlockCount, mBlockStarts, mBlockSizes
+//   packedDNA (dnaSize+3)/4 bytes
+//   ...
+//   type (1 byte, STREAM_END)
+//
+// Frames are self-contained so the stream can be consumed incrementally, for
+// example over a pipe or network connection, with ImportStream.
+func ExportStream(r *Reader, out io.Writer) (error) {
+    w := bufio.NewWriter(out)
+    _, err := w.WriteString(STREAM_MAGIC)
+    if err != nil {
+        return err
+    }
+
+    buf := make([]byte, 4)
+    putUint32 := func(v uint32) {
+        binary.LittleEndian.PutUint32(buf, v)
+        w.Write(buf)
+    }
+    putBlocks := func(blocks Blocks) {
+        putUint32(uint32(len(blocks)))
+        for _, b := range blocks {
+            putUint32(uint32(b.Start))
+        }
+        for _, b := range blocks {
+            putUint32(uint32(b.Length))
+        }
+    }
+
+    for _, name := range r.namesByOffset() {
+        rec, err := r.parseRecord(name, true)
+        if err != nil {
+            return err
+        }
+
+        packed, err := r.ReadPackedRange(name, 0, 0)
+        if err != nil {
+            return err
+        }
+
+        w.WriteByte(STREAM_SEQ)
+        w.WriteByte(byte(len(name)))
+        w.WriteString(name)
+        putUint32(rec.dnaSize)
+        putBlocks(rec.nBlocks)
+        putBlocks(rec.mBlocks)
+        _, err = w.Write(packed)
+        if err != nil {
+            return err
+        }
+    }
+
+    w.WriteByte(STREAM_END)
+
+    return w.Flush()
+}
+
+// ImportStream reads a framed binary stream written by ExportStream and adds
+// each sequence to w without decoding it.
+func ImportStream(in io.Reader, w *Writer) (error) {
+    if w.closed {
+        return ErrClosed
+    }
+
+    r := bufio.NewReader(in)
+
+    magic := make([]byte, len(STREAM_MAGIC))
+    _, err := io.ReadFull(r, magic)
+    if err != nil || string(magic) != STREAM_MAGIC {
+        return fmt.Errorf("Invalid stream header. Not a 2bit stream?")
+    }
+
+    buf := make([]byte, 4)
+    getUint32 := func() (uint32, error) {
+        _, err := io.ReadFull(r, buf)
+        return binary.LittleEndian.Uint32(buf), err
+    }
+    getBlocks := func(dnaSize uint32) (Blocks, error) {
+        count, err := getUint32()
+        if err != nil {
+            return nil, fmt.Errorf("%w: %s", ErrTruncated, err)
+        }
+        if count > dnaSize {
+            return nil, fmt.Errorf("Invalid block count %d", count)
+        }
+        // the stream is untrusted: grow as entries actually arrive
+        capacity := int(count)
+        if capacity > defaultBufSize {
+            capacity = defaultBufSize
+        }
+        blocks := make(Blocks, 0, capacity)
+        for i := uint32(0); i < count; i++ {
+            start, err := getUint32()
+            if err != nil {
+                return nil, fmt.Errorf("%w: %s", ErrTruncated, err)
+            }
+            blocks = append(blocks, &Block{Start: int(start)})
+        }
+        for i := range blocks {
+            size, err := getUint32()
+            if err != nil {
+                return nil, fmt.Errorf("%w: %s", ErrTruncated, err)
+            }
+            if uint64(blocks[i].Start)+uint64(size) > uint64(dnaSize) {
+                return nil, fmt.Errorf("Block %d-%d extends past end of sequence", blocks[i].Start, size)
+            }
+            blocks[i].Length = int(size)
+        }
+        return blocks, nil
+    }
+
+    for {
+        frame, err := r.ReadByte()
+        if err != nil {
+            return fmt.Errorf("%w: stream frame: %s", ErrTruncated, err)
+        }
+
+        if frame == STREAM_END {
+            return nil
+        }
+        if frame != STREAM_SEQ {
+            return fmt.Errorf("Invalid stream frame type %d", frame)
+        }
+
+        size, err := r.ReadByte()
+        if err != nil {
+            return fmt.Errorf("%w: stream frame: %s", ErrTruncated, err)
+        }
+        name := make([]byte, size)
+        _, err = io.ReadFull(r, name)
+        if err != nil {
+            return fmt.Errorf("%w: stream frame: %s", ErrTruncated, err)
+        }
+
+        rec := new(seqRecord)
+        rec.dnaSize, err = getUint32()
+        if err != nil {
+            return fmt.Errorf("%w: %s: %s", ErrTruncated, name, err)
+        }
+        if int64(rec.dnaSize) > maxInt {
+            return fmt.Errorf("Sequence %s is too large: %d", name, rec.dnaSize)
+        }
+
+        rec.nBlocks, err = getBlocks(rec.dnaSize)
+        if err != nil {
+            return fmt.Errorf("Failed to read %s nBlocks: %w", name, err)
+        }
+        rec.mBlocks, err = getBlocks(rec.dnaSize)
+        if err != nil {
+            return fmt.Errorf("Failed to read %s mBlocks: %w", name, err)
+        }
+
+        packed := packedSize64(int64(rec.dnaSize))
+        if packed > maxInt {
+            return fmt.Errorf("Sequence %s is too large: %d", name, rec.dnaSize)
+        }
+        var seq bytes.Buffer
+        _, err = io.CopyN(&seq, r, packed)
+        if err != nil {
+            return fmt.Errorf("%w: %s", ErrTruncated, name)
+        }
+        rec.sequence = seq.Bytes()
+
+        err = w.addRecord(string(name), rec)
+        if err != nil {
+            return err
+        }
+    }
+}
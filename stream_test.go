@@ -0,0 +1,75 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "errors"
+    "runtime"
+)
+
+func TestStream(t *testing.T) {
+    seqs := map[string]string{"chr1": "ACTgcctttnnnNantnaCgc", "chr2": "", "chrM": "GATTACA"}
+    tb := newTestReader(t, seqs)
+
+    var stream bytes.Buffer
+    err := ExportStream(tb, &stream)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    w := NewWriter()
+    err = ImportStream(bytes.NewReader(stream.Bytes()), w)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    var out bytes.Buffer
+    w.WriteTo(&out)
+    imported, err := NewReader(bytes.NewReader(out.Bytes()))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    for name, good := range seqs {
+        seq, err := imported.Read(name)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        if string(seq) != good {
+            t.Errorf("Invalid sequence %s: %s != %s", name, seq, good)
+        }
+    }
+
+    err = ImportStream(bytes.NewReader(stream.Bytes()[:stream.Len()-3]), NewWriter())
+    if !errors.Is(err, ErrTruncated) {
+        t.Errorf("Expected ErrTruncated, got: %v", err)
+    }
+}
+
+func TestStreamHostileSizes(t *testing.T) {
+    huge := []byte{0xff, 0xff, 0xff, 0xff}
+    frames := [][]byte{
+        // dnaSize and N block count of 2^32-1 with no entries
+        append(append([]byte(STREAM_MAGIC+"\x01\x01x"), huge...), huge...),
+        // dnaSize of 2^32-1 without blocks or packed bases
+        append(append([]byte(STREAM_MAGIC+"\x01\x01x"), huge...), make([]byte, 8)...),
+    }
+
+    for _, frame := range frames {
+        var before, after runtime.MemStats
+        runtime.ReadMemStats(&before)
+        err := ImportStream(bytes.NewReader(frame), NewWriter())
+        runtime.ReadMemStats(&after)
+
+        if !errors.Is(err, ErrTruncated) {
+            t.Errorf("Expected ErrTruncated, got: %v", err)
+        }
+        if after.TotalAlloc-before.TotalAlloc > 1<<20 {
+            t.Errorf("Allocated %d bytes for a %d byte stream", after.TotalAlloc-before.TotalAlloc, len(frame))
+        }
+    }
+}
@@ -0,0 +1,77 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+)
+
+// ErrCorrupt is returned when a record is inconsistent with its own fields
+// or with the rest of the file
+var ErrCorrupt = errors.New("twobit: corrupt record")
+
+// Strict makes the Reader cross-check each record when it is parsed: every N
+// and mask block must lie within dnaSize, the record must start after the
+// file index and its packed data must end before the next record. Failures
+// are reported with ErrCorrupt naming the inconsistency, catching subtly
+// corrupt files before any sequence is returned. Strict reads the whole file
+// index and always loads block tables (see BlockTables).
+func Strict() (ReadOption) {
+    return func(r *Reader) (error) {
+        r.strict = true
+        return nil
+    }
+}
+
+// Return the offset of the first record after offset, or the file size if
+// there is none. Sequences excluded by a NameFilter are not considered.
+func (r *Reader) nextRecord(offset int64) (int64) {
+    if r.bounds == nil {
+        r.bounds = make([]int64, 0, len(r.index))
+        for _, off := range r.index {
+            r.bounds = append(r.bounds, off)
+        }
+        sort.Slice(r.bounds, func(i, j int) bool { return r.bounds[i] < r.bounds[j] })
+    }
+
+    i := sort.Search(len(r.bounds), func(i int) bool { return r.bounds[i] > offset })
+    if i < len(r.bounds) {
+        return r.bounds[i]
+    }
+
+    return r.size
+}
+
+// Check the record of sequence name starts after the file index, before any
+// of it is read
+func (r *Reader) checkOffset(name string, offset int64) (error) {
+    err := r.loadIndex()
+    if err != nil {
+        return err
+    }
+
+    if offset < r.indexPos {
+        return fmt.Errorf("%w: %s starts at offset %d inside the file index ending at %d", ErrCorrupt, name, offset, r.indexPos)
+    }
+
+    return nil
+}
+
+// Cross-check record rec of sequence name stored at offset
+func (r *Reader) checkRecord(name string, offset int64, rec *seqRecord) (error) {
+    err := rec.checkBlocks(name)
+    if err != nil {
+        return err
+    }
+
+    end := rec.offset+packedSize64(int64(rec.dnaSize))
+    if next := r.nextRecord(offset); next >= 0 && end > next {
+        return fmt.Errorf("%w: %s packed data of %d bases ends at offset %d, past the next record at %d", ErrCorrupt, name, rec.dnaSize, end, next)
+    }
+
+    return nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "encoding/binary"
+    "errors"
+    "github.com/aebruno/twobit/testfixtures"
+)
+
+func TestStrict(t *testing.T) {
+    packed := []byte{0x1b, 0x1b}
+    good := testfixtures.NewBuilder().Add("chr1", "ACGTacgtNNNN").Add("chr2", "ACGT").Bytes()
+    pastEnd := testfixtures.NewBuilder().AddRaw(testfixtures.Sequence{
+        Name: "chr1", DnaSize: 8, NBlocks: []testfixtures.Block{{Start: 6, Size: 4}}, Packed: packed,
+    }).Bytes()
+    // chr1 claims 16 bases but only 2 packed bytes precede chr2
+    overlap := testfixtures.NewBuilder().
+        AddRaw(testfixtures.Sequence{Name: "chr1", DnaSize: 16, Packed: packed}).
+        AddRaw(testfixtures.Sequence{Name: "chr2", DnaSize: 8, Packed: packed}).Bytes()
+    // chr2 points back into the index
+    inIndex := append([]byte{}, good...)
+    binary.LittleEndian.PutUint32(inIndex[16+5+4+5:], 16)
+
+    tests := []struct {
+        label  string
+        data   []byte
+        name   string
+        ok     bool
+    }{
+        {"valid", good, "chr1", true},
+        {"block past end", pastEnd, "chr1", false},
+        {"packed past next record", overlap, "chr1", false},
+        {"record inside index", inIndex, "chr2", false},
+    }
+
+    for _, test := range tests {
+        // lenient reads ignore the inconsistency
+        tb, err := NewReader(bytes.NewReader(test.data))
+        if err != nil {
+            t.Fatalf("%s: %s", test.label, err)
+        }
+        tb.Read(test.name)
+
+        tb, err = NewReader(bytes.NewReader(test.data), Strict(), BlockTables(BLOCKS_SEARCH))
+        if err != nil {
+            t.Fatalf("%s: %s", test.label, err)
+        }
+        _, err = tb.ReadRange(test.name, 0, 1)
+        if test.ok && err != nil {
+            t.Errorf("%s: %s", test.label, err)
+        }
+        if !test.ok && !errors.Is(err, ErrCorrupt) {
+            t.Errorf("%s: expected ErrCorrupt, got %v", test.label, err)
+        }
+    }
+}
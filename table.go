@@ -0,0 +1,122 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "io"
+    "bufio"
+    "fmt"
+)
+
+// SequenceMetadata is one row of per sequence metadata
+type SequenceMetadata struct {
+    Name     string
+    Length   int
+    Offset   int64  // file offset of the packed DNA
+    NBlocks  int    // number of N blocks
+    MBlocks  int    // number of mask blocks
+    RangeStats
+}
+
+// WindowStats is one row of per window statistics
+type WindowStats struct {
+    Range
+    RangeStats
+}
+
+// Returns a metadata row for each sequence in file order
+func (r *Reader) SequenceMetadata() ([]*SequenceMetadata, error) {
+    names := r.namesByOffset()
+    rows := make([]*SequenceMetadata, 0, len(names))
+    for _, name := range names {
+        rec, err := r.parseRecord(name, true)
+        if err != nil {
+            return nil, err
+        }
+        stats, err := r.RangeStats(name, 0, 0)
+        if err != nil {
+            return nil, err
+        }
+
+        rows = append(rows, &SequenceMetadata{
+            Name: name,
+            Length: int(rec.dnaSize),
+            Offset: rec.offset,
+            NBlocks: len(rec.nBlocks),
+            MBlocks: len(rec.mBlocks),
+            RangeStats: *stats,
+        })
+    }
+
+    return rows, nil
+}
+
+// Returns statistics for consecutive non-overlapping windows of size bases
+// across every sequence in file order. The last window of each sequence may
+// be shorter than size. The N and mask blocks of each sequence are swept
+// once across its windows.
+func (r *Reader) WindowStats(size int) ([]*WindowStats, error) {
+    if size <= 0 {
+        return nil, fmt.Errorf("Invalid window size: %d", size)
+    }
+
+    rows := make([]*WindowStats, 0)
+    for _, name := range r.namesByOffset() {
+        rec, err := r.parseRecord(name, true)
+        if err != nil {
+            return nil, err
+        }
+        rec = rec.sortedBlocks()
+        length := int(rec.dnaSize)
+
+        c := new(blockCursor)
+        for start := 0; start < length; start += size {
+            end := start+size
+            if end > length {
+                end = length
+            }
+            stats, err := r.rangeStats(rec, start, end, c)
+            if err != nil {
+                return nil, err
+            }
+            rows = append(rows, &WindowStats{Range: Range{name, start, end}, RangeStats: *stats})
+        }
+    }
+
+    return rows, nil
+}
+
+// Write metadata rows as a tab-delimited table with a header line, suitable
+// for loading directly into a dataframe
+func WriteMetadataTable(out io.Writer, rows []*SequenceMetadata) (error) {
+    w := bufio.NewWriter(out)
+    fmt.Fprintf(w, "name\tlength\toffset\tn_blocks\tm_blocks\ta\tc\tg\tt\tn\tmasked\tgc\n")
+    for _, m := range rows {
+        _, err := fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%.6f\n",
+            m.Name, m.Length, m.Offset, m.NBlocks, m.MBlocks,
+            m.A, m.C, m.G, m.T, m.N, m.Masked, m.GC)
+        if err != nil {
+            return err
+        }
+    }
+
+    return w.Flush()
+}
+
+// Write window rows as a tab-delimited table with a header line, suitable
+// for loading directly into a dataframe
+func WriteWindowTable(out io.Writer, rows []*WindowStats) (error) {
+    w := bufio.NewWriter(out)
+    fmt.Fprintf(w, "name\tstart\tend\ta\tc\tg\tt\tn\tmasked\tgc\n")
+    for _, s := range rows {
+        _, err := fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%.6f\n",
+            s.Name, s.Start, s.End, s.A, s.C, s.G, s.T, s.N, s.Masked, s.GC)
+        if err != nil {
+            return err
+        }
+    }
+
+    return w.Flush()
+}
@@ -0,0 +1,68 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "reflect"
+    "strings"
+)
+
+func TestTables(t *testing.T) {
+    tb := newTestReader(t, map[string]string{"chr1": "ACGTNNacgtGC"})
+
+    meta, err := tb.SequenceMetadata()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if len(meta) != 1 || meta[0].Length != 12 || meta[0].N != 2 || meta[0].Masked != 4 || meta[0].NBlocks != 1 {
+        t.Errorf("Invalid metadata: %+v", meta[0])
+    }
+
+    windows, err := tb.WindowStats(5)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if len(windows) != 3 || windows[2].Start != 10 || windows[2].End != 12 || windows[2].GC != 1 {
+        t.Errorf("Invalid windows: %+v", windows)
+    }
+
+    var out bytes.Buffer
+    err = WriteWindowTable(&out, windows)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+    if len(lines) != 4 || lines[1] != "chr1\t0\t5\t1\t1\t1\t1\t1\t0\t0.500000" {
+        t.Errorf("Invalid window table: %q", lines)
+    }
+
+    _, err = tb.WindowStats(0)
+    if err == nil {
+        t.Errorf("Expected error for zero window size")
+    }
+}
+
+func TestWindowStatsSweep(t *testing.T) {
+    seq := strings.Repeat("ACGTNNNacgtnGGccA", 50)
+    tb := newTestReader(t, map[string]string{"chr1": seq, "chr2": "NNNNacgt"})
+
+    for _, size := range []int{1, 3, 7, 100, 1000} {
+        windows, err := tb.WindowStats(size)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        for _, w := range windows {
+            stats, err := tb.RangeStats(w.Name, w.Start, w.End)
+            if err != nil {
+                t.Fatalf("%s", err)
+            }
+            if !reflect.DeepEqual(*stats, w.RangeStats) {
+                t.Errorf("Window %s differs: %+v != %+v", w.Range, w.RangeStats, *stats)
+            }
+        }
+    }
+}
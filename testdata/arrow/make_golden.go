@@ -0,0 +1,164 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+//go:build ignore
+
+// Writes the golden Arrow IPC streams used by arrow_test.go with the Apache
+// Arrow Go module (github.com/apache/arrow-go) and checks that it reads the
+// output of WriteWindowArrow and WriteMetadataArrow back as the same tables.
+// The twobit package does not depend on arrow-go, so run this from a module
+// that requires github.com/apache/arrow-go/v18 (the golden files were made
+// with v18.8.0) and replaces github.com/aebruno/twobit with this tree:
+//
+//   go run -tags arrow make_golden.go -out /path/to/twobit/testdata/arrow
+package main
+
+import (
+    "bytes"
+    "flag"
+    "fmt"
+    "log"
+    "os"
+    "path/filepath"
+    "github.com/aebruno/twobit"
+    "github.com/apache/arrow-go/v18/arrow"
+    "github.com/apache/arrow-go/v18/arrow/array"
+    "github.com/apache/arrow-go/v18/arrow/ipc"
+    "github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// The sequences of the tables, the same as in TestWriteArrowGolden
+var seqs = []struct {
+    name  string
+    seq   string
+}{
+    {"chr1", "ACGTNNacgtGC"},
+    {"chr2", "ggggNNNNAT"},
+}
+
+// Build a record with a string first column, a float64 last column and int64
+// columns in between, the shape of both tables
+func record(names []string, strs []string, ints [][]int64, floats []float64) (arrow.Record) {
+    fields := make([]arrow.Field, len(names))
+    fields[0] = arrow.Field{Name: names[0], Type: arrow.BinaryTypes.String}
+    for i := 1; i < len(names)-1; i++ {
+        fields[i] = arrow.Field{Name: names[i], Type: arrow.PrimitiveTypes.Int64}
+    }
+    fields[len(names)-1] = arrow.Field{Name: names[len(names)-1], Type: arrow.PrimitiveTypes.Float64}
+
+    b := array.NewRecordBuilder(memory.DefaultAllocator, arrow.NewSchema(fields, nil))
+    b.Field(0).(*array.StringBuilder).AppendValues(strs, nil)
+    for i, col := range ints {
+        b.Field(i+1).(*array.Int64Builder).AppendValues(col, nil)
+    }
+    b.Field(len(names)-1).(*array.Float64Builder).AppendValues(floats, nil)
+
+    return b.NewRecord()
+}
+
+// Write rec as an IPC stream to path
+func writeGolden(path string, rec arrow.Record) {
+    f, err := os.Create(path)
+    if err != nil {
+        log.Fatal(err)
+    }
+    w := ipc.NewWriter(f, ipc.WithSchema(rec.Schema()))
+    err = w.Write(rec)
+    if err == nil {
+        err = w.Close()
+    }
+    if err == nil {
+        err = f.Close()
+    }
+    if err != nil {
+        log.Fatal(err)
+    }
+}
+
+// Read the stream written by twobit with arrow-go and compare it to want
+func check(name string, data []byte, want arrow.Record) {
+    r, err := ipc.NewReader(bytes.NewReader(data))
+    if err != nil {
+        log.Fatalf("%s: arrow-go cannot read the stream: %s", name, err)
+    }
+    defer r.Release()
+
+    if !r.Schema().Equal(want.Schema()) {
+        log.Fatalf("%s: schema %s != %s", name, r.Schema(), want.Schema())
+    }
+    if !r.Next() {
+        log.Fatalf("%s: no record batch: %v", name, r.Err())
+    }
+    if !array.RecordEqual(r.Record(), want) {
+        log.Fatalf("%s: record %v != %v", name, r.Record(), want)
+    }
+    if r.Next() {
+        log.Fatalf("%s: unexpected second record batch", name)
+    }
+}
+
+func main() {
+    out := flag.String("out", ".", "output directory")
+    flag.Parse()
+
+    w := twobit.NewWriter()
+    for _, s := range seqs {
+        w.Add(s.name, s.seq)
+    }
+    var file bytes.Buffer
+    w.WriteTo(&file)
+    tb, err := twobit.NewReader(bytes.NewReader(file.Bytes()))
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    windows, err := tb.WindowStats(5)
+    if err != nil {
+        log.Fatal(err)
+    }
+    var strs []string
+    var floats []float64
+    ints := make([][]int64, 8)
+    for _, s := range windows {
+        strs = append(strs, s.Name)
+        for i, v := range []int{s.Start, s.End, s.A, s.C, s.G, s.T, s.N, s.Masked} {
+            ints[i] = append(ints[i], int64(v))
+        }
+        floats = append(floats, s.GC)
+    }
+    want := record([]string{"name", "start", "end", "a", "c", "g", "t", "n", "masked", "gc"}, strs, ints, floats)
+
+    var got bytes.Buffer
+    err = twobit.WriteWindowArrow(&got, windows)
+    if err != nil {
+        log.Fatal(err)
+    }
+    check("windows", got.Bytes(), want)
+    writeGolden(filepath.Join(*out, "windows.arrows"), want)
+
+    meta, err := tb.SequenceMetadata()
+    if err != nil {
+        log.Fatal(err)
+    }
+    strs, floats, ints = nil, nil, make([][]int64, 10)
+    for _, m := range meta {
+        strs = append(strs, m.Name)
+        for i, v := range []int64{int64(m.Length), m.Offset, int64(m.NBlocks), int64(m.MBlocks),
+            int64(m.A), int64(m.C), int64(m.G), int64(m.T), int64(m.N), int64(m.Masked)} {
+            ints[i] = append(ints[i], v)
+        }
+        floats = append(floats, m.GC)
+    }
+    want = record([]string{"name", "length", "offset", "n_blocks", "m_blocks", "a", "c", "g", "t", "n", "masked", "gc"}, strs, ints, floats)
+
+    got.Reset()
+    err = twobit.WriteMetadataArrow(&got, meta)
+    if err != nil {
+        log.Fatal(err)
+    }
+    check("metadata", got.Bytes(), want)
+    writeGolden(filepath.Join(*out, "metadata.arrows"), want)
+
+    fmt.Println("arrow-go reads both tables")
+}
@@ -0,0 +1,195 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package testfixtures
+
+import (
+    "fmt"
+    "math"
+    "math/rand"
+)
+
+// Adder receives generated sequences. *twobit.Writer implements it, the
+// interface avoids an import cycle with the twobit package tests.
+type Adder interface {
+    Add(name, seq string) error
+}
+
+// GenomeOptions describes a synthetic genome. Zero values select the
+// defaults noted for each field.
+type GenomeOptions struct {
+    Seed            int64   // seed of the random generator
+    Prefix          string  // sequence name prefix, default "chr"
+    Sequences       int     // number of sequences, default 1
+    MinLength       int     // shortest sequence, default 1000
+    MaxLength       int     // longest sequence, default MinLength
+    Length          func(rng *rand.Rand) int // overrides the log-uniform MinLength-MaxLength lengths
+    GC              float64 // fraction of G and C bases, default 0.5
+    GapRate         float64 // expected N gaps per base, default no gaps
+    MinGap          int     // shortest gap, default 1
+    MaxGap          int     // longest gap, default MinGap
+    MaskedFraction  float64 // expected fraction of soft-masked bases
+    MaskLength      int     // mean length of masked runs, default 300
+}
+
+// Fill in defaults
+func (o GenomeOptions) normalize() (GenomeOptions, error) {
+    if o.Prefix == "" {
+        o.Prefix = "chr"
+    }
+    if o.Sequences == 0 {
+        o.Sequences = 1
+    }
+    if o.MinLength == 0 {
+        o.MinLength = 1000
+    }
+    if o.MaxLength == 0 {
+        o.MaxLength = o.MinLength
+    }
+    if o.GC == 0 {
+        o.GC = 0.5
+    }
+    if o.MinGap == 0 {
+        o.MinGap = 1
+    }
+    if o.MaxGap == 0 {
+        o.MaxGap = o.MinGap
+    }
+    if o.MaskLength == 0 {
+        o.MaskLength = 300
+    }
+
+    switch {
+    case o.Sequences < 0:
+        return o, fmt.Errorf("Invalid sequence count: %d", o.Sequences)
+    case o.MinLength < 0 || o.MaxLength < o.MinLength:
+        return o, fmt.Errorf("Invalid lengths: %d-%d", o.MinLength, o.MaxLength)
+    case o.GC < 0 || o.GC > 1:
+        return o, fmt.Errorf("Invalid GC fraction: %g", o.GC)
+    case o.GapRate < 0 || o.MinGap < 0 || o.MaxGap < o.MinGap:
+        return o, fmt.Errorf("Invalid gaps: rate %g lengths %d-%d", o.GapRate, o.MinGap, o.MaxGap)
+    case o.MaskedFraction < 0 || o.MaskedFraction >= 1 || o.MaskLength < 0:
+        return o, fmt.Errorf("Invalid masking: fraction %g length %d", o.MaskedFraction, o.MaskLength)
+    }
+
+    return o, nil
+}
+
+// Draw a sequence length
+func (o GenomeOptions) length(rng *rand.Rand) (int) {
+    if o.Length != nil {
+        return o.Length(rng)
+    }
+    if o.MinLength == o.MaxLength {
+        return o.MinLength
+    }
+
+    // log-uniform, so short scaffolds are as common as long chromosomes
+    lo, hi := math.Log(float64(o.MinLength)), math.Log(float64(o.MaxLength))
+    return int(math.Exp(lo+rng.Float64()*(hi-lo)))
+}
+
+// Generate a random sequence of n bases
+func (o GenomeOptions) sequence(rng *rand.Rand, n int) (string) {
+    seq := make([]byte, n)
+    for i := range seq {
+        if rng.Float64() < o.GC {
+            seq[i] = "GC"[rng.Intn(2)]
+        } else {
+            seq[i] = "AT"[rng.Intn(2)]
+        }
+    }
+
+    // alternate masked and unmasked runs with exponential lengths whose
+    // means give the masked fraction
+    if f := o.MaskedFraction; f > 0 {
+        masked := rng.Float64() < f
+        for pos := 0; pos < n; {
+            mean := float64(o.MaskLength)
+            if !masked {
+                mean = mean*(1-f)/f
+            }
+            end := pos+1+int(rng.ExpFloat64()*mean)
+            if end > n {
+                end = n
+            }
+            if masked {
+                for i := pos; i < end; i++ {
+                    seq[i] += 'a'-'A'
+                }
+            }
+            pos = end
+            masked = !masked
+        }
+    }
+
+    if o.GapRate > 0 {
+        pos := int(rng.ExpFloat64()/o.GapRate)
+        for pos < n {
+            end := pos+o.MinGap+rng.Intn(o.MaxGap-o.MinGap+1)
+            for i := pos; i < end && i < n; i++ {
+                seq[i] = 'N'
+            }
+            pos = end+int(rng.ExpFloat64()/o.GapRate)
+        }
+    }
+
+    return string(seq)
+}
+
+// Genome adds a reproducible random genome described by opts to w. Sequences
+// are named by the prefix and a counter (chr1, chr2, ...) and the same
+// options always produce the same sequences.
+func Genome(w Adder, opts GenomeOptions) (error) {
+    opts, err := opts.normalize()
+    if err != nil {
+        return err
+    }
+
+    rng := rand.New(rand.NewSource(opts.Seed))
+    for i := 1; i <= opts.Sequences; i++ {
+        n := opts.length(rng)
+        if n < 0 {
+            return fmt.Errorf("Invalid length for %s%d: %d", opts.Prefix, i, n)
+        }
+        err = w.Add(fmt.Sprintf("%s%d", opts.Prefix, i), opts.sequence(rng, n))
+        if err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+// Assembly adds a reproducible genome shaped like a draft assembly to w:
+// chroms chromosomes (chr1, chr2, ...) of size bases followed by scaffolds
+// 1-6kb scaffolds (chrUn_scaffold1, ...), soft-masked with N gaps of 100 to
+// 10000 bases. The benchmarks and examples/synthetic.2bit are built with it.
+func Assembly(w Adder, seed int64, chroms, size, scaffolds int) (error) {
+    opts := GenomeOptions{
+        Seed:           seed,
+        Sequences:      chroms,
+        MinLength:      size,
+        GapRate:        1.0/20000,
+        MinGap:         100,
+        MaxGap:         10000,
+        MaskedFraction: 0.1,
+    }
+    if chroms > 0 {
+        err := Genome(w, opts)
+        if err != nil {
+            return err
+        }
+    }
+
+    if scaffolds > 0 {
+        opts.Seed++
+        opts.Prefix = "chrUn_scaffold"
+        opts.Sequences = scaffolds
+        opts.MinLength, opts.MaxLength = 1000, 6000
+        return Genome(w, opts)
+    }
+
+    return nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package testfixtures
+
+import (
+    "testing"
+    "bytes"
+    "fmt"
+    "math"
+    "strings"
+    "github.com/aebruno/twobit"
+)
+
+// collects generated sequences
+type sequences map[string]string
+
+func (s sequences) Add(name, seq string) (error) {
+    s[name] = seq
+    return nil
+}
+
+func TestGenome(t *testing.T) {
+    opts := GenomeOptions{
+        Seed: 7,
+        Sequences: 5,
+        MinLength: 10000,
+        MaxLength: 200000,
+        GC: 0.4,
+        GapRate: 1e-4,
+        MinGap: 100,
+        MaxGap: 500,
+        MaskedFraction: 0.5,
+    }
+
+    seqs := make(sequences)
+    err := Genome(seqs, opts)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    var total, gc, n, masked int
+    for _, seq := range seqs {
+        if len(seq) < opts.MinLength || len(seq) > opts.MaxLength {
+            t.Errorf("Invalid length: %d", len(seq))
+        }
+        for i := 0; i < len(seq); i++ {
+            switch seq[i] {
+            case 'N':
+                n++
+                continue
+            case 'g', 'c':
+                gc++
+                masked++
+            case 'a', 't':
+                masked++
+            case 'G', 'C':
+                gc++
+            }
+            total++
+        }
+    }
+
+    if f := float64(gc)/float64(total); math.Abs(f-opts.GC) > 0.01 {
+        t.Errorf("Invalid GC fraction: %.3f", f)
+    }
+    if f := float64(masked)/float64(total); math.Abs(f-opts.MaskedFraction) > 0.1 {
+        t.Errorf("Invalid masked fraction: %.3f", f)
+    }
+    if n == 0 {
+        t.Errorf("No gaps generated")
+    }
+
+    // same seed, same genome, written straight into a Writer
+    w := twobit.NewWriter()
+    err = Genome(w, opts)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    var out bytes.Buffer
+    w.WriteTo(&out)
+    tb, _ := twobit.NewReader(bytes.NewReader(out.Bytes()))
+    for name, seq := range seqs {
+        got, err := tb.Read(name)
+        if err != nil || string(got) != seq {
+            t.Errorf("Genome not reproducible for %s: %v", name, err)
+        }
+    }
+
+    err = Genome(seqs, GenomeOptions{GC: 2})
+    if err == nil {
+        t.Errorf("Invalid options accepted")
+    }
+}
+
+func TestAssembly(t *testing.T) {
+    seqs := make(sequences)
+    err := Assembly(seqs, 3, 2, 50000, 10)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    if len(seqs) != 12 || len(seqs["chr2"]) != 50000 || !strings.Contains(seqs["chr1"], "N") || !strings.ContainsAny(seqs["chr1"], "acgt") {
+        t.Errorf("Invalid chromosomes: %d sequences", len(seqs))
+    }
+    for i := 1; i <= 10; i++ {
+        seq, ok := seqs[fmt.Sprintf("chrUn_scaffold%d", i)]
+        if !ok || len(seq) < 1000 || len(seq) > 6000 {
+            t.Errorf("Invalid scaffold %d: %d", i, len(seq))
+        }
+    }
+
+    again := make(sequences)
+    Assembly(again, 3, 2, 50000, 10)
+    if again["chr1"] != seqs["chr1"] || again["chrUn_scaffold10"] != seqs["chrUn_scaffold10"] {
+        t.Errorf("Assembly is not reproducible")
+    }
+
+    none := make(sequences)
+    err = Assembly(none, 3, 0, 0, 0)
+    if err != nil || len(none) != 0 {
+        t.Errorf("Expected empty assembly: %d %v", len(none), err)
+    }
+}
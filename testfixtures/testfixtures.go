@@ -0,0 +1,242 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+// Package testfixtures builds small and pathological 2bit files for testing
+// readers and tools. Files are encoded directly without the twobit package so
+// malformed files (bad block tables, big endian files, truncated data) can be
+// produced as easily as valid ones. Genome generates reproducible synthetic
+// genomes of any size for tests and benchmarks.
+package testfixtures
+
+import (
+    "encoding/binary"
+    "math/rand"
+    "strings"
+)
+
+const sig = 0x1A412743
+
+// Block is an N or mask block as stored on disk
+type Block struct {
+    Start    uint32
+    Size     uint32
+}
+
+// Sequence is a raw sequence record. Fields are written as given, no
+// validation is done.
+type Sequence struct {
+    Name       string
+    DnaSize    uint32
+    NBlocks    []Block
+    MBlocks    []Block
+    Reserved   uint32
+    Packed     []byte
+}
+
+// Builder assembles a 2bit file from raw sequence records
+type Builder struct {
+    ByteOrder    binary.ByteOrder
+    Version      uint32
+    Sequences    []Sequence
+}
+
+// NewBuilder returns a Builder for little endian version 0 files
+func NewBuilder() (*Builder) {
+    return &Builder{ByteOrder: binary.LittleEndian}
+}
+
+// AddRaw appends a raw sequence record
+func (b *Builder) AddRaw(s Sequence) (*Builder) {
+    b.Sequences = append(b.Sequences, s)
+    return b
+}
+
+// Add appends a sequence encoded from seq. Lower case bases are masked and
+// N/n bases are stored as N blocks.
+func (b *Builder) Add(name, seq string) (*Builder) {
+    return b.AddRaw(Sequence{
+        Name: name,
+        DnaSize: uint32(len(seq)),
+        NBlocks: blocks(seq, func(c byte) bool { return c == 'N' || c == 'n' }),
+        MBlocks: blocks(seq, func(c byte) bool { return c >= 'a' && c <= 'z' }),
+        Packed: pack(seq),
+    })
+}
+
+// Bytes encodes the 2bit file
+func (b *Builder) Bytes() ([]byte) {
+    out := make([]byte, 0)
+    put := func(v uint32) {
+        buf := make([]byte, 4)
+        b.ByteOrder.PutUint32(buf, v)
+        out = append(out, buf...)
+    }
+
+    put(sig)
+    put(b.Version)
+    put(uint32(len(b.Sequences)))
+    put(0)
+
+    offset := 16
+    for _, s := range b.Sequences {
+        offset += 5+len(s.Name)
+    }
+
+    for _, s := range b.Sequences {
+        out = append(out, byte(len(s.Name)))
+        out = append(out, s.Name...)
+        put(uint32(offset))
+        offset += 16 + 8*len(s.NBlocks) + 8*len(s.MBlocks) + len(s.Packed)
+    }
+
+    for _, s := range b.Sequences {
+        put(s.DnaSize)
+        for _, blocks := range [][]Block{s.NBlocks, s.MBlocks} {
+            put(uint32(len(blocks)))
+            for _, blk := range blocks {
+                put(blk.Start)
+            }
+            for _, blk := range blocks {
+                put(blk.Size)
+            }
+        }
+        put(s.Reserved)
+        out = append(out, s.Packed...)
+    }
+
+    return out
+}
+
+// Empty returns a valid 2bit file with no sequences
+func Empty() ([]byte) {
+    return NewBuilder().Bytes()
+}
+
+// ZeroLength returns a 2bit file holding a single zero-length sequence "empty"
+func ZeroLength() ([]byte) {
+    return NewBuilder().Add("empty", "").Bytes()
+}
+
+// AllN returns a 2bit file holding a single sequence "gap" of n Ns
+func AllN(n int) ([]byte) {
+    return NewBuilder().Add("gap", strings.Repeat("N", n)).Bytes()
+}
+
+// LongName returns a 2bit file holding a sequence with a 255 character name,
+// the longest allowed by the format
+func LongName() ([]byte) {
+    return NewBuilder().Add(strings.Repeat("x", 255), "ACGT").Bytes()
+}
+
+// ManyBlocks returns a 2bit file holding a sequence "blocks" of n bases in
+// which every other base is a masked N, giving the maximum possible number of
+// N and mask blocks for its length
+func ManyBlocks(n int) ([]byte) {
+    seq := make([]byte, n)
+    for i := range seq {
+        seq[i] = 'A'
+        if i%2 == 1 {
+            seq[i] = 'n'
+        }
+    }
+
+    return NewBuilder().Add("blocks", string(seq)).Bytes()
+}
+
+// Truncate returns the first n bytes of a copy of data
+func Truncate(data []byte, n int) ([]byte) {
+    return append([]byte{}, data[:n]...)
+}
+
+// Return the blocks of consecutive bases matching match
+func blocks(seq string, match func(byte) bool) ([]Block) {
+    out := make([]Block, 0)
+    start := -1
+    for i := 0; i <= len(seq); i++ {
+        if i < len(seq) && match(seq[i]) {
+            if start < 0 {
+                start = i
+            }
+            continue
+        }
+        if start >= 0 {
+            out = append(out, Block{Start: uint32(start), Size: uint32(i-start)})
+            start = -1
+        }
+    }
+
+    return out
+}
+
+// Pack seq 4 bases per byte. T=0, C=1, A=2, G=3, anything else is T.
+func pack(seq string) ([]byte) {
+    out := make([]byte, (len(seq)+3)/4)
+    for i := 0; i < len(out)*4; i++ {
+        var v byte
+        if i < len(seq) {
+            switch seq[i] {
+            case 'C', 'c':
+                v = 1
+            case 'A', 'a':
+                v = 2
+            case 'G', 'g':
+                v = 3
+            }
+        }
+        out[i/4] = out[i/4]<<2 | v
+    }
+
+    return out
+}
+
+// RandomSequence returns n bases drawn uniformly from alphabet using rng
+func RandomSequence(rng *rand.Rand, n int, alphabet string) (string) {
+    seq := make([]byte, n)
+    for i := range seq {
+        seq[i] = alphabet[rng.Intn(len(alphabet))]
+    }
+
+    return string(seq)
+}
+
+// Unpacked returns the bases expected from unpacking the packed form of seq:
+// A, C and G in upper case and T for every other character. This is what
+// twobit.Unpack(twobit.Pack(seq)) must return.
+func Unpacked(seq string) (string) {
+    out := []byte(seq)
+    for i, c := range out {
+        switch c {
+        case 'A', 'a':
+            out[i] = 'A'
+        case 'C', 'c':
+            out[i] = 'C'
+        case 'G', 'g':
+            out[i] = 'G'
+        default:
+            out[i] = 'T'
+        }
+    }
+
+    return string(out)
+}
+
+// Stored returns the bases expected from reading seq back after writing it to
+// a 2bit file: A, C, G, T and N keep their case, other lower case characters
+// read as t and anything else as T.
+func Stored(seq string) (string) {
+    out := []byte(seq)
+    for i, c := range out {
+        switch c {
+        case 'A', 'C', 'G', 'T', 'N', 'a', 'c', 'g', 't', 'n':
+        default:
+            if c >= 'a' && c <= 'z' {
+                out[i] = 't'
+            } else {
+                out[i] = 'T'
+            }
+        }
+    }
+
+    return string(out)
+}
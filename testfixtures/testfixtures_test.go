@@ -0,0 +1,23 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package testfixtures
+
+import (
+    "testing"
+    "bytes"
+    "io/ioutil"
+)
+
+func TestBuilderMatchesExample(t *testing.T) {
+    good, err := ioutil.ReadFile("../examples/simple.2bit")
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    data := NewBuilder().Add("ex1", "ACTgcctttnnnNantnaCgc").Bytes()
+    if !bytes.Equal(data, good) {
+        t.Errorf("Builder output does not match examples/simple.2bit")
+    }
+}
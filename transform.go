@@ -0,0 +1,90 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "io"
+    "fmt"
+    "sync"
+)
+
+// Source is random access storage holding a 2bit file
+type Source interface {
+    io.ReaderAt
+    Size() int64
+}
+
+// Transform wraps a Source, for example to decrypt it or to reassemble it
+// from chunks in object storage. The returned Source must present the plain
+// 2bit file.
+type Transform func(src Source) (Source, error)
+
+// WithTransform adds a transform applied to the underlying storage before
+// any data is read. Transforms are applied in the order given, each wrapping
+// the result of the previous one, and all reads go through the outermost
+// Source.
+func WithTransform(t Transform) (ReadOption) {
+    return func(r *Reader) (error) {
+        if t == nil {
+            return fmt.Errorf("Invalid nil transform")
+        }
+        r.transforms = append(r.transforms, t)
+        return nil
+    }
+}
+
+// NewReaderAt returns a new TwoBit file reader which reads size bytes from
+// ra
+func NewReaderAt(ra io.ReaderAt, size int64, opts ...ReadOption) (*Reader, error) {
+    return NewReader(io.NewSectionReader(ra, 0, size), opts...)
+}
+
+// seekReaderAt adapts an io.ReadSeeker which is not an io.ReaderAt. Reads
+// are serialized as forks of the Reader may call ReadAt concurrently.
+type seekReaderAt struct {
+    mu        sync.Mutex
+    reader    io.ReadSeeker
+    size      int64
+}
+
+func (s *seekReaderAt) ReadAt(p []byte, off int64) (int, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    _, err := s.reader.Seek(off, 0)
+    if err != nil {
+        return 0, err
+    }
+
+    return io.ReadFull(s.reader, p)
+}
+
+func (s *seekReaderAt) Size() (int64) {
+    return s.size
+}
+
+// Convert r to a Source and apply transforms in order
+func applyTransforms(r io.ReadSeeker, transforms []Transform) (Source, error) {
+    size, err := r.Seek(0, 2)
+    if err != nil {
+        return nil, err
+    }
+
+    var src Source
+    if ra, ok := r.(io.ReaderAt); ok {
+        src = io.NewSectionReader(ra, 0, size)
+    } else {
+        src = &seekReaderAt{reader: r, size: size}
+    }
+
+    for _, t := range transforms {
+        src, err = t(src)
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    return src, nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "context"
+    "fmt"
+    "io"
+    "strings"
+)
+
+// xorSource "decrypts" a source by xoring every byte with key
+type xorSource struct {
+    Source
+    key    byte
+}
+
+func (x *xorSource) ReadAt(p []byte, off int64) (int, error) {
+    n, err := x.Source.ReadAt(p, off)
+    for i := 0; i < n; i++ {
+        p[i] ^= x.key
+    }
+    return n, err
+}
+
+func TestTransform(t *testing.T) {
+    w := NewWriter()
+    w.Add("chr1", "ACTGNNNNacgtGATTACA")
+    var out bytes.Buffer
+    w.WriteTo(&out)
+
+    encrypted := out.Bytes()
+    for i := range encrypted {
+        encrypted[i] ^= 0x5a
+    }
+
+    _, err := NewReader(bytes.NewReader(encrypted))
+    if err == nil {
+        t.Fatalf("Expected error reading encrypted file")
+    }
+
+    decrypt := func(src Source) (Source, error) {
+        return &xorSource{Source: src, key: 0x5a}, nil
+    }
+
+    for _, bufSize := range []int{0, 1, defaultBufSize} {
+        tb, err := NewReaderAt(bytes.NewReader(encrypted), int64(len(encrypted)), WithTransform(decrypt), BufferSize(bufSize))
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+
+        seq, err := tb.ReadRange("chr1", 2, 14)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        if string(seq) != "TGNNNNacgtGA" {
+            t.Errorf("Invalid decrypted sequence: %s", seq)
+        }
+    }
+}
+
+// Run with -race: forks of a Reader over a plain io.ReadSeeker share it
+func TestTransformSeekerConcurrent(t *testing.T) {
+    w := NewWriter()
+    for i := 0; i < 16; i++ {
+        w.Add(fmt.Sprintf("chr%d", i), strings.Repeat("ACGTNNacgt", 20+i))
+    }
+    var out bytes.Buffer
+    w.WriteTo(&out)
+
+    identity := func(src Source) (Source, error) {
+        return src, nil
+    }
+    // hide ReadAt so the seekReaderAt adapter is used
+    in := struct{ io.ReadSeeker }{bytes.NewReader(out.Bytes())}
+    tb, err := NewReader(in, WithTransform(identity))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    want, err := tb.Digests(context.Background(), 1)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    got, err := tb.Digests(context.Background(), 8)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    for name, d := range want {
+        if got[name] != d {
+            t.Errorf("Invalid concurrent digest of %s", name)
+        }
+    }
+}
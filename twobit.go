@@ -4,6 +4,11 @@
 
 // Package twobit implements the 2bit compact randomly-accessible file format
 // for storing DNA sequence data.
+//
+// Malformed files are reported as errors, never by panicking, and the
+// package does not recover panics internally: a panic while reading any
+// input is a bug. FuzzReader checks this, and inputs that once panicked are
+// kept in its corpus under testdata/fuzz.
 package twobit
 
 import (
@@ -11,9 +16,21 @@ import (
     "io"
     "bytes"
     "bufio"
+    "errors"
+    "os"
+    "math"
+    "crypto/sha256"
+    "sort"
     "encoding/binary"
 )
 
+// ErrTruncated is returned when a sequence's packed DNA extends past the end
+// of the file
+var ErrTruncated = errors.New("twobit: truncated sequence data")
+
+// ErrClosed is returned when using a Writer that has already been closed
+var ErrClosed = errors.New("twobit: writer already closed")
+
 // 2bit header
 type header struct {
     sig         uint32
@@ -23,44 +40,96 @@ type header struct {
     byteOrder   binary.ByteOrder
 }
 
-// Block represents either blocks of Ns or masked (lower-case) blocks
-type Block struct {
-    start    int
-    count    int
-}
-
 // seqRecord stores sequence record from the file index
 type seqRecord struct {
     dnaSize      uint32
-    nBlocks      []*Block
-    mBlocks      []*Block
+    nBlocks      Blocks
+    mBlocks      Blocks
     reserved     uint32
     sequence     []byte
+    offset       int64
+    spill        *spillFile // holds the packed bases at spillAt instead of sequence
+    spillAt      int64
 }
 
 // TwoBit stores the file index and header information of the 2bit file
 type twoBit struct {
     reader       io.ReadSeeker
+    size         int64
     hdr          header
-    index        map[string]int
+    index        map[string]int64
     records      map[string]*seqRecord
+    file         *os.File
+    closed       bool
+    atomic       bool
+    path         string
+    gap          byte
+    lengths      map[string]int
+    buf          []byte
+    bufSize      int
+    report       *WriteReport
+    dedup        int
+    digests      map[[sha256.Size]byte]string
+    duplicates   map[string]string
+    building     *seqBuilder
+    version      uint32
+    transforms   []Transform
+    readAhead    int
+    src          io.ReaderAt
+    filter       func(name string) bool
+    lazy         bool
+    indexPos     int64
+    indexRead    int
+    order        []string
+    layout       Layout
+    provenance   *Provenance
+    qc           *QCOptions
+    qcWarnings   []*QCWarning
+    maxBases     int
+    validName    NameValidator
+    blockMode    BlockMode
+    tables       map[string]*tableRecord
+    onPacked     SequenceHook
+    onWritten    SequenceHook
+    strict       bool
+    bounds       []int64
+    entries      []IndexEntry
+    reserved     *reservation
+    allowAmino   bool
+    spill        *spillFile
+    descriptions map[string]string
+    maxMemory    int64
+    memoryTemp   TempStorage
+    memory       int64 // running total of the memory of records, see setRecord
+    sidecar      *Sidecar
 }
 
 type Reader twoBit
 type Writer twoBit
 
+// unpacked maps each packed byte to its 4 bases
+var unpacked [256][BASES_PER_BYTE]byte
+
 func init() {
+    for i := range unpacked {
+        base := i
+        for j := 3; j >= 0; j-- {
+            unpacked[i][j] = BYTES2NT[base & 0x3]
+            base >>= 2
+        }
+    }
+
     NT2BYTES = make([]byte, 256)
-    NT2BYTES[BASE_N]    = uint8(0)
-    NT2BYTES[BASE_T]    = uint8(0)
-    NT2BYTES[BASE_C]    = uint8(1)
-    NT2BYTES[BASE_A]    = uint8(2)
-    NT2BYTES[BASE_G]    = uint8(3)
-    NT2BYTES[BASE_N+32] = uint8(0)
-    NT2BYTES[BASE_T+32] = uint8(0)
-    NT2BYTES[BASE_C+32] = uint8(1)
-    NT2BYTES[BASE_A+32] = uint8(2)
-    NT2BYTES[BASE_G+32] = uint8(3)
+    NT2BYTES[BASE_N]    = uint8(ENCODE_T)
+    NT2BYTES[BASE_T]    = uint8(ENCODE_T)
+    NT2BYTES[BASE_C]    = uint8(ENCODE_C)
+    NT2BYTES[BASE_A]    = uint8(ENCODE_A)
+    NT2BYTES[BASE_G]    = uint8(ENCODE_G)
+    NT2BYTES[BASE_N+32] = uint8(ENCODE_T)
+    NT2BYTES[BASE_T+32] = uint8(ENCODE_T)
+    NT2BYTES[BASE_C+32] = uint8(ENCODE_C)
+    NT2BYTES[BASE_A+32] = uint8(ENCODE_A)
+    NT2BYTES[BASE_G+32] = uint8(ENCODE_G)
 }
 
 // Return the size in packed bytes of a dna sequence. 4 bases per byte
@@ -68,59 +137,131 @@ func packedSize(dnaSize int) (int) {
     return (dnaSize + 3) >> 2
 }
 
-// Return length of block
-func (b *Block) Length() int {
-    return b.start+b.count
-}
-
-// Return start of block
-func (b *Block) Start() int {
-    return b.start
-}
-
-// Return count of block
-func (b *Block) Count() int {
-    return b.count
+// Return the size in packed bytes of a dna sequence without overflowing for
+// any 32-bit dnaSize
+func packedSize64(dnaSize int64) (int64) {
+    return (dnaSize + 3) >> 2
 }
 
 // Return the size in bytes the seqRecord rec will take up in the twobit file
 func (rec *seqRecord) size() int {
-    size := 16 // dnaSize (4), nBlockCount (4), mBlockCount (4), reserved (4)
+    size := RECORD_DNA_SIZE_LEN + 2*RECORD_BLOCK_COUNT_LEN + RECORD_RESERVED_LEN
 
-    size += 2 * 4 * len(rec.nBlocks) // nBlockStarts, nBlockSizes
-    size += 2 * 4 * len(rec.mBlocks) // mBlockStarts, mBlockSizes
-    size += len(rec.sequence)   // packedDNA
+    size += 2 * RECORD_BLOCK_FIELD_LEN * len(rec.nBlocks) // nBlockStarts, nBlockSizes
+    size += 2 * RECORD_BLOCK_FIELD_LEN * len(rec.mBlocks) // mBlockStarts, mBlockSizes
+    size += rec.packedLen()     // packedDNA
 
     return size
 }
 
-// Parse the file index of a 2bit file
+// Return the number of packed bytes of rec, which may have been spilled
+func (rec *seqRecord) packedLen() (int) {
+    if rec.spill != nil {
+        return packedSize(int(rec.dnaSize))
+    }
+
+    return len(rec.sequence)
+}
+
+// Return the size of offsets in the file index
+func (r *Reader) indexOffsetLen() (int) {
+    if r.hdr.version == VERSION_LONG {
+        return INDEX_OFFSET_LEN_LONG
+    }
+
+    return INDEX_OFFSET_LEN
+}
+
+// Parse the file index of a 2bit file. With a lazy index only the start of
+// the index is recorded and entries are read on demand.
 func (r *Reader) parseIndex() (error) {
-    r.index = make(map[string]int)
+    r.index = make(map[string]int64)
+    r.indexPos = HEADER_SIZE
+
+    if r.lazy {
+        return nil
+    }
 
-    for i := 0; i < r.Count(); i++ {
-        size := make([]byte, 1)
-        _, err := r.reader.Read(size)
+    _, err := r.scanIndex("")
+    return err
+}
+
+// Read index entries not yet read, stopping after the entry for name. An
+// empty name reads the remaining index. Returns true if name was found.
+func (r *Reader) scanIndex(name string) (bool, error) {
+    if r.indexRead >= r.Count() {
+        return false, nil
+    }
+
+    _, err := r.reader.Seek(r.indexPos, 0)
+    if err != nil {
+        return false, err
+    }
+
+    size := make([]byte, 1)
+    offset := make([]byte, r.indexOffsetLen())
+    for r.indexRead < r.Count() {
+        _, err := io.ReadFull(r.reader, size)
         if err != nil {
-            return fmt.Errorf("Failed to read file index: %s", err)
+            return false, fmt.Errorf("Failed to read file index: %s", err)
         }
 
-        name := make([]byte, size[0])
-        _, err = r.reader.Read(name)
+        entry := make([]byte, size[0])
+        _, err = io.ReadFull(r.reader, entry)
         if err != nil {
-            return fmt.Errorf("Failed to read file index: %s", err)
+            return false, fmt.Errorf("Failed to read file index: %s", err)
         }
 
-        offset := make([]byte, 4)
-        _, err = r.reader.Read(offset)
+        _, err = io.ReadFull(r.reader, offset)
         if err != nil {
-            return fmt.Errorf("Failed to read file index: %s", err)
+            return false, fmt.Errorf("Failed to read file index: %s", err)
+        }
+
+        r.indexRead++
+        r.indexPos += int64(INDEX_NAME_SIZE_LEN+len(entry)+len(offset))
+
+        if r.filter != nil && !r.filter(string(entry)) {
+            continue
         }
 
-        r.index[string(name)] = int(r.hdr.byteOrder.Uint32(offset))
+        if len(offset) == INDEX_OFFSET_LEN_LONG {
+            off := r.hdr.byteOrder.Uint64(offset)
+            if off > math.MaxInt64 {
+                return false, fmt.Errorf("Invalid offset for %s: %d", entry, off)
+            }
+            r.index[string(entry)] = int64(off)
+        } else {
+            r.index[string(entry)] = int64(r.hdr.byteOrder.Uint32(offset))
+        }
+        r.entries = append(r.entries, IndexEntry{Name: string(entry), Offset: r.index[string(entry)]})
+
+        if len(name) > 0 && string(entry) == name {
+            return true, nil
+        }
     }
 
-    return nil
+    return false, nil
+}
+
+// Returns the file offset of the record for sequence name, reading the
+// index as far as needed for a lazy index
+func (r *Reader) lookup(name string) (int64, bool, error) {
+    if offset, ok := r.index[name]; ok {
+        return offset, true, nil
+    }
+
+    found, err := r.scanIndex(name)
+    if err != nil || !found {
+        return 0, false, err
+    }
+
+    return r.index[name], true, nil
+}
+
+// Read the remainder of a lazy index
+func (r *Reader) loadIndex() (error) {
+    _, err := r.scanIndex("")
+    return err
 }
 
 /*
@@ -134,8 +275,8 @@ func (r *Reader) Seek(offset int64) (int64, error) {
 
 // Parse the header of a 2bit file
 func (r *Reader) parseHeader() (error) {
-    b := make([]byte, 16)
-    _, err := r.reader.Read(b)
+    b := make([]byte, HEADER_SIZE)
+    _, err := io.ReadFull(r.reader, b)
     if err != nil {
         return err
     }
@@ -152,7 +293,7 @@ func (r *Reader) parseHeader() (error) {
     }
 
     r.hdr.version = r.hdr.byteOrder.Uint32(b[4:8])
-    if r.hdr.version != uint32(0) {
+    if r.hdr.version != uint32(VERSION) && r.hdr.version != uint32(VERSION_LONG) {
         return fmt.Errorf("Unsupported version %d", r.hdr.version)
     }
     r.hdr.count = r.hdr.byteOrder.Uint32(b[8:12])
@@ -165,37 +306,59 @@ func (r *Reader) parseHeader() (error) {
 }
 
 // Parse the nBlock and mBlock coordinates
-func (r *Reader) parseBlockCoords() ([]*Block, error) {
+func (r *Reader) parseBlockCoords() (Blocks, error) {
     buf := make([]byte, 4)
-    _, err := r.reader.Read(buf)
+    _, err := io.ReadFull(r.reader, buf)
     if err != nil {
         return nil, fmt.Errorf("Failed to read blockCount: %s", err)
     }
 
     count := r.hdr.byteOrder.Uint32(buf)
 
-    starts := make([]uint32, count)
-    for i := range(starts) {
-        _, err := r.reader.Read(buf)
+    // a corrupt count must not allocate more than the file could hold. When
+    // the size is unknown the arrays grow as entries are actually read.
+    capacity := int(count)
+    if r.size >= 0 {
+        offset, err := r.reader.Seek(0, io.SeekCurrent)
+        if err != nil {
+            return nil, err
+        }
+        if offset+2*RECORD_BLOCK_FIELD_LEN*int64(count) > r.size {
+            return nil, fmt.Errorf("%w: block table of %d entries extends past end of file", ErrTruncated, count)
+        }
+    } else if capacity > defaultBufSize {
+        capacity = defaultBufSize
+    }
+
+    starts := make([]uint32, 0, capacity)
+    for i := uint32(0); i < count; i++ {
+        _, err := io.ReadFull(r.reader, buf)
         if err != nil {
             return nil, fmt.Errorf("Failed to block start: %s", err)
         }
-        starts[i] = r.hdr.byteOrder.Uint32(buf)
+        starts = append(starts, r.hdr.byteOrder.Uint32(buf))
     }
 
-    sizes := make([]uint32, count)
-    for i := range(sizes) {
-        _, err := r.reader.Read(buf)
+    sizes := make([]uint32, 0, capacity)
+    for i := uint32(0); i < count; i++ {
+        _, err := io.ReadFull(r.reader, buf)
         if err != nil {
             return nil, fmt.Errorf("Failed to block size: %s", err)
         }
-        sizes[i] = r.hdr.byteOrder.Uint32(buf)
+        sizes = append(sizes, r.hdr.byteOrder.Uint32(buf))
     }
 
-    blocks := make([]*Block, len(starts))
+    blocks := make(Blocks, len(starts))
 
     for i := range(starts) {
-        blocks[i] = &Block{start: int(starts[i]), count: int(sizes[i])}
+        if uint64(starts[i])+uint64(sizes[i]) > math.MaxUint32 {
+            return nil, fmt.Errorf("Block %d-%d overflows 32-bit coordinates", starts[i], sizes[i])
+        }
+        end, err := toInt(int64(starts[i])+int64(sizes[i]))
+        if err != nil {
+            return nil, err
+        }
+        blocks[i] = &Block{Start: int(starts[i]), Length: end-int(starts[i])}
     }
 
     return blocks, nil
@@ -203,23 +366,42 @@ func (r *Reader) parseBlockCoords() ([]*Block, error) {
 
 // Parse the sequence record information
 func (r *Reader) parseRecord(name string, coords bool) (*seqRecord, error) {
+    if rec, ok := r.records[name]; ok {
+        return rec, nil
+    }
+
     rec := new(seqRecord)
 
-    offset, ok := r.index[name]
+    offset, ok, err := r.lookup(name)
+    if err != nil {
+        return nil, err
+    }
     if !ok {
         return nil, fmt.Errorf("Invalid sequence name: %s", name)
     }
 
-    r.reader.Seek(int64(offset), 0)
+    if r.strict && coords {
+        err = r.checkOffset(name, offset)
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    r.reader.Seek(offset, 0)
 
     buf := make([]byte, 4)
-    _, err := r.reader.Read(buf)
+    _, err = io.ReadFull(r.reader, buf)
     if err != nil {
         return nil, fmt.Errorf("Failed to read dnaSize: %s", err)
     }
 
     rec.dnaSize = r.hdr.byteOrder.Uint32(buf)
 
+    _, err = toInt(int64(rec.dnaSize))
+    if err != nil {
+        return nil, fmt.Errorf("Sequence %s is too large: %s", name, err)
+    }
+
     if coords {
         rec.nBlocks, err = r.parseBlockCoords()
         if err != nil {
@@ -231,7 +413,7 @@ func (r *Reader) parseRecord(name string, coords bool) (*seqRecord, error) {
             return nil, fmt.Errorf("Failed to read mBlocks: %s", err)
         }
 
-        _, err = r.reader.Read(buf)
+        _, err = io.ReadFull(r.reader, buf)
         if err != nil {
             return nil, fmt.Errorf("Failed to read reserved: %s", err)
         }
@@ -241,19 +423,89 @@ func (r *Reader) parseRecord(name string, coords bool) (*seqRecord, error) {
         if rec.reserved != uint32(0) {
             return nil, fmt.Errorf("Invalid reserved")
         }
+
+        err = r.checkPacked(name, rec)
+        if err != nil {
+            return nil, err
+        }
+
+        if r.strict {
+            err = r.checkRecord(name, offset, rec)
+            if err != nil {
+                return nil, err
+            }
+        }
+
+        if r.records == nil {
+            r.records = make(map[string]*seqRecord)
+        }
+        r.records[name] = rec
     }
 
     return rec, nil
 }
 
+// Check that every N and mask block of rec lies within the sequence. Blocks
+// past the end are ignored when reading but code indexing packed data by
+// block coordinates must reject them.
+func (rec *seqRecord) checkBlocks(name string) (error) {
+    size := int64(rec.dnaSize)
+    for _, blocks := range []Blocks{rec.nBlocks, rec.mBlocks} {
+        for _, b := range blocks {
+            if int64(b.Start)+int64(b.Length) > size {
+                return fmt.Errorf("%w: block %d-%d in %s extends past sequence length %d", ErrCorrupt, b.Start, b.End(), name, size)
+            }
+        }
+    }
+
+    return nil
+}
+
+// Record the offset of the packed dna for rec and verify the file is large
+// enough to hold it. The reader must be positioned just after the reserved
+// field of the record.
+func (r *Reader) checkPacked(name string, rec *seqRecord) (error) {
+    offset, err := r.reader.Seek(0, 1)
+    if err != nil {
+        return fmt.Errorf("Failed to locate packed dna: %s", err)
+    }
+    rec.offset = offset
+
+    // size is unknown when streaming
+    if r.size < 0 {
+        return nil
+    }
+
+    expected := packedSize64(int64(rec.dnaSize))
+    if offset+expected > r.size {
+        actual := r.size-offset
+        if actual < 0 {
+            actual = 0
+        }
+        return fmt.Errorf("%w: %s expected %d packed bytes, found %d", ErrTruncated, name, expected, actual)
+    }
+
+    return nil
+}
+
 // Return blocks of Ns in sequence with name
-func (r *Reader) NBlocks(name string) ([]*Block, error) {
+func (r *Reader) NBlocks(name string) (Blocks, error) {
     rec, err := r.parseRecord(name, true)
     if err != nil {
         return nil, err
     }
 
-    return rec.nBlocks, nil
+    return rec.nBlocks.copy(), nil
+}
+
+// Return masked (lower-case) blocks in sequence with name
+func (r *Reader) MBlocks(name string) (Blocks, error) {
+    rec, err := r.parseRecord(name, true)
+    if err != nil {
+        return nil, err
+    }
+
+    return rec.mBlocks.copy(), nil
 }
 
 // Read entire sequence.
@@ -261,126 +513,432 @@ func (r *Reader) Read(name string) ([]byte, error) {
     return r.ReadRange(name, 0, 0)
 }
 
-// Read sequence from start to end.
+// Normalize start and end for a sequence of length bases. Ranges are 0-based
+// and half-open: bases start to end-1 are read. An end of 0 reads through to
+// the end of the sequence. Any range on a zero-length sequence is normalized
+// to the empty range. Negative coordinates, ends past the end of the sequence
+// and empty ranges are errors.
+func clampRange(start, end, bases int) (int, int, error) {
+    if bases == 0 {
+        return 0, 0, nil
+    }
+
+    if end == 0 {
+        end = bases
+    }
+
+    if start < 0 || end < 0 {
+        return start, end, fmt.Errorf("Invalid range: %d-%d: negative coordinate", start, end)
+    }
+    if end > bases {
+        return start, end, fmt.Errorf("Invalid range: %d-%d: end past sequence length %d", start, end, bases)
+    }
+    if end <= start {
+        return start, end, fmt.Errorf("Invalid range: %d-%d", start, end)
+    }
+
+    return start, end, nil
+}
+
+// Apply the nBlocks and mBlocks of rec to seq which holds the bases from start
+// to end. N blocks are rendered using gap.
+func (rec *seqRecord) applyBlocks(seq []byte, start, end int, gap byte) {
+    for _, b := range rec.nBlocks {
+        lo, hi := b.clip(start, end)
+        if lo >= hi {
+            continue
+        }
+        fill(seq[lo-start:hi-start], gap)
+    }
+
+    for _, b := range rec.mBlocks {
+        lo, hi := b.clip(start, end)
+        if lo >= hi {
+            continue
+        }
+        toLower(seq[lo-start:hi-start])
+    }
+}
+
+// Set every byte of seq to c
+func fill(seq []byte, c byte) {
+    if len(seq) == 0 {
+        return
+    }
+
+    seq[0] = c
+    for n := 1; n < len(seq); n *= 2 {
+        copy(seq[n:], seq[:n])
+    }
+}
+
+const (
+    lowBits  = 0x0101010101010101
+    highBits = 0x8080808080808080
+)
+
+// Convert upper case ASCII letters in seq to lower case in place. Bytes are
+// processed 8 at a time: for each byte the high bit of the lane is set when
+// the byte is in 'A'-'Z' and then shifted down to the case bit (0x20).
+func toLower(seq []byte) {
+    i := 0
+    for ; i+8 <= len(seq); i += 8 {
+        w := binary.LittleEndian.Uint64(seq[i:])
+        heptets := w &^ highBits
+        geA := heptets + (0x80-'A')*lowBits
+        gtZ := heptets + (0x80-'Z'-1)*lowBits
+        upper := geA &^ gtZ &^ w & highBits
+        binary.LittleEndian.PutUint64(seq[i:], w | upper>>2)
+    }
+
+    for ; i < len(seq); i++ {
+        if seq[i] >= 'A' && seq[i] <= 'Z' {
+            seq[i] += 32 // ('a' - 'A')
+        }
+    }
+}
+
+// Read sequence from start to end. Coordinates are 0-based and half-open, so
+// bases start to end-1 are returned. An end of 0 reads to the end of the
+// sequence. Negative coordinates, empty ranges and ranges extending past the
+// end of the sequence are errors.
 func (r *Reader) ReadRange(name string, start, end int) ([]byte, error) {
-    rec, err := r.parseRecord(name, true)
+    rec, start, end, err := r.rangeRecord(name, start, end)
     if err != nil {
         return nil, err
     }
 
-    bases := int(rec.dnaSize)
+    err = checkSize(name, start, end, r.maxBases)
+    if err != nil {
+        return nil, err
+    }
 
-    // TODO: handle -1 ?
-    if start < 0 {
-        start = 0
+    seq := make([]byte, end-start)
+    err = r.readInto(seq, rec, start, end)
+    if err != nil {
+        return nil, err
     }
 
-    //TODO: should we error out here?
-    if end > bases {
-        end = bases
+    return seq, nil
+}
+
+// Read sequence from start to end into dst and return the number of bases
+// written. dst is never grown: if it is shorter than the requested range
+// io.ErrShortBuffer is returned along with the number of bases required, so
+// callers can grow dst and retry. Reusing dst across calls avoids allocating
+// per read.
+func (r *Reader) ReadRangeInto(dst []byte, name string, start, end int) (int, error) {
+    rec, start, end, err := r.rangeRecord(name, start, end)
+    if err != nil {
+        return 0, err
     }
 
-    // TODO: handle -1 ?
-    if end == 0 || end < 0 {
-        end = bases
+    if len(dst) < end-start {
+        return end-start, io.ErrShortBuffer
     }
 
-    if end <= start {
-        return nil, fmt.Errorf("Invalid range: %d-%d", start, end)
+    err = r.readInto(dst, rec, start, end)
+    if err != nil {
+        return 0, err
     }
 
-    bases = end-start
-    size := packedSize(bases)
-    if start > 0 {
-        shift := packedSize(start)
-        if start % 4 != 0 {
-            shift--
-            size++
+    return end-start, nil
+}
+
+// Decode n bases from packed into dst. phase is the position (0-3) of the
+// first base within the first packed byte.
+func decode(dst, packed []byte, phase, n int) {
+    if n <= 0 {
+        return
+    }
+
+    i := 0
+    k := 0
+    if phase > 0 {
+        for j := phase; j < BASES_PER_BYTE && i < n; j++ {
+            dst[i] = unpacked[packed[k]][j]
+            i++
         }
+        k++
+    }
 
-        r.reader.Seek(int64(shift), 1)
+    for ; i+BASES_PER_BYTE <= n; i += BASES_PER_BYTE {
+        copy(dst[i:i+BASES_PER_BYTE], unpacked[packed[k]][:])
+        k++
     }
 
-    dna := make([]byte, size*4)
-    chunks := size/defaultBufSize
-    if size % defaultBufSize > 0 {
-        chunks++
+    if i < n {
+        copy(dst[i:n], unpacked[packed[k]][:n-i])
     }
+}
 
-    buf := make([]byte, defaultBufSize)
+// Decode bases start to end of rec into dst and apply N and mask blocks
+func (r *Reader) readInto(dst []byte, rec *seqRecord, start, end int) (error) {
+    // gap-only ranges are synthesized from the block tables
+    if rec.gapOnly(start, end) {
+        rec.applyBlocks(dst[0:end-start], start, end, r.gap)
+        return nil
+    }
 
-    i := 0
-    for c := 0; c < chunks; c++ {
-        sz := defaultBufSize
-        if i+defaultBufSize > size {
-            sz = size % defaultBufSize
-        }
-        n, err := r.reader.Read(buf[0:sz])
-        if n != sz {
-            return nil, fmt.Errorf("Failed to read %d dna bytes: %s", sz, err)
-        } else if err != nil && err != io.EOF {
-            return nil, fmt.Errorf("Failed to read dna bytes: %s", err)
-        }
-
-        for k := 0; k < n; k++ {
-            base := buf[k]
-            for j := 3; j >= 0; j-- {
-                dna[(i*4)+j] = BYTES2NT[int(base & 0x3)]
-                base >>= 2
-            }
-            i++
+    first := start/BASES_PER_BYTE
+    size := packedSize(end)-first
+
+    _, err := r.reader.Seek(rec.offset+int64(first), 0)
+    if err != nil {
+        return err
+    }
+
+    if r.buf == nil {
+        r.buf = make([]byte, defaultBufSize)
+    }
+
+    phase := start%BASES_PER_BYTE
+    out := dst[0:end-start]
+    for size > 0 {
+        sz := len(r.buf)
+        if size < sz {
+            sz = size
         }
+
+        n, err := io.ReadFull(r.reader, r.buf[0:sz])
+        if err != nil {
+            return fmt.Errorf("Failed to read %d dna bytes, got %d: %s", sz, n, err)
+        }
+
+        bases := sz*BASES_PER_BYTE-phase
+        if bases > len(out) {
+            bases = len(out)
+        }
+        decode(out, r.buf[0:sz], phase, bases)
+
+        out = out[bases:]
+        phase = 0
+        size -= sz
     }
 
-    seq := dna[(start%4):(start%4)+bases]
+    rec.applyBlocks(dst[0:end-start], start, end, r.gap)
 
-    for _, b := range rec.nBlocks {
-        if b.Length() < start || b.start > end {
-            continue
+    return nil
+}
+
+// Read a single base at pos (0-based) with N and mask blocks applied. Only
+// the packed byte holding the base is read; block tables are cached.
+func (r *Reader) Base(name string, pos int) (byte, error) {
+    rec, err := r.parseRecord(name, true)
+    if err != nil {
+        return 0, err
+    }
+
+    if pos < 0 || pos >= int(rec.dnaSize) {
+        return 0, fmt.Errorf("Invalid position: %d", pos)
+    }
+
+    return r.base(rec, pos)
+}
+
+// Read a single base at pos of rec
+func (r *Reader) base(rec *seqRecord, pos int) (byte, error) {
+    if inBlocks(rec.nBlocks, pos) {
+        if inBlocks(rec.mBlocks, pos) && r.gap >= 'A' && r.gap <= 'Z' {
+            return r.gap + 32, nil
         }
-        idx := b.start-start
-        cnt := b.count
-        if idx < 0 {
-            cnt += idx
-            idx = 0
+        return r.gap, nil
+    }
+
+    _, err := r.reader.Seek(rec.offset+int64(pos/BASES_PER_BYTE), 0)
+    if err != nil {
+        return 0, err
+    }
+
+    b := make([]byte, 1)
+    _, err = io.ReadFull(r.reader, b)
+    if err != nil {
+        return 0, fmt.Errorf("Failed to read dna byte: %s", err)
+    }
+
+    base := unpacked[b[0]][pos%BASES_PER_BYTE]
+    if inBlocks(rec.mBlocks, pos) {
+        base += 32
+    }
+
+    return base, nil
+}
+
+// Read the bases at positions (0-based) with N and mask blocks applied. The
+// returned bases are in the same order as positions. Positions are sorted
+// internally so the packed data is read in a single forward sweep, which is
+// much faster than calling Base for each position.
+func (r *Reader) Bases(name string, positions []int) ([]byte, error) {
+    rec, err := r.parseRecord(name, true)
+    if err != nil {
+        return nil, err
+    }
+
+    order := make([]int, len(positions))
+    for i, pos := range positions {
+        if pos < 0 || pos >= int(rec.dnaSize) {
+            return nil, fmt.Errorf("Invalid position: %d", pos)
         }
-        for i := 0; i < cnt; i++ {
-            seq[idx] = BASE_N
-            idx++
-            if idx >= len(seq) {
-                break
+        order[i] = i
+    }
+
+    sort.Slice(order, func(i, j int) bool {
+        return positions[order[i]] < positions[order[j]]
+    })
+
+    if r.buf == nil {
+        r.buf = make([]byte, defaultBufSize)
+    }
+
+    bases := make([]byte, len(positions))
+
+    // packed bytes first to first+len(chunk) are currently buffered
+    first := -1
+    var chunk []byte
+    for _, i := range order {
+        pos := positions[i]
+        k := pos/BASES_PER_BYTE
+        if first < 0 || k >= first+len(chunk) {
+            sz := packedSize(int(rec.dnaSize))-k
+            if sz > len(r.buf) {
+                sz = len(r.buf)
             }
+
+            _, err := r.reader.Seek(rec.offset+int64(k), 0)
+            if err != nil {
+                return nil, err
+            }
+
+            chunk = r.buf[0:sz]
+            _, err = io.ReadFull(r.reader, chunk)
+            if err != nil {
+                return nil, fmt.Errorf("Failed to read dna bytes: %s", err)
+            }
+            first = k
         }
+
+        bases[i] = unpacked[chunk[k-first]][pos%BASES_PER_BYTE]
     }
 
-    for _, b := range rec.mBlocks {
-        if b.Length() < start || b.start > end {
-            continue
+    for i, pos := range positions {
+        if inBlocks(rec.nBlocks, pos) {
+            bases[i] = r.gap
         }
-        idx := b.start-start
-        cnt := b.count
-        if idx < 0 {
-            cnt += idx
-            idx = 0
+        if inBlocks(rec.mBlocks, pos) && bases[i] >= 'A' && bases[i] <= 'Z' {
+            bases[i] += 32
         }
-        for i := 0; i < cnt; i++ {
-            // Faster lower case.. see: https://groups.google.com/forum/#!topic/golang-nuts/Il2DX4xpW3w
-            seq[idx] = seq[idx] + 32 // ('a' - 'A')
-            idx++
-            if idx >= len(seq) {
-                break
-            }
+    }
+
+    return bases, nil
+}
+
+// Returns true if start to end falls entirely within a single N block
+func (rec *seqRecord) gapOnly(start, end int) (bool) {
+    if start >= end {
+        return false
+    }
+
+    i := sort.Search(len(rec.nBlocks), func(i int) bool {
+        return rec.nBlocks[i].Start+rec.nBlocks[i].Length > start
+    })
+
+    return i < len(rec.nBlocks) && rec.nBlocks[i].Start <= start && rec.nBlocks[i].Start+rec.nBlocks[i].Length >= end
+}
+
+// Returns true if pos falls within one of blocks. blocks must be sorted by
+// start and non-overlapping as they are in valid 2bit files.
+func inBlocks(blocks Blocks, pos int) (bool) {
+    i := sort.Search(len(blocks), func(i int) bool {
+        return blocks[i].Start+blocks[i].Length > pos
+    })
+
+    return i < len(blocks) && blocks[i].Start <= pos
+}
+
+// ReadOption configures a Reader
+type ReadOption func(*Reader) (error)
+
+// GapChar sets the character used to render N blocks in place of N. Any
+// character except the nucleotides A, C, G, T and U is allowed, for example
+// '-' or 'n'.
+func GapChar(c byte) (ReadOption) {
+    return func(r *Reader) (error) {
+        switch c {
+        case 'A', 'C', 'G', 'T', 'U', 'a', 'c', 'g', 't', 'u':
+            return fmt.Errorf("Invalid gap character: %q is a nucleotide", c)
         }
+        r.gap = c
+        return nil
     }
+}
 
-    return seq, nil
+// BufferSize sets the size of the read buffer placed in front of the
+// underlying reader. Defaults to 4096 bytes. A size of 0 disables buffering.
+func BufferSize(n int) (ReadOption) {
+    return func(r *Reader) (error) {
+        if n < 0 {
+            return fmt.Errorf("Invalid buffer size: %d", n)
+        }
+        r.bufSize = n
+        return nil
+    }
+}
+
+// ReadAhead enables read ahead of n bytes for sequential access. When a read
+// continues where the previous one stopped, as in a full sequence scan, the
+// underlying reader is read in chunks of n bytes while random access keeps
+// using the smaller BufferSize reads. Useful for network-backed readers where
+// each read has a high fixed cost. A size of 0 disables read ahead (default).
+func ReadAhead(n int) (ReadOption) {
+    return func(r *Reader) (error) {
+        if n < 0 {
+            return fmt.Errorf("Invalid read ahead size: %d", n)
+        }
+        r.readAhead = n
+        return nil
+    }
 }
 
 // NewReader returns a new TwoBit file reader which reads from r
-func NewReader(r io.ReadSeeker) (*Reader, error) {
+func NewReader(r io.ReadSeeker, opts ...ReadOption) (*Reader, error) {
     tb := new(Reader)
-    tb.reader = r
-    err := tb.parseHeader()
+    tb.gap = BASE_N
+    tb.bufSize = defaultBufSize
+
+    for _, opt := range opts {
+        err := opt(tb)
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    if len(tb.transforms) > 0 {
+        src, err := applyTransforms(r, tb.transforms)
+        if err != nil {
+            return nil, err
+        }
+        r = io.NewSectionReader(src, 0, src.Size())
+    }
+
+    if ra, ok := r.(io.ReaderAt); ok {
+        tb.src = ra
+    }
+
+    tb.reader = tb.buffered(r)
+    r = tb.reader
+
+    size, err := r.Seek(0, 2)
+    if err != nil {
+        return nil, err
+    }
+    tb.size = size
+
+    _, err = r.Seek(0, 0)
+    if err != nil {
+        return nil, err
+    }
+
+    err = tb.parseHeader()
     if err != nil {
         return nil, err
     }
@@ -393,8 +951,107 @@ func NewReader(r io.ReadSeeker) (*Reader, error) {
     return tb, nil
 }
 
+// Wrap r in a buffered reader according to the buffer options
+func (r *Reader) buffered(rs io.ReadSeeker) (io.ReadSeeker) {
+    if r.bufSize == 0 && r.readAhead == 0 {
+        return rs
+    }
+
+    b := newBufferedReadSeeker(rs, r.bufSize)
+    b.aheadSize = r.readAhead
+    return b
+}
+
+// Returns a Reader sharing the parsed header, index and records of r but
+// with its own position in the underlying storage, so both can be used from
+// different goroutines. Records must be parsed before forking as the record
+// cache is shared. The underlying reader must implement io.ReaderAt.
+func (r *Reader) fork() (*Reader, error) {
+    if r.src == nil {
+        return nil, fmt.Errorf("Underlying reader does not support concurrent access")
+    }
+
+    f := new(Reader)
+    *f = *r
+    f.file = nil
+    f.buf = nil
+    f.reader = r.buffered(io.NewSectionReader(r.src, 0, r.size))
+
+    return f, nil
+}
+
+// PackedRegion describes where the packed DNA of a sequence is stored in the
+// 2bit file
+type PackedRegion struct {
+    Offset     int64 // byte offset of the first packed byte
+    Length     int   // number of packed bytes
+    DnaSize    int   // number of bases
+}
+
+// Returns the location of the packed DNA for every sequence in the 2bit file.
+// Combined with the ENCODE_* constants this allows consumers to mmap the file
+// and slice the packed regions directly. N and mask blocks are not applied to
+// the packed data.
+func (r *Reader) Offsets() (map[string]*PackedRegion, error) {
+    err := r.loadIndex()
+    if err != nil {
+        return nil, err
+    }
+
+    offsets := make(map[string]*PackedRegion, len(r.index))
+    for name := range r.index {
+        rec, err := r.parseRecord(name, true)
+        if err != nil {
+            return nil, err
+        }
+
+        offsets[name] = &PackedRegion{
+            Offset: rec.offset,
+            Length: packedSize(int(rec.dnaSize)),
+            DnaSize: int(rec.dnaSize),
+        }
+    }
+
+    return offsets, nil
+}
+
+// Read the raw packed bytes covering bases start to end. The first returned
+// byte holds base start at position start%4 (counting from the high bits).
+// N and mask blocks are not applied.
+func (r *Reader) ReadPackedRange(name string, start, end int) ([]byte, error) {
+    rec, err := r.parseRecord(name, true)
+    if err != nil {
+        return nil, err
+    }
+
+    start, end, err = clampRange(start, end, int(rec.dnaSize))
+    if err != nil {
+        return nil, err
+    }
+
+    first := start/BASES_PER_BYTE
+    last := packedSize(end)
+
+    _, err = r.reader.Seek(rec.offset+int64(first), 0)
+    if err != nil {
+        return nil, err
+    }
+
+    packed := make([]byte, last-first)
+    _, err = io.ReadFull(r.reader, packed)
+    if err != nil {
+        return nil, fmt.Errorf("Failed to read packed dna: %s", err)
+    }
+
+    return packed, nil
+}
+
 // Returns the length for sequence with name
 func (r *Reader) Length(name string) (int, error) {
+    if n, ok := r.lengths[name]; ok {
+        return n, nil
+    }
+
     rec, err := r.parseRecord(name, false)
     if err != nil {
         return -1, err
@@ -403,6 +1060,47 @@ func (r *Reader) Length(name string) (int, error) {
     return int(rec.dnaSize), nil
 }
 
+// Returns the lengths of all sequences in the 2bit file keyed by name. The
+// lengths are read in a single pass over the file and cached.
+func (r *Reader) LengthAll() (map[string]int, error) {
+    if r.lengths == nil {
+        names := r.namesByOffset()
+
+        lengths := make(map[string]int, len(names))
+        for _, name := range names {
+            rec, err := r.parseRecord(name, false)
+            if err != nil {
+                return nil, err
+            }
+            lengths[name] = int(rec.dnaSize)
+        }
+
+        r.lengths = lengths
+    }
+
+    all := make(map[string]int, len(r.lengths))
+    for name, n := range r.lengths {
+        all[name] = n
+    }
+
+    return all, nil
+}
+
+// Returns the total length of all sequences in the 2bit file
+func (r *Reader) TotalLength() (int, error) {
+    lengths, err := r.LengthAll()
+    if err != nil {
+        return -1, err
+    }
+
+    total := 0
+    for _, n := range lengths {
+        total += n
+    }
+
+    return total, nil
+}
+
 // Returns the length for sequence with name but does not count Ns
 func (r *Reader) LengthNoN(name string) (int, error) {
     rec, err := r.parseRecord(name, true)
@@ -412,14 +1110,17 @@ func (r *Reader) LengthNoN(name string) (int, error) {
 
     n := 0
     for _, b := range rec.nBlocks {
-        n += b.count
+        n += b.Length
     }
 
     return int(rec.dnaSize)-n, nil
 }
 
-// Returns the names of sequences in the 2bit file
+// Returns the names of sequences in the 2bit file. With a LazyIndex this reads
+// the remainder of the index and returns the names read successfully.
 func (r *Reader) Names() ([]string) {
+    r.loadIndex()
+
     names := make([]string, len(r.index))
 
     i := 0
@@ -431,7 +1132,40 @@ func (r *Reader) Names() ([]string) {
     return names
 }
 
-// Returns the count of sequences in the 2bit file
+// IndexEntry is one entry of the file index
+type IndexEntry struct {
+    Name    string
+    Offset  int64 // byte offset of the sequence record
+}
+
+// Index returns a copy of the file index in the order it is stored, for
+// diagnostics and for tools that need the raw record offsets, such as a
+// combined index over many files. Entries excluded by a NameFilter are
+// omitted. With a LazyIndex the rest of the index is read first.
+func (r *Reader) Index() ([]IndexEntry, error) {
+    err := r.loadIndex()
+    if err != nil {
+        return nil, err
+    }
+
+    entries := make([]IndexEntry, len(r.entries))
+    copy(entries, r.entries)
+
+    return entries, nil
+}
+
+// Returns the names of sequences in the order they are stored in the file
+func (r *Reader) namesByOffset() ([]string) {
+    names := r.Names()
+    sort.Slice(names, func(i, j int) bool {
+        return r.index[names[i]] < r.index[names[j]]
+    })
+
+    return names
+}
+
+// Returns the count of sequences in the 2bit file. This includes sequences
+// excluded by a NameFilter.
 func (r *Reader) Count() (int) {
     return int(r.hdr.count)
 }
@@ -441,6 +1175,31 @@ func (r *Reader) Version() (int) {
     return int(r.hdr.version)
 }
 
+// Returns the byte order of the 2bit file
+func (r *Reader) ByteOrder() (binary.ByteOrder) {
+    return r.hdr.byteOrder
+}
+
+// HeaderInfo holds the raw header fields of a 2bit file
+type HeaderInfo struct {
+    Signature    uint32
+    Version      uint32
+    Count        uint32
+    Reserved     uint32
+    ByteOrder    binary.ByteOrder
+}
+
+// Returns the raw header of the 2bit file
+func (r *Reader) Header() (HeaderInfo) {
+    return HeaderInfo{
+        Signature: r.hdr.sig,
+        Version: r.hdr.version,
+        Count: r.hdr.count,
+        Reserved: r.hdr.reserved,
+        ByteOrder: r.hdr.byteOrder,
+    }
+}
+
 // Unpack array of bytes to DNA string of length sz
 func Unpack(raw []byte, sz int) (string) {
     var dna bytes.Buffer
@@ -481,16 +1240,23 @@ func Pack(s string) ([]byte, error) {
     return out, nil
 }
 
+// WriterOption configures a Writer
+type WriterOption func(*Writer)
+
 // New Writer
-func NewWriter() (*Writer) {
+func NewWriter(opts ...WriterOption) (*Writer) {
     tb := new(Writer)
     tb.records = make(map[string]*seqRecord)
 
+    for _, opt := range opts {
+        opt(tb)
+    }
+
     return tb
 }
 
-func mapMBlocks(seq string) []*Block {
-    blocks := make([]*Block, 0)
+func mapMBlocks(seq string) Blocks {
+    blocks := make(Blocks, 0)
 
     n      := len(seq)
     start  := 0
@@ -504,21 +1270,21 @@ func mapMBlocks(seq string) []*Block {
             }
         } else {
             if isLast {
-                blocks = append(blocks, &Block{start:start, count: i-start})
+                blocks = append(blocks, &Block{Start: start, Length: i-start})
             }
         }
         isLast = match
     }
 
     if isLast {
-        blocks = append(blocks, &Block{start:start, count: n-start})
+        blocks = append(blocks, &Block{Start: start, Length: n-start})
     }
 
     return blocks
 }
 
-func mapNBlocks(seq string) []*Block {
-    blocks := make([]*Block, 0)
+func mapNBlocks(seq string) Blocks {
+    blocks := make(Blocks, 0)
 
     n      := len(seq)
     start  := 0
@@ -532,14 +1298,14 @@ func mapNBlocks(seq string) []*Block {
             }
         } else {
             if isLast {
-                blocks = append(blocks, &Block{start:start, count: i-start})
+                blocks = append(blocks, &Block{Start: start, Length: i-start})
             }
         }
         isLast = match
     }
 
     if isLast {
-        blocks = append(blocks, &Block{start:start, count: n-start})
+        blocks = append(blocks, &Block{Start: start, Length: n-start})
     }
 
     return blocks
@@ -547,33 +1313,164 @@ func mapNBlocks(seq string) []*Block {
 
 // Add sequence
 func (w *Writer) Add(name, seq string) (error) {
-    if len(name) > 255 {
-        return fmt.Errorf("Name string cannot be longer than 255 characters")
+    if w.closed {
+        return ErrClosed
+    }
+    err := w.checkName(name)
+    if err != nil {
+        return err
+    }
+    if uint64(len(seq)) > math.MaxUint32 {
+        return fmt.Errorf("Sequence %s is longer than %d bases", name, uint32(math.MaxUint32))
+    }
+
+    if w.qc != nil || !w.allowAmino {
+        c := composition(seq)
+        err := w.checkNucleotide(name, c)
+        if err != nil {
+            return err
+        }
+        err = w.checkQC(name, len(seq), c.N, c.Other+c.AminoAcid)
+        if err != nil {
+            return err
+        }
     }
+
+    // only pay for hashing the sequence when dedup is on
+    if w.dedup != dedupOff {
+        err = w.checkDuplicate(name, sha256.Sum256([]byte(seq)))
+        if err != nil {
+            return err
+        }
+    }
+
     rec := new(seqRecord)
     rec.dnaSize = uint32(len(seq))
     rec.nBlocks = mapNBlocks(seq)
     rec.mBlocks = mapMBlocks(seq)
 
-    pack, err := Pack(seq)
-    if err != nil {
-        return err
+    // the format still requires packed bytes for gap-only sequences but they
+    // are all zero (T) so there is nothing to pack
+    if rec.gapOnly(0, len(seq)) {
+        rec.sequence = make([]byte, packedSize(len(seq)))
+    } else {
+        pack, err := Pack(seq)
+        if err != nil {
+            return err
+        }
+        rec.sequence = pack
     }
 
-    rec.sequence = pack
+    return w.addRecord(name, rec)
+}
 
+// Store the record for sequence name. New names are appended to the
+// insertion order, replacing an existing sequence keeps its position.
+func (w *Writer) setRecord(name string, rec *seqRecord) {
+    if old, ok := w.records[name]; ok {
+        w.memory -= old.memory()
+    } else {
+        w.order = append(w.order, name)
+    }
     w.records[name] = rec
+    w.memory += rec.memory()
+}
 
-    return nil
+// Remove the record for sequence name, which must be the last added
+func (w *Writer) removeLast(name string) {
+    if rec, ok := w.records[name]; ok {
+        w.memory -= rec.memory()
+        delete(w.records, name)
+    }
+    if n := len(w.order); n > 0 && w.order[n-1] == name {
+        w.order = w.order[:n-1]
+    }
+}
+
+// SequenceReport describes where a sequence was written
+type SequenceReport struct {
+    Name           string
+    Offset         int64 // byte offset of the sequence record
+    PackedOffset   int64 // byte offset of the packed DNA
+    PackedSize     int   // number of packed DNA bytes
+    DnaSize        int   // number of bases
+}
+
+// WriteReport summarizes the output of WriteTo
+type WriteReport struct {
+    Bytes          int64 // total bytes written
+    IndexSize      int   // size of the file index in bytes
+    Sequences      []SequenceReport // in file order
+}
+
+// Returns the report of the last successful WriteTo (or Close for Writers
+// returned by Create), nil if nothing has been written
+func (w *Writer) Report() (*WriteReport) {
+    return w.report
+}
+
+// Encode rec as an on-disk sequence record
+func (rec *seqRecord) encode() ([]byte) {
+    sz := rec.size()
+    buf := make([]byte, sz)
+
+    binary.LittleEndian.PutUint32(buf[0:4], rec.dnaSize)
+    binary.LittleEndian.PutUint32(buf[4:8], uint32(len(rec.nBlocks)))
+    idx := 8
+    for _, b := range rec.nBlocks {
+        binary.LittleEndian.PutUint32(buf[idx:idx+4], uint32(b.Start))
+        idx += 4
+    }
+    for _, b := range rec.nBlocks {
+        binary.LittleEndian.PutUint32(buf[idx:idx+4], uint32(b.Length))
+        idx += 4
+    }
+
+    binary.LittleEndian.PutUint32(buf[idx:idx+4], uint32(len(rec.mBlocks)))
+    idx += 4
+    for _, b := range rec.mBlocks {
+        binary.LittleEndian.PutUint32(buf[idx:idx+4], uint32(b.Start))
+        idx += 4
+    }
+    for _, b := range rec.mBlocks {
+        binary.LittleEndian.PutUint32(buf[idx:idx+4], uint32(b.Length))
+        idx += 4
+    }
+
+    // reserved
+    binary.LittleEndian.PutUint32(buf[idx:idx+4], uint32(0))
+    idx += 4
+
+    copy(buf[idx:sz], rec.sequence[:])
+
+    return buf
 }
 
 // Write sequences in 2bit format to out
 func (w *Writer) WriteTo(out io.Writer) (error) {
+    if w.building != nil {
+        return fmt.Errorf("Sequence %s was started but not ended", w.building.name)
+    }
+    if w.reserved != nil {
+        return fmt.Errorf("Sequences were reserved, use WriteIndex and Finish")
+    }
+
     outbuf := bufio.NewWriter(out)
+    w.report = nil
+    report := new(WriteReport)
+
+    offsetLen := INDEX_OFFSET_LEN
+    switch w.version {
+    case VERSION:
+    case VERSION_LONG:
+        offsetLen = INDEX_OFFSET_LEN_LONG
+    default:
+        return fmt.Errorf("Unsupported version %d", w.version)
+    }
 
     buf := make([]byte, 16)
     binary.LittleEndian.PutUint32(buf[0:4], SIG)
-    binary.LittleEndian.PutUint32(buf[4:8], uint32(0))
+    binary.LittleEndian.PutUint32(buf[4:8], w.version)
     binary.LittleEndian.PutUint32(buf[8:12], uint32(len(w.records)))
     binary.LittleEndian.PutUint32(buf[12:16], uint32(0))
     _, err := outbuf.Write(buf)
@@ -581,29 +1478,47 @@ func (w *Writer) WriteTo(out io.Writer) (error) {
         return err
     }
 
+    if w.layout.Alignment < 0 || w.layout.IndexPadding < 0 {
+        return fmt.Errorf("Invalid layout: %+v", w.layout)
+    }
+
+    names := w.sequenceOrder()
     idxSize := 0
-    recSize := 0
-    var names []string
-    for name, rec := range w.records {
-        names = append(names, name)
-        idxSize += 5 + len(name)
-        recSize += rec.size()
+    for _, name := range names {
+        idxSize += INDEX_NAME_SIZE_LEN + len(name) + offsetLen
     }
 
-    buf = make([]byte, idxSize)
-    offset := 16+idxSize
+    report.IndexSize = idxSize
+
+    buf = make([]byte, idxSize+w.layout.IndexPadding)
+    offset := int64(HEADER_SIZE+idxSize+w.layout.IndexPadding)
     idx := 0
     // Write out index
     for _, name := range names {
+        offset = w.layout.align(offset)
         buf[idx] = uint8(len(name))
         idx++
         for j := 0; j < len(name); j++ {
             buf[idx] = name[j]
             idx++
         }
-        binary.LittleEndian.PutUint32(buf[idx:idx+4], uint32(offset))
-        offset += w.records[name].size()
-        idx += 4
+        if offsetLen == INDEX_OFFSET_LEN_LONG {
+            binary.LittleEndian.PutUint64(buf[idx:idx+8], uint64(offset))
+        } else if offset > math.MaxUint32 {
+            return fmt.Errorf("Sequence %s starts past the 32-bit offset limit of version %d files", name, VERSION)
+        } else {
+            binary.LittleEndian.PutUint32(buf[idx:idx+4], uint32(offset))
+        }
+        rec := w.records[name]
+        report.Sequences = append(report.Sequences, SequenceReport{
+            Name: name,
+            Offset: offset,
+            PackedOffset: offset+int64(rec.size()-rec.packedLen()),
+            PackedSize: rec.packedLen(),
+            DnaSize: int(rec.dnaSize),
+        })
+        offset += int64(rec.size())
+        idx += offsetLen
     }
 
     _, err = outbuf.Write(buf)
@@ -612,44 +1527,37 @@ func (w *Writer) WriteTo(out io.Writer) (error) {
     }
 
     // Write out records
-    for _, name := range names {
-        rec := w.records[name]
-        sz := rec.size()
-        buf = make([]byte, sz)
-
-        binary.LittleEndian.PutUint32(buf[0:4], rec.dnaSize)
-        binary.LittleEndian.PutUint32(buf[4:8], uint32(len(rec.nBlocks)))
-        idx := 8
-        for _, b := range rec.nBlocks {
-            binary.LittleEndian.PutUint32(buf[idx:idx+4], uint32(b.start))
-            idx += 4
-        }
-        for _, b := range rec.nBlocks {
-            binary.LittleEndian.PutUint32(buf[idx:idx+4], uint32(b.count))
-            idx += 4
-        }
-
-        binary.LittleEndian.PutUint32(buf[idx:idx+4], uint32(len(rec.mBlocks)))
-        idx += 4
-        for _, b := range rec.mBlocks {
-            binary.LittleEndian.PutUint32(buf[idx:idx+4], uint32(b.start))
-            idx += 4
+    pos := int64(HEADER_SIZE+len(buf))
+    for i, name := range names {
+        rec, err := w.records[name].loaded()
+        if err != nil {
+            return err
         }
-        for _, b := range rec.mBlocks {
-            binary.LittleEndian.PutUint32(buf[idx:idx+4], uint32(b.count))
-            idx += 4
+        if pad := report.Sequences[i].Offset-pos; pad > 0 {
+            _, err = outbuf.Write(make([]byte, pad))
+            if err != nil {
+                return err
+            }
         }
 
-        // reserved
-        binary.LittleEndian.PutUint32(buf[idx:idx+4], uint32(0))
-        idx += 4
+        buf = rec.encode()
+        pos = report.Sequences[i].Offset+int64(len(buf))
 
-        copy(buf[idx:sz], rec.sequence[:])
-
-        _, err := outbuf.Write(buf)
+        _, err = outbuf.Write(buf)
         if err != nil {
             return err
         }
+
+        if w.onWritten != nil {
+            err = outbuf.Flush()
+            if err != nil {
+                return err
+            }
+            err = w.onWritten(&SequenceEvent{SequenceReport: report.Sequences[i], Digest: rec.digest()})
+            if err != nil {
+                return fmt.Errorf("Written hook failed for %s: %w", name, err)
+            }
+        }
     }
 
     err = outbuf.Flush()
@@ -657,5 +1565,8 @@ func (w *Writer) WriteTo(out io.Writer) (error) {
         return err
     }
 
+    report.Bytes = offset
+    w.report = report
+
     return nil
 }
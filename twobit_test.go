@@ -11,7 +11,14 @@ import (
     "os"
     "reflect"
     "crypto/md5"
+    "errors"
     "fmt"
+    "io"
+    "io/ioutil"
+    "math/rand"
+    "strings"
+    "encoding/binary"
+    "github.com/aebruno/twobit/testfixtures"
 )
 
 func openTestTwoBit() (*Reader, error) {
@@ -176,16 +183,16 @@ func TestAdd(t *testing.T) {
         t.Errorf("invalid mBlock count: %d != %d", len(rec.mBlocks), 3)
     }
 
-    nBlocks := []*Block{
-        &Block{start:9, count:4},
-        &Block{start:14, count:1},
-        &Block{start:16, count:1},
+    nBlocks := Blocks{
+        &Block{Start: 9, Length: 4},
+        &Block{Start: 14, Length: 1},
+        &Block{Start: 16, Length: 1},
     }
 
-    mBlocks := []*Block{
-        &Block{start:3, count:9},
-        &Block{start:13, count:5},
-        &Block{start:19, count:2},
+    mBlocks := Blocks{
+        &Block{Start: 3, Length: 9},
+        &Block{Start: 13, Length: 5},
+        &Block{Start: 19, Length: 2},
     }
 
     if !reflect.DeepEqual(nBlocks, rec.nBlocks) {
@@ -219,3 +226,633 @@ func TestWrite(t *testing.T) {
         t.Errorf("Invalid 2bit output. Failed md5sum check")
     }
 }
+
+func TestTruncated(t *testing.T) {
+    data, err := ioutil.ReadFile("examples/simple.2bit")
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    tb, err := NewReader(bytes.NewReader(data[:len(data)-2]))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    _, err = tb.Read("ex1")
+    if !errors.Is(err, ErrTruncated) {
+        t.Errorf("Expected ErrTruncated, got: %v", err)
+    }
+}
+
+func TestOffsets(t *testing.T) {
+    tb, err := openTestTwoBit()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    offsets, err := tb.Offsets()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    region, ok := offsets["ex1"]
+    if !ok {
+        t.Fatalf("ex1 not found in offsets")
+    }
+
+    if region.Offset != 88 || region.Length != 6 || region.DnaSize != 21 {
+        t.Errorf("Invalid packed region: %#v", region)
+    }
+
+    packed, err := tb.ReadPackedRange("ex1", 5, 11)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    if len(packed) != 2 {
+        t.Fatalf("Invalid packed length: %d != %d", len(packed), 2)
+    }
+
+    if seq := Unpack(packed, 8)[1:7]; seq != "CTTTTT" {
+        t.Errorf("Invalid packed sequence: %s != %s", seq, "CTTTTT")
+    }
+}
+
+func TestGapChar(t *testing.T) {
+    f, err := os.Open("examples/simple.2bit")
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    defer f.Close()
+
+    _, err = NewReader(f, GapChar('a'))
+    if err == nil {
+        t.Errorf("Expected error for nucleotide gap character")
+    }
+
+    tb, err := NewReader(f, GapChar('-'))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    seq, err := tb.Read("ex1")
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    good := "ACTgccttt----a-t-aCgc"
+    if string(seq) != good {
+        t.Errorf("Invalid sequence: %s != %s", seq, good)
+    }
+}
+
+func TestLengthAll(t *testing.T) {
+    tb, err := openTestTwoBit()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    lengths, err := tb.LengthAll()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    if !reflect.DeepEqual(lengths, map[string]int{"ex1": 21}) {
+        t.Errorf("Invalid lengths: %v", lengths)
+    }
+
+    total, err := tb.TotalLength()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    if total != 21 {
+        t.Errorf("Invalid total length: %d != %d", total, 21)
+    }
+}
+
+func TestReadRangeInto(t *testing.T) {
+    tb, err := openTestTwoBit()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    dst := make([]byte, 2)
+    n, err := tb.ReadRangeInto(dst, "ex1", 5, 11)
+    if err != io.ErrShortBuffer || n != 6 {
+        t.Fatalf("Expected ErrShortBuffer with n=6, got: %d %v", n, err)
+    }
+
+    dst = make([]byte, n)
+    n, err = tb.ReadRangeInto(dst, "ex1", 5, 11)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    if string(dst[:n]) != "ctttnn" {
+        t.Errorf("Invalid sequence: %s != %s", dst[:n], "ctttnn")
+    }
+}
+
+func TestToLower(t *testing.T) {
+    all := make([]byte, 256)
+    for i := range all {
+        all[i] = byte(i)
+    }
+
+    for offset := 0; offset < 8; offset++ {
+        seq := append([]byte{}, all[offset:]...)
+        toLower(seq)
+        good := bytes.ToLower(all[offset:])
+        for i := range seq {
+            if seq[i] != good[i] && all[offset+i] < 0x80 {
+                t.Errorf("Invalid lower case of %#x: %#x != %#x", all[offset+i], seq[i], good[i])
+            }
+            if all[offset+i] >= 0x80 && seq[i] != all[offset+i] {
+                t.Errorf("Non-ASCII byte %#x modified to %#x", all[offset+i], seq[i])
+            }
+        }
+    }
+}
+
+func TestDecode(t *testing.T) {
+    seq := "ACGTTGCAAC"
+    packed, err := Pack(seq)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    for start := 0; start < len(seq); start++ {
+        for end := start; end <= len(seq); end++ {
+            dst := make([]byte, end-start)
+            decode(dst, packed[start/4:], start%4, end-start)
+            if string(dst) != seq[start:end] {
+                t.Errorf("Invalid decode %d-%d: %s != %s", start, end, dst, seq[start:end])
+            }
+        }
+    }
+}
+
+func TestReadRangeBoundaries(t *testing.T) {
+    // cover every start%4 and end%4 combination including single bases at
+    // both edges of the sequence
+    seq := "ACGTnnNNacgtACGTacgTTNaGcCgAtT"
+
+    w := NewWriter()
+    err := w.Add("seq", seq)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    var out bytes.Buffer
+    err = w.WriteTo(&out)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    tb, err := NewReader(bytes.NewReader(out.Bytes()))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    for start := 0; start < len(seq); start++ {
+        for end := start+1; end <= len(seq); end++ {
+            got, err := tb.ReadRange("seq", start, end)
+            if err != nil {
+                t.Fatalf("Failed to read %d-%d: %s", start, end, err)
+            }
+            if string(got) != seq[start:end] {
+                t.Errorf("Invalid sequence %d-%d: %s != %s", start, end, got, seq[start:end])
+            }
+        }
+    }
+}
+
+func TestBase(t *testing.T) {
+    tb, err := openTestTwoBit()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    good := "ACTgcctttnnnNantnaCgc"
+    for i := range good {
+        b, err := tb.Base("ex1", i)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        if b != good[i] {
+            t.Errorf("Invalid base at %d: %c != %c", i, b, good[i])
+        }
+    }
+
+    _, err = tb.Base("ex1", len(good))
+    if err == nil {
+        t.Errorf("Expected error for position past end of sequence")
+    }
+}
+
+func TestBases(t *testing.T) {
+    tb, err := openTestTwoBit()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    bases, err := tb.Bases("ex1", []int{20, 0, 9, 3, 9, 18})
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    if string(bases) != "cAngnC" {
+        t.Errorf("Invalid bases: %s != %s", bases, "cAngnC")
+    }
+
+    _, err = tb.Bases("ex1", []int{0, 21})
+    if err == nil {
+        t.Errorf("Expected error for position past end of sequence")
+    }
+}
+
+func TestWriteReport(t *testing.T) {
+    w := NewWriter()
+    if w.Report() != nil {
+        t.Errorf("Expected nil report before write")
+    }
+
+    err := w.Add("ex1", "ACTgcctttnnnNantnaCgc")
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    var out bytes.Buffer
+    err = w.WriteTo(&out)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    report := w.Report()
+    if report.Bytes != int64(out.Len()) || report.IndexSize != 8 {
+        t.Errorf("Invalid report: %#v", report)
+    }
+
+    good := []SequenceReport{{Name: "ex1", Offset: 24, PackedOffset: 88, PackedSize: 6, DnaSize: 21}}
+    if !reflect.DeepEqual(report.Sequences, good) {
+        t.Errorf("Invalid sequence report: %#v", report.Sequences)
+    }
+}
+
+func TestFixtures(t *testing.T) {
+    tests := map[string]struct {
+        data []byte
+        name string
+        seq  string
+    }{
+        "long name":   {testfixtures.LongName(), strings.Repeat("x", 255), "ACGT"},
+        "many blocks": {testfixtures.ManyBlocks(10), "blocks", "AnAnAnAnAn"},
+    }
+
+    big := testfixtures.NewBuilder()
+    big.ByteOrder = binary.BigEndian
+    tests["big endian"] = struct {
+        data []byte
+        name string
+        seq  string
+    }{big.Add("ex1", "ACTgcctttnnnNantnaCgc").Bytes(), "ex1", "ACTgcctttnnnNantnaCgc"}
+
+    for desc, tt := range tests {
+        tb, err := NewReader(bytes.NewReader(tt.data))
+        if err != nil {
+            t.Fatalf("%s: %s", desc, err)
+        }
+
+        seq, err := tb.Read(tt.name)
+        if err != nil {
+            t.Fatalf("%s: %s", desc, err)
+        }
+
+        if string(seq) != tt.seq {
+            t.Errorf("%s: invalid sequence: %s != %s", desc, seq, tt.seq)
+        }
+    }
+
+    tb, err := NewReader(bytes.NewReader(testfixtures.Empty()))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if tb.Count() != 0 {
+        t.Errorf("Invalid sequence count: %d != %d", tb.Count(), 0)
+    }
+}
+
+func TestZeroLength(t *testing.T) {
+    w := NewWriter()
+    err := w.Add("empty", "")
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    w.Add("ex1", "ACGT")
+
+    var out bytes.Buffer
+    err = w.WriteTo(&out)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    for _, data := range [][]byte{out.Bytes(), testfixtures.ZeroLength()} {
+        tb, err := NewReader(bytes.NewReader(data))
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+
+        for _, coords := range [][]int{{0, 0}, {0, 10}, {5, 2}} {
+            seq, err := tb.ReadRange("empty", coords[0], coords[1])
+            if err != nil {
+                t.Errorf("Failed to read zero-length sequence: %s", err)
+            }
+            if len(seq) != 0 {
+                t.Errorf("Invalid zero-length sequence: %s", seq)
+            }
+        }
+
+        n, err := tb.Length("empty")
+        if err != nil || n != 0 {
+            t.Errorf("Invalid length: %d %v", n, err)
+        }
+
+        blocks, err := tb.NBlocks("empty")
+        if err != nil || len(blocks) != 0 {
+            t.Errorf("Invalid nBlocks: %v %v", blocks, err)
+        }
+    }
+}
+
+func TestAllN(t *testing.T) {
+    data := testfixtures.AllN(1001)
+
+    tb, err := NewReader(bytes.NewReader(data))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    seq, err := tb.ReadRange("gap", 3, 1000)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if string(seq) != strings.Repeat("N", 997) {
+        t.Errorf("Invalid all-N sequence")
+    }
+
+    w := NewWriter()
+    w.Add("gap", strings.Repeat("n", 9))
+    if !bytes.Equal(w.records["gap"].sequence, make([]byte, 3)) {
+        t.Errorf("Invalid packed data for all-N sequence: %v", w.records["gap"].sequence)
+    }
+}
+
+func TestHeaderInfo(t *testing.T) {
+    big := testfixtures.NewBuilder()
+    big.ByteOrder = binary.BigEndian
+    big.Add("ex1", "ACGT").Add("ex2", "ACGT")
+
+    tb, err := NewReader(bytes.NewReader(big.Bytes()))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    if tb.ByteOrder() != binary.BigEndian {
+        t.Errorf("Invalid byte order: %s", tb.ByteOrder())
+    }
+
+    good := HeaderInfo{Signature: SIG, Version: 0, Count: 2, ByteOrder: binary.BigEndian}
+    if tb.Header() != good {
+        t.Errorf("Invalid header: %+v", tb.Header())
+    }
+}
+
+func TestHalfOpenBoundaries(t *testing.T) {
+    // N block covers 4-8 and the mask block 8-12
+    tb := newTestReader(t, map[string]string{"chr1": "ACGTNNNNacgtACGT"})
+
+    tests := []struct {
+        start  int
+        end    int
+        want   string
+        valid  bool
+    }{
+        {0, 0, "ACGTNNNNacgtACGT", true},
+        {0, 16, "ACGTNNNNacgtACGT", true},
+        {0, 4, "ACGT", true},
+        {3, 5, "TN", true},
+        {4, 8, "NNNN", true},
+        {7, 9, "Na", true},
+        {8, 12, "acgt", true},
+        {11, 13, "tA", true},
+        {15, 16, "T", true},
+        {0, 17, "", false},
+        {-1, 4, "", false},
+        {2, -1, "", false},
+        {4, 4, "", false},
+        {5, 4, "", false},
+        {16, 16, "", false},
+    }
+
+    for _, tt := range tests {
+        seq, err := tb.ReadRange("chr1", tt.start, tt.end)
+        if !tt.valid {
+            if err == nil {
+                t.Errorf("Range %d-%d should be invalid", tt.start, tt.end)
+            }
+            continue
+        }
+        if err != nil {
+            t.Errorf("Range %d-%d: %s", tt.start, tt.end, err)
+            continue
+        }
+        if string(seq) != tt.want {
+            t.Errorf("Range %d-%d: %s != %s", tt.start, tt.end, seq, tt.want)
+        }
+    }
+
+    nBlocks, _ := tb.NBlocks("chr1")
+    mBlocks, _ := tb.MBlocks("chr1")
+    if len(nBlocks) != 1 || nBlocks[0].Start != 4 || nBlocks[0].End() != 8 {
+        t.Errorf("N block should be 4-8: %+v", nBlocks)
+    }
+    if len(mBlocks) != 1 || mBlocks[0].Start != 8 || mBlocks[0].End() != 12 {
+        t.Errorf("Mask block should be 8-12: %+v", mBlocks)
+    }
+
+    // blocks that only touch do not overlap
+    a := &Block{Start: 4, Length: 4}
+    overlaps := []struct {
+        b     *Block
+        want  bool
+    }{
+        {&Block{Start: 0, Length: 4}, false},
+        {&Block{Start: 8, Length: 4}, false},
+        {&Block{Start: 7, Length: 1}, true},
+        {&Block{Start: 3, Length: 2}, true},
+        {&Block{Start: 5, Length: 0}, false},
+    }
+    for _, tt := range overlaps {
+        if a.Overlaps(tt.b) != tt.want {
+            t.Errorf("Overlap of %+v and %+v should be %t", a, tt.b, tt.want)
+        }
+    }
+}
+
+// Write seq to a 2bit file and read it back
+func roundTrip(t testing.TB, seq string) (string) {
+    w := NewWriter()
+    err := w.Add("seq", seq)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    var out bytes.Buffer
+    err = w.WriteTo(&out)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    tb, err := NewReader(bytes.NewReader(out.Bytes()))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    got, err := tb.Read("seq")
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    return string(got)
+}
+
+func TestPackProperties(t *testing.T) {
+    rng := rand.New(rand.NewSource(42))
+    alphabets := []string{"ACGT", "acgtACGT", "ACGTNn", "ACGTNacgtnRYKM-*"}
+
+    for i := 0; i < 500; i++ {
+        alphabet := alphabets[i%len(alphabets)]
+        seq := testfixtures.RandomSequence(rng, rng.Intn(70), alphabet)
+
+        packed, err := Pack(seq)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        if len(packed) != packedSize(len(seq)) {
+            t.Errorf("Invalid packed size for %q: %d", seq, len(packed))
+        }
+        if got := Unpack(packed, len(seq)); got != testfixtures.Unpacked(seq) {
+            t.Errorf("Unpack(Pack(%q)) = %q", seq, got)
+        }
+        if got := roundTrip(t, seq); got != testfixtures.Stored(seq) {
+            t.Errorf("Round trip of %q = %q", seq, got)
+        }
+    }
+}
+
+func FuzzPack(f *testing.F) {
+    for _, seed := range []string{"", "A", "ACGT", "ACGTA", "acgtNNnnRY", "NNNNNNNNN"} {
+        f.Add(seed)
+    }
+
+    f.Fuzz(func(t *testing.T, seq string) {
+        packed, err := Pack(seq)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        if got := Unpack(packed, len(seq)); got != testfixtures.Unpacked(seq) {
+            t.Errorf("Unpack(Pack(%q)) = %q", seq, got)
+        }
+        if got := roundTrip(t, seq); got != testfixtures.Stored(seq) {
+            t.Errorf("Round trip of %q = %q", seq, got)
+        }
+    })
+}
+
+// Exercise every read path of the file in data. Malformed files must fail
+// with errors, never panic.
+func readAllPaths(data []byte) {
+    for _, mode := range []BlockMode{BLOCKS_LOAD, BLOCKS_SEARCH} {
+        tb, err := NewReader(bytes.NewReader(data), BlockTables(mode))
+        if err != nil {
+            return
+        }
+
+        for _, name := range tb.Names() {
+            tb.Read(name)
+            tb.ReadRange(name, 1, 3)
+            tb.ReadRangeInto(make([]byte, 2), name, 0, 2)
+            tb.ReadPackedRange(name, 0, 0)
+            tb.Base(name, 0)
+            tb.Bases(name, []int{0, 1, 2})
+            tb.LengthNoN(name)
+            tb.Digest(name)
+            tb.NBlocks(name)
+            NewWriter().AddReverseComplement("rc", tb, name)
+            NewWriter().AddInterval("iv", tb, Interval{name, 0, 1})
+        }
+        tb.Offsets()
+        tb.MaskSummary()
+        HardMask(tb, ioutil.Discard, nil)
+    }
+
+    s, err := NewScanner(bytes.NewReader(data))
+    if err != nil {
+        return
+    }
+    for s.Scan() {
+    }
+}
+
+func FuzzReader(f *testing.F) {
+    for _, seed := range [][]byte{
+        testfixtures.Empty(),
+        testfixtures.ZeroLength(),
+        testfixtures.AllN(9),
+        testfixtures.ManyBlocks(12),
+        testfixtures.NewBuilder().Add("chr1", "ACGTacgtNNNNACGT").Add("chr2", "ggCC").Bytes(),
+    } {
+        f.Add(seed)
+    }
+
+    f.Fuzz(func(t *testing.T, data []byte) {
+        readAllPaths(data)
+    })
+}
+
+func TestIndex(t *testing.T) {
+    w := NewWriter()
+    w.Add("chr2", "ACGT")
+    w.Add("chr1", "ACGTacgtNNNN")
+    w.Add("chrM", "GG")
+    var out bytes.Buffer
+    w.WriteTo(&out)
+
+    for _, opts := range [][]ReadOption{nil, {LazyIndex()}} {
+        tb, err := NewReader(bytes.NewReader(out.Bytes()), opts...)
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+
+        entries, err := tb.Index()
+        if err != nil {
+            t.Fatalf("%s", err)
+        }
+        if len(entries) != 3 {
+            t.Fatalf("Invalid index: %+v", entries)
+        }
+        for i, e := range entries {
+            good := w.Report().Sequences[i]
+            if e.Name != good.Name || e.Offset != good.Offset {
+                t.Errorf("Invalid index entry %d: %+v != %+v", i, e, good)
+            }
+        }
+
+        entries[0].Name = "changed"
+        again, _ := tb.Index()
+        if again[0].Name != "chr2" {
+            t.Errorf("Index is not a copy")
+        }
+    }
+}
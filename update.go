@@ -0,0 +1,48 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "io"
+    "sort"
+)
+
+// Update writes a copy of src to dst in which the sequences in changed are
+// replaced. Unchanged sequences keep their position and their packed data
+// and block tables are copied as byte ranges without repacking, so only the
+// changed sequences are encoded. Names in changed which are not in src are
+// appended in name order. To drop sequences open src with a NameFilter.
+func Update(src *Reader, dst io.Writer, changed map[string]string, opts ...WriterOption) (error) {
+    w := NewWriter(opts...)
+
+    for _, name := range src.namesByOffset() {
+        var err error
+        if seq, ok := changed[name]; ok {
+            err = w.Add(name, seq)
+        } else {
+            err = w.copySequence(src, name, name)
+        }
+        if err != nil {
+            return err
+        }
+    }
+
+    added := make([]string, 0)
+    for name := range changed {
+        if _, ok := src.index[name]; !ok {
+            added = append(added, name)
+        }
+    }
+    sort.Strings(added)
+
+    for _, name := range added {
+        err := w.Add(name, changed[name])
+        if err != nil {
+            return err
+        }
+    }
+
+    return w.WriteTo(dst)
+}
@@ -0,0 +1,67 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "strings"
+)
+
+func TestUpdate(t *testing.T) {
+    w := NewWriter()
+    w.Add("chr1", "ACGTNNacgt")
+    w.Add("chr2", "GGGGCCCC")
+    w.Add("chr3", "TTTTnnnnAAAA")
+    var orig bytes.Buffer
+    w.WriteTo(&orig)
+
+    src, err := NewReader(bytes.NewReader(orig.Bytes()))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    var out bytes.Buffer
+    err = Update(src, &out, map[string]string{"chr2": "GATTACA", "chr0": "ccNN"})
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    tb, err := NewReader(bytes.NewReader(out.Bytes()))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    if order := strings.Join(tb.namesByOffset(), ","); order != "chr1,chr2,chr3,chr0" {
+        t.Errorf("Invalid order: %s", order)
+    }
+
+    for name, good := range map[string]string{"chr1": "ACGTNNacgt", "chr2": "GATTACA", "chr3": "TTTTnnnnAAAA", "chr0": "ccNN"} {
+        seq, err := tb.Read(name)
+        if err != nil || string(seq) != good {
+            t.Errorf("Invalid sequence %s: %s != %s (%v)", name, seq, good, err)
+        }
+    }
+
+    // no changes reproduces the original file
+    var same bytes.Buffer
+    err = Update(src, &same, nil)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+    if !bytes.Equal(same.Bytes(), orig.Bytes()) {
+        t.Errorf("Update without changes is not byte identical")
+    }
+
+    filtered, _ := NewReader(bytes.NewReader(orig.Bytes()), NameFilter(func(name string) bool {
+        return name != "chr3"
+    }))
+    out.Reset()
+    Update(filtered, &out, nil)
+    tb, _ = NewReader(bytes.NewReader(out.Bytes()))
+    if tb.Count() != 2 {
+        t.Errorf("Filtered sequence was not dropped")
+    }
+}
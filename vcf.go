@@ -0,0 +1,95 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "io"
+    "bufio"
+    "bytes"
+    "fmt"
+    "strconv"
+    "strings"
+)
+
+// VCFMismatch describes a VCF record whose REF allele does not match the
+// reference sequence
+type VCFMismatch struct {
+    Line     int    // line number in the VCF
+    Chrom    string // CHROM column
+    Pos      int    // POS column (1-based)
+    Ref      string // REF column
+    Found    string // bases in the reference, empty if Chrom is not found
+}
+
+// Check the REF allele of every record in the VCF read from in against the
+// sequences in r. The VCF is streamed so files of any size are supported.
+// Comparison is case-insensitive. Records are reported as mismatches when the
+// REF allele differs, extends past the end of the sequence or the CHROM is not
+// found in r.
+func (r *Reader) ValidateVCF(in io.Reader) ([]*VCFMismatch, error) {
+    mismatches := make([]*VCFMismatch, 0)
+
+    scanner := bufio.NewScanner(in)
+    scanner.Buffer(make([]byte, defaultBufSize), 64*1024*1024)
+
+    line := 0
+    for scanner.Scan() {
+        line++
+        text := scanner.Text()
+        if len(text) == 0 || text[0] == '#' {
+            continue
+        }
+
+        cols := strings.SplitN(text, "\t", 5)
+        if len(cols) < 4 {
+            return nil, fmt.Errorf("Invalid VCF record on line %d", line)
+        }
+
+        pos, err := strconv.Atoi(cols[1])
+        if err != nil || pos < 1 {
+            return nil, fmt.Errorf("Invalid VCF position on line %d: %s", line, cols[1])
+        }
+
+        m := &VCFMismatch{Line: line, Chrom: cols[0], Pos: pos, Ref: cols[3]}
+
+        _, ok, err := r.lookup(m.Chrom)
+        if err != nil {
+            return nil, err
+        }
+        if !ok {
+            mismatches = append(mismatches, m)
+            continue
+        }
+
+        length, err := r.Length(m.Chrom)
+        if err != nil {
+            return nil, err
+        }
+
+        start := pos-1
+        end := start+len(m.Ref)
+        if end > length {
+            end = length
+        }
+
+        if start < end {
+            seq, err := r.ReadRange(m.Chrom, start, end)
+            if err != nil {
+                return nil, err
+            }
+            m.Found = string(seq)
+        }
+
+        if !bytes.EqualFold([]byte(m.Found), []byte(m.Ref)) {
+            mismatches = append(mismatches, m)
+        }
+    }
+
+    if err := scanner.Err(); err != nil {
+        return nil, fmt.Errorf("Failed to read VCF: %s", err)
+    }
+
+    return mismatches, nil
+}
@@ -0,0 +1,46 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "strings"
+)
+
+func TestValidateVCF(t *testing.T) {
+    tb, err := openTestTwoBit()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    vcf := "##fileformat=VCFv4.2\n" +
+        "#CHROM\tPOS\tID\tREF\tALT\n" +
+        "ex1\t1\t.\tA\tG\n" +
+        "ex1\t4\t.\tGCC\tG\n" +
+        "ex1\t2\t.\tG\tA\n" +
+        "ex1\t21\t.\tCA\tC\n" +
+        "chrX\t1\t.\tA\tG\n"
+
+    mismatches, err := tb.ValidateVCF(strings.NewReader(vcf))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    if len(mismatches) != 3 {
+        t.Fatalf("Invalid mismatch count: %d != %d", len(mismatches), 3)
+    }
+
+    if m := mismatches[0]; m.Line != 5 || m.Found != "C" {
+        t.Errorf("Invalid mismatch: %#v", m)
+    }
+
+    if m := mismatches[1]; m.Line != 6 || m.Found != "c" {
+        t.Errorf("Invalid mismatch: %#v", m)
+    }
+
+    if m := mismatches[2]; m.Chrom != "chrX" || m.Found != "" {
+        t.Errorf("Invalid mismatch: %#v", m)
+    }
+}
@@ -0,0 +1,78 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "fmt"
+    "io"
+)
+
+// FileVersion sets the version of the file written. Version 0 (the default)
+// uses 32-bit offsets in the file index and is limited to files of 4GB.
+// Version 1 uses 64-bit offsets.
+func FileVersion(v int) (WriterOption) {
+    return func(w *Writer) {
+        w.version = uint32(v)
+    }
+}
+
+// Rewrite copies all sequences from src to dst as a file of version
+// targetVersion, converting between the 32-bit (0) and 64-bit (1) offset
+// variants. Packed data and block tables are copied without decoding.
+// Converting to version 0 fails if the output would exceed the 32-bit offset
+// limit.
+func Rewrite(src *Reader, dst io.Writer, targetVersion int) (error) {
+    if targetVersion != VERSION && targetVersion != VERSION_LONG {
+        return fmt.Errorf("Unsupported version %d", targetVersion)
+    }
+
+    w := NewWriter(FileVersion(targetVersion))
+    err := w.copyFrom(src)
+    if err != nil {
+        return err
+    }
+
+    return w.WriteTo(dst)
+}
+
+// Add all sequences in src to w in file order without decoding them
+func (w *Writer) copyFrom(src *Reader) (error) {
+    for _, name := range src.namesByOffset() {
+        err := w.copySequence(src, name, name)
+        if err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+// Add sequence name from src to w as dstName without decoding it
+func (w *Writer) copySequence(src *Reader, name, dstName string) (error) {
+    if w.closed {
+        return ErrClosed
+    }
+    err := w.checkName(dstName)
+    if err != nil {
+        return err
+    }
+
+    rec, err := src.parseRecord(name, true)
+    if err != nil {
+        return err
+    }
+
+    packed, err := src.ReadPackedRange(name, 0, 0)
+    if err != nil {
+        return err
+    }
+
+    return w.addRecord(dstName, &seqRecord{
+        dnaSize: rec.dnaSize,
+        nBlocks: rec.nBlocks.copy(),
+        mBlocks: rec.mBlocks.copy(),
+        sequence: packed,
+    })
+}
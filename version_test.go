@@ -0,0 +1,54 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+    "testing"
+    "bytes"
+    "io/ioutil"
+)
+
+func TestRewrite(t *testing.T) {
+    tb, err := openTestTwoBit()
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    var v1 bytes.Buffer
+    err = Rewrite(tb, &v1, VERSION_LONG)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    long, err := NewReader(bytes.NewReader(v1.Bytes()))
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    if long.Version() != VERSION_LONG {
+        t.Errorf("Invalid version: %d != %d", long.Version(), VERSION_LONG)
+    }
+
+    seq, err := long.Read("ex1")
+    if err != nil || string(seq) != "ACTgcctttnnnNantnaCgc" {
+        t.Errorf("Invalid sequence in version 1 file: %s %v", seq, err)
+    }
+
+    var v0 bytes.Buffer
+    err = Rewrite(long, &v0, VERSION)
+    if err != nil {
+        t.Fatalf("%s", err)
+    }
+
+    good, _ := ioutil.ReadFile("examples/simple.2bit")
+    if !bytes.Equal(v0.Bytes(), good) {
+        t.Errorf("Round trip through version 1 does not match original file")
+    }
+
+    err = Rewrite(tb, &v0, 2)
+    if err == nil {
+        t.Errorf("Expected error for unsupported version")
+    }
+}